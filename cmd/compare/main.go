@@ -0,0 +1,81 @@
+// compare 把一次运行与同场景、同宿主机的上一次运行对比，发现回归时以非 0 退出
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"test-docker/pkg/scenario"
+)
+
+func main() {
+	path := flag.String("history", scenario.DefaultHistoryPath, "运行历史文件")
+	runID := flag.String("run", "", "要对比的 RunID，默认取最近一次运行")
+	name := flag.String("scenario", "", "未指定 -run 时，取该场景最近一次运行")
+	threshold := flag.Float64("threshold", 0.1, "朝变差方向的相对变化超过该比例即视为回归")
+	flag.Parse()
+
+	records, err := scenario.LoadHistory(*path)
+	if err != nil {
+		log.Fatalf("读取运行历史失败: %v", err)
+	}
+	current, ok := pick(records, *runID, *name)
+	if !ok {
+		log.Fatalf("历史中找不到要对比的运行")
+	}
+	prev, ok := scenario.PreviousRun(records, current)
+	if !ok {
+		fmt.Printf("%s 没有同场景、同宿主机（%s）的上一次运行，无法对比\n", current.RunID, current.Host.Hash)
+		return
+	}
+
+	c := scenario.CompareRuns(prev, current, *threshold)
+	fmt.Printf("对比 %s -> %s（宿主机 %s，阈值 %.0f%%）\n", prev.RunID, current.RunID, current.Host.Hash, *threshold*100)
+	if c.ParamsChanged {
+		fmt.Println("注意：两次运行的参数不同，结果仅供参考")
+	}
+	if c.VerdictChange {
+		fmt.Printf("结论变化：passed %t -> %t\n", prev.Passed, current.Passed)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "指标\t上一次\t本次\t变化\t")
+	for _, d := range c.Diffs {
+		mark := ""
+		if d.Regression {
+			mark = "回归"
+		}
+		fmt.Fprintf(w, "%s\t%.3f\t%.3f\t%s\t%s\n", d.Name, d.Previous, d.Current, change(d), mark)
+	}
+	w.Flush()
+
+	if c.Regressed() {
+		os.Exit(1)
+	}
+}
+
+// pick 按 RunID 或场景名选出要对比的运行，都为空时取最近一次运行
+func pick(records []scenario.HistoryRecord, runID, name string) (scenario.HistoryRecord, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		switch {
+		case runID != "":
+			if rec.RunID == runID {
+				return rec, true
+			}
+		case name == "" || rec.Scenario == name:
+			return rec, true
+		}
+	}
+	return scenario.HistoryRecord{}, false
+}
+
+func change(d scenario.MetricDiff) string {
+	if d.Undefined {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", d.Change*100)
+}
@@ -0,0 +1,109 @@
+// doctor 汇报宿主机对资源限制场景的支持情况
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"test-docker/pkg/scenario"
+	_ "test-docker/scenarios/cgroup"
+	_ "test-docker/scenarios/cpu"
+	_ "test-docker/scenarios/health"
+	_ "test-docker/scenarios/logging"
+	_ "test-docker/scenarios/memory"
+	_ "test-docker/scenarios/rootfs"
+	_ "test-docker/scenarios/security"
+	_ "test-docker/scenarios/stack"
+	_ "test-docker/scenarios/update"
+	_ "test-docker/scenarios/volume"
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+	image := flag.String("image", scenario.ProbeImage, "探测容器使用的镜像")
+	asJSON := flag.Bool("json", false, "以 JSON 输出宿主机信息与检查结果")
+	scenario.RegisterEndpointFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cli, err := scenario.NewDockerClient()
+	if err != nil {
+		log.Fatalf("创建 Docker 客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	if err := scenario.PullImage(ctx, cli, *image); err != nil {
+		log.Fatalf("拉取镜像失败: %v", err)
+	}
+	facts, err := scenario.CollectHostFacts(ctx, cli, *image)
+	if err != nil {
+		log.Fatalf("收集宿主机信息失败: %v", err)
+	}
+	checks := scenario.EvaluateHost(facts)
+	support := scenario.SupportedScenarios(checks, scenario.Families())
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]any{"endpoint": scenario.ActiveEndpoint, "host": facts, "checks": checks, "scenarios": support, "blocked": blockedScenarios(checks)}); err != nil {
+			log.Fatalf("输出 JSON 失败: %v", err)
+		}
+	} else {
+		printHuman(facts, checks, support)
+	}
+
+	if failed := scenario.RequiredFailures(checks); len(failed) > 0 {
+		names := make([]string, 0, len(failed))
+		for _, c := range failed {
+			names = append(names, c.Name)
+		}
+		fmt.Fprintf(os.Stderr, "必需能力不满足: %s\n", strings.Join(names, ", "))
+		os.Exit(1)
+	}
+}
+
+func printHuman(facts *scenario.HostFacts, checks []scenario.HostCheck, support map[string]scenario.CheckStatus) {
+	fmt.Printf("daemon %s\n", scenario.ActiveEndpoint)
+	fmt.Printf("Docker %s (API %s)，内核 %s，%s\n", facts.DaemonVersion, facts.APIVersion, facts.KernelVersion, facts.OperatingSystem)
+	fmt.Printf("cgroup v%s/%s，存储驱动 %s，%d CPU，%d MiB 内存\n\n",
+		facts.CgroupVersion, facts.CgroupDriver, facts.StorageDriver, facts.NCPU, facts.MemTotal/scenario.MiB)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "检查项\t结论\t必需\t影响场景\t说明")
+	for _, c := range checks {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", c.Name, c.Status, c.Required, strings.Join(c.Scenarios, ","), c.Detail)
+	}
+	w.Flush()
+
+	fmt.Println()
+	for _, family := range scenario.Families() {
+		fmt.Printf("scenarios/%-8s %s\n", family, support[family])
+	}
+	blocked := blockedScenarios(checks)
+	for _, name := range scenario.Names() {
+		if reasons := blocked[name]; len(reasons) > 0 {
+			fmt.Printf("无法运行 %s: %s\n", name, strings.Join(reasons, ", "))
+		}
+	}
+}
+
+// blockedScenarios 返回宿主机不满足依赖的已注册场景及对应的检查项
+func blockedScenarios(checks []scenario.HostCheck) map[string][]string {
+	blocked := map[string][]string{}
+	for _, name := range scenario.Names() {
+		s, _ := scenario.Lookup(name)
+		for _, c := range scenario.Blockers(checks, s) {
+			blocked[name] = append(blocked[name], c.Name)
+		}
+	}
+	return blocked
+}
@@ -0,0 +1,63 @@
+// history 列出历史库中的场景运行记录
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"test-docker/pkg/scenario"
+)
+
+func main() {
+	path := flag.String("history", scenario.DefaultHistoryPath, "运行历史文件")
+	name := flag.String("scenario", "", "只列出指定场景")
+	limit := flag.Int("n", 20, "最多列出最近的 n 条记录")
+	flag.Parse()
+
+	records, err := scenario.LoadHistory(*path)
+	if err != nil {
+		log.Fatalf("读取运行历史失败: %v", err)
+	}
+	var selected []scenario.HistoryRecord
+	for _, rec := range records {
+		if *name == "" || rec.Scenario == *name {
+			selected = append(selected, rec)
+		}
+	}
+	if len(selected) > *limit {
+		selected = selected[len(selected)-*limit:]
+	}
+	if len(selected) == 0 {
+		fmt.Println("没有匹配的运行记录")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RunID\t宿主机\t结论\t耗时(s)\tOOM 用时(s)\t峰值内存(MiB)\t平均 vCPU\t限流(s)")
+	for _, rec := range selected {
+		verdict := "pass"
+		if !rec.Passed {
+			verdict = "fail"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.1f\t%s\t%s\t%s\t%s\n",
+			rec.RunID, rec.Host.Hash, verdict,
+			rec.Metrics[scenario.MetricDuration],
+			metric(rec, scenario.MetricTimeToOOM, 1),
+			metric(rec, scenario.MetricPeakMemory, scenario.MiB),
+			metric(rec, scenario.MetricAchievedCPU, 1),
+			metric(rec, scenario.MetricThrottled, 1))
+	}
+	w.Flush()
+}
+
+// metric 按 scale 换算指标，缺失时输出 -
+func metric(rec scenario.HistoryRecord, name string, scale float64) string {
+	v, ok := rec.Metrics[name]
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", v/scale)
+}
@@ -0,0 +1,69 @@
+// report 把保存的场景报告渲染为可离线查看的单个 HTML 文件
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"test-docker/pkg/scenario"
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+	dir := flag.String("dir", scenario.ReportDir, "报告目录")
+	runID := flag.String("run", "", "要渲染的 RunID，默认取目录中最新的报告")
+	out := flag.String("out", "", "输出的 HTML 路径，默认与报告同名")
+	flag.Parse()
+
+	path := filepath.Join(*dir, *runID+".json")
+	if *runID == "" {
+		latest, err := latestReport(*dir)
+		if err != nil {
+			log.Fatalf("查找最新报告失败: %v", err)
+		}
+		path = latest
+	}
+	r, err := scenario.LoadReport(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(path, ".json") + ".html"
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("创建 HTML 文件失败: %v", err)
+	}
+	defer f.Close()
+	if err := scenario.WriteHTML(f, r); err != nil {
+		log.Fatalf("渲染 HTML 失败: %v", err)
+	}
+	log.Printf("已生成 %s", *out)
+}
+
+// latestReport 返回目录中修改时间最新的报告 JSON
+func latestReport(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestMod int64
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil {
+			return "", err
+		}
+		if mod := info.ModTime().UnixNano(); mod > latestMod {
+			latest, latestMod = m, mod
+		}
+	}
+	if latest == "" {
+		return "", os.ErrNotExist
+	}
+	return latest, nil
+}
@@ -0,0 +1,20 @@
+// scenario 为所有内置场景的统一入口，-list 列出场景，位置参数指定要运行的场景
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	_ "test-docker/scenarios/cgroup"
+	_ "test-docker/scenarios/cpu"
+	_ "test-docker/scenarios/health"
+	_ "test-docker/scenarios/logging"
+	_ "test-docker/scenarios/memory"
+	_ "test-docker/scenarios/rootfs"
+	_ "test-docker/scenarios/security"
+	_ "test-docker/scenarios/stack"
+	_ "test-docker/scenarios/update"
+	_ "test-docker/scenarios/volume"
+)
+
+func main() {
+	scenario.Main()
+}
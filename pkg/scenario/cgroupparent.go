@@ -0,0 +1,244 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/client"
+)
+
+// hostCgroupMount 为辅助容器内宿主机 cgroup 根的挂载点
+const hostCgroupMount = "/host-cgroup"
+
+// hostSystemctl 在宿主机 1 号进程的命名空间中执行 systemctl，辅助容器需使用宿主机的 PID 命名空间
+const hostSystemctl = "nsenter -t 1 -m -u -i -n -p -- systemctl"
+
+// ParentCgroup 描述一组容器共用的父 cgroup 及其聚合限额。Name 为 systemd slice（如 team-a.slice，
+// 用于 systemd cgroup driver）或 cgroupfs 路径（如 /test-docker/team-a，用于 cgroupfs driver），
+// 直接作为容器的 HostConfig.CgroupParent
+type ParentCgroup struct {
+	Name     string
+	Memory   int64
+	NanoCPUs int64
+	// V1 表示宿主机使用 cgroup v1，各控制器位于独立层级
+	V1 bool
+}
+
+// DefaultParentName 按 daemon 的 cgroup driver 为 group 生成父 cgroup 名称
+func DefaultParentName(driver, group string) string {
+	if driver == "systemd" {
+		// slice 名称中的 "-" 表示层级，这里只保留一层
+		return "testdocker_" + strings.ReplaceAll(group, "-", "_") + ".slice"
+	}
+	return "/test-docker/" + group
+}
+
+// IsSlice 表示 Name 是否为 systemd slice
+func (p ParentCgroup) IsSlice() bool {
+	return strings.HasSuffix(p.Name, ".slice")
+}
+
+// RelPath 返回父 cgroup 相对 cgroup 根的路径；slice 按 systemd 规则展开，a-b.slice 位于 a.slice/a-b.slice
+func (p ParentCgroup) RelPath() string {
+	if !p.IsSlice() {
+		return strings.Trim(path.Clean("/"+p.Name), "/")
+	}
+	base := strings.TrimSuffix(p.Name, ".slice")
+	parts := strings.Split(base, "-")
+	dirs := make([]string, len(parts))
+	for i := range parts {
+		dirs[i] = strings.Join(parts[:i+1], "-") + ".slice"
+	}
+	return path.Join(dirs...)
+}
+
+// file 返回控制器文件在宿主机 cgroup 根下的相对路径，v1 下加上控制器层级
+func (p ParentCgroup) file(controller, name string) string {
+	if p.V1 {
+		return path.Join(controller, p.RelPath(), name)
+	}
+	return path.Join(p.RelPath(), name)
+}
+
+// LimitFiles 返回聚合限额需要写入的 cgroup 文件与取值
+func (p ParentCgroup) LimitFiles() map[string]string {
+	files := map[string]string{}
+	if p.Memory > 0 {
+		if p.V1 {
+			files[p.file("memory", "memory.limit_in_bytes")] = strconv.FormatInt(p.Memory, 10)
+		} else {
+			files[p.file("", "memory.max")] = strconv.FormatInt(p.Memory, 10)
+		}
+	}
+	if p.NanoCPUs > 0 {
+		quota := p.NanoCPUs * cfsPeriod / 1e9
+		if p.V1 {
+			files[p.file("cpu", "cpu.cfs_period_us")] = strconv.Itoa(cfsPeriod)
+			files[p.file("cpu", "cpu.cfs_quota_us")] = strconv.FormatInt(quota, 10)
+		} else {
+			files[p.file("", "cpu.max")] = fmt.Sprintf("%d %d", quota, cfsPeriod)
+		}
+	}
+	return files
+}
+
+// SliceProperties 返回通过 systemctl set-property 设置聚合限额的属性，取值与 LimitFiles 写入的一致：
+// CPUQuota 按默认 100ms 周期换算，v1 下内存使用 MemoryLimit
+func (p ParentCgroup) SliceProperties() []string {
+	var props []string
+	if p.Memory > 0 {
+		key := "MemoryMax"
+		if p.V1 {
+			key = "MemoryLimit"
+		}
+		props = append(props, fmt.Sprintf("%s=%d", key, p.Memory))
+	}
+	if p.NanoCPUs > 0 {
+		props = append(props, fmt.Sprintf("CPUQuota=%d%%", p.NanoCPUs*100/1e9))
+	}
+	return props
+}
+
+// UsageFiles 返回父 cgroup 的内存用量、内存峰值与 CPU 累计用量文件
+func (p ParentCgroup) UsageFiles() (memory, peak, cpu string) {
+	if p.V1 {
+		return p.file("memory", "memory.usage_in_bytes"), p.file("memory", "memory.max_usage_in_bytes"), p.file("cpuacct", "cpuacct.usage")
+	}
+	return p.file("", "memory.current"), p.file("", "memory.peak"), p.file("", "cpu.stat")
+}
+
+// runHostCgroup 在挂载了宿主机 cgroup 根的特权辅助容器内执行脚本，脚本中的路径相对 cgroup 根。
+// 辅助容器使用宿主机的 PID 命名空间，脚本可以通过 hostSystemctl 操作 systemd slice
+func runHostCgroup(ctx context.Context, cli *client.Client, image, script, namePrefix string) (*RunResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := RunControlledContainer(ctx, cli, &container.Config{
+		Image:      image,
+		WorkingDir: hostCgroupMount,
+		Cmd:        []string{"sh", "-c", script},
+	}, &container.HostConfig{
+		Privileged:   true,
+		PidMode:      "host",
+		CgroupnsMode: container.CgroupnsModeHost,
+		Mounts: []mount.Mount{{
+			Type:   mount.TypeBind,
+			Source: "/sys/fs/cgroup",
+			Target: hostCgroupMount,
+		}},
+	}, namePrefix)
+	if err != nil {
+		return nil, err
+	}
+	if result.StatusCode != 0 {
+		return result, fmt.Errorf("辅助容器退出码 %d: %s", result.StatusCode, strings.TrimSpace(result.Stderr))
+	}
+	return result, nil
+}
+
+// ApplyParentLimits 设置父 cgroup 的聚合限额并读回，返回读回的文件内容。
+// systemd slice 由 systemd 管理，直接写 cgroup 文件会被 systemd 覆盖，因此通过 systemctl set-property --runtime 设置；
+// slice 在第一个容器启动时才由 systemd 创建，应在容器启动后调用。cgroupfs driver 下直接写入文件，目录不存在时先创建
+func ApplyParentLimits(ctx context.Context, cli *client.Client, image string, p ParentCgroup) (map[string]string, error) {
+	result, err := runHostCgroup(ctx, cli, image, applyScript(p), "cgroup-parent-apply")
+	if err != nil {
+		return nil, fmt.Errorf("写入父 cgroup %s 的限额失败: %w", p.Name, err)
+	}
+	return ParseReadFiles(result.Stdout), nil
+}
+
+// applyScript 生成设置聚合限额并读回 LimitFiles 的脚本
+func applyScript(p ParentCgroup) string {
+	files := p.LimitFiles()
+	paths := make([]string, 0, len(files))
+	for f := range files {
+		paths = append(paths, f)
+	}
+	// v1 下 cfs_period_us 需要先于 cfs_quota_us 写入
+	sort.Strings(paths)
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	if p.IsSlice() {
+		fmt.Fprintf(&b, "%s set-property --runtime %q %s\n", hostSystemctl, p.Name, strings.Join(p.SliceProperties(), " "))
+	} else {
+		for _, f := range paths {
+			fmt.Fprintf(&b, "mkdir -p %q\n", path.Dir(f))
+			fmt.Fprintf(&b, "echo %q > %q\n", files[f], f)
+		}
+	}
+	b.WriteString(ReadFilesStep("", paths...).Cmd[2])
+	return b.String()
+}
+
+// CheckParentLimits 比较读回的父 cgroup 文件与期望的聚合限额，返回不一致的描述
+func CheckParentLimits(p ParentCgroup, files map[string]string) []string {
+	var mismatches []string
+	want := p.LimitFiles()
+	paths := make([]string, 0, len(want))
+	for f := range want {
+		paths = append(paths, f)
+	}
+	sort.Strings(paths)
+	for _, f := range paths {
+		if got, ok := files[f]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s: 未找到", f))
+		} else if got != want[f] {
+			mismatches = append(mismatches, fmt.Sprintf("%s: 期望 %q，实际 %q", f, want[f], got))
+		}
+	}
+	return mismatches
+}
+
+// ReadParentFiles 读取父 cgroup 下的文件，不存在的文件会被跳过
+func ReadParentFiles(ctx context.Context, cli *client.Client, image string, paths ...string) (map[string]string, error) {
+	result, err := runHostCgroup(ctx, cli, image, ReadFilesStep("", paths...).Cmd[2], "cgroup-parent-read")
+	if err != nil {
+		return nil, fmt.Errorf("读取父 cgroup 失败: %w", err)
+	}
+	return ParseReadFiles(result.Stdout), nil
+}
+
+// RemoveParentCgroup 删除父 cgroup（需已没有容器）。slice 通过 systemctl 停止并撤销 set-property 的运行时设置；
+// cgroupfs 下 daemon 会在每个控制器层级下创建父 cgroup，v1 下需要逐个层级删除；各层级内从叶子向上删除到根，
+// 不存在的层级会被跳过，上层目录仍被其他组使用时保留
+func RemoveParentCgroup(ctx context.Context, cli *client.Client, image string, p ParentCgroup) error {
+	script := removeScript(p)
+	if p.IsSlice() {
+		script = fmt.Sprintf("%[1]s stop %[2]q && %[1]s revert %[2]q", hostSystemctl, p.Name)
+	}
+	if _, err := runHostCgroup(ctx, cli, image, script, "cgroup-parent-remove"); err != nil {
+		return fmt.Errorf("删除父 cgroup %s 失败: %w", p.Name, err)
+	}
+	return nil
+}
+
+// removeHead 为 removeScript 的开头：%[1]s 为要遍历的层级，%[2]s 为父 cgroup 的相对路径
+const removeHead = `rc=0
+for h in %[1]s; do
+    h=${h%%/}
+    [ -d "$h/%[2]s" ] || continue
+    if ! rmdir "$h/%[2]s"; then rc=1; continue; fi
+`
+
+// removeScript 生成删除父 cgroup 的脚本：v2 只有一个层级，v1 遍历 cgroup 根下的每个控制器层级。
+// 叶子目录存在却删除失败时以 1 退出
+func removeScript(p ParentCgroup) string {
+	hierarchies := "."
+	if p.V1 {
+		hierarchies = "*/"
+	}
+	leaf := p.RelPath()
+	var b strings.Builder
+	fmt.Fprintf(&b, removeHead, hierarchies, leaf)
+	for dir := path.Dir(leaf); dir != "."; dir = path.Dir(dir) {
+		fmt.Fprintf(&b, "    rmdir \"$h/%s\" 2>/dev/null || continue\n", dir)
+	}
+	b.WriteString("done\nexit $rc\n")
+	return b.String()
+}
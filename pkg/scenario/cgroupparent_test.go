@@ -0,0 +1,124 @@
+package scenario
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParentCgroupPaths(t *testing.T) {
+	tests := []struct {
+		p     ParentCgroup
+		rel   string
+		limit map[string]string
+	}{
+		{
+			p:   ParentCgroup{Name: "/test-docker/team-a/", Memory: 192 * MiB, NanoCPUs: 1_000_000_000},
+			rel: "test-docker/team-a",
+			limit: map[string]string{
+				"test-docker/team-a/memory.max": "201326592",
+				"test-docker/team-a/cpu.max":    "100000 100000",
+			},
+		},
+		{
+			p:     ParentCgroup{Name: "jobs-team.slice", Memory: 64 * MiB},
+			rel:   "jobs.slice/jobs-team.slice",
+			limit: map[string]string{"jobs.slice/jobs-team.slice/memory.max": "67108864"},
+		},
+		{
+			p:   ParentCgroup{Name: "/team", NanoCPUs: 500_000_000, V1: true},
+			rel: "team",
+			limit: map[string]string{
+				"cpu/team/cpu.cfs_period_us": "100000",
+				"cpu/team/cpu.cfs_quota_us":  "50000",
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.p.RelPath(); got != tt.rel {
+			t.Errorf("%s: RelPath = %q, want %q", tt.p.Name, got, tt.rel)
+		}
+		if got := tt.p.LimitFiles(); !maps.Equal(got, tt.limit) {
+			t.Errorf("%s: LimitFiles = %v, want %v", tt.p.Name, got, tt.limit)
+		}
+	}
+
+	if got := DefaultParentName("systemd", "team-a"); got != "testdocker_team_a.slice" {
+		t.Errorf("DefaultParentName(systemd) = %q", got)
+	}
+	if got := DefaultParentName("cgroupfs", "team-a"); got != "/test-docker/team-a" {
+		t.Errorf("DefaultParentName(cgroupfs) = %q", got)
+	}
+}
+
+func TestCheckParentLimits(t *testing.T) {
+	p := ParentCgroup{Name: "/g", Memory: 64 * MiB, NanoCPUs: 1_000_000_000}
+	files := ParseReadFiles("== g/cpu.max\n100000 100000\n== g/memory.max\nmax\n")
+	got := CheckParentLimits(p, files)
+	if len(got) != 1 || !strings.HasPrefix(got[0], "g/memory.max: 期望") {
+		t.Errorf("CheckParentLimits = %q", got)
+	}
+	if got := CheckParentLimits(p, map[string]string{}); len(got) != 2 {
+		t.Errorf("CheckParentLimits(empty) = %q, want 2 missing", got)
+	}
+}
+
+func TestRemoveScript(t *testing.T) {
+	v2 := removeScript(ParentCgroup{Name: "/test-docker/team-a"})
+	for _, want := range []string{
+		"for h in .; do",
+		`if ! rmdir "$h/test-docker/team-a"; then rc=1; continue; fi`,
+		`rmdir "$h/test-docker" 2>/dev/null || continue`,
+	} {
+		if !strings.Contains(v2, want) {
+			t.Errorf("v2 script missing %q:\n%s", want, v2)
+		}
+	}
+	if v1 := removeScript(ParentCgroup{Name: "/test-docker/team-a", V1: true}); !strings.Contains(v1, "for h in */; do") {
+		t.Errorf("v1 script does not walk every hierarchy:\n%s", v1)
+	}
+}
+
+func TestApplyScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       ParentCgroup
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "slice",
+			p:    ParentCgroup{Name: "testdocker_team_a.slice", Memory: 192 * MiB, NanoCPUs: 1_000_000_000},
+			want: []string{
+				hostSystemctl + ` set-property --runtime "testdocker_team_a.slice" MemoryMax=201326592 CPUQuota=100%`,
+				"testdocker_team_a.slice/memory.max",
+			},
+			notWant: []string{`" > "`, "mkdir"},
+		},
+		{
+			name:    "slice v1",
+			p:       ParentCgroup{Name: "g.slice", Memory: 64 * MiB, NanoCPUs: 500_000_000, V1: true},
+			want:    []string{"MemoryLimit=67108864 CPUQuota=50%"},
+			notWant: []string{"MemoryMax"},
+		},
+		{
+			name:    "cgroupfs",
+			p:       ParentCgroup{Name: "/test-docker/team-a", Memory: 64 * MiB},
+			want:    []string{`mkdir -p "test-docker/team-a"`, `echo "67108864" > "test-docker/team-a/memory.max"`},
+			notWant: []string{"systemctl"},
+		},
+	}
+	for _, tt := range tests {
+		script := applyScript(tt.p)
+		for _, want := range tt.want {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s: script missing %q:\n%s", tt.name, want, script)
+			}
+		}
+		for _, bad := range tt.notWant {
+			if strings.Contains(script, bad) {
+				t.Errorf("%s: script contains %q:\n%s", tt.name, bad, script)
+			}
+		}
+	}
+}
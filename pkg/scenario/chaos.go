@@ -0,0 +1,384 @@
+package scenario
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// 混沌动作的类型
+const (
+	// ChaosPause 暂停容器，Duration 后恢复
+	ChaosPause = "pause"
+	// ChaosKill 向容器发送 Signal
+	ChaosKill = "kill"
+	// ChaosStop 以 StopTimeout 停止容器，超时后 daemon 发送 SIGKILL
+	ChaosStop = "stop"
+	// ChaosDisconnect 把容器从 Network 断开，Duration 大于 0 时到期后重新连接
+	ChaosDisconnect = "disconnect"
+	// ChaosDetachVolume 模拟卷在 daemon 一侧被摘除：在容器的 mount namespace 中惰性卸载 Target，
+	// 已打开的文件仍可读写，之后对该路径的访问落到挂载点下的根文件系统
+	ChaosDetachVolume = "detach-volume"
+)
+
+// chaosPoll 为等待进度触发时的轮询间隔
+const chaosPoll = 50 * time.Millisecond
+
+// ProgressFunc 从容器输出的一行中解析进度（0-1），该行不含进度时返回 false
+type ProgressFunc func(line string) (float64, bool)
+
+// ChaosAction 描述对运行中容器施加的一次扰动。AtProgress 大于 0 时在解析到的进度达到该值时触发，
+// 否则在相对开始 After 的时刻触发；动作按声明顺序依次等待与执行
+type ChaosAction struct {
+	Name       string
+	Kind       string
+	After      time.Duration
+	AtProgress float64
+	// Duration 为 pause 与 disconnect 的持续时间
+	Duration time.Duration
+	// Signal 为 kill 发送的信号，为空时使用 SIGKILL
+	Signal string
+	// StopTimeout 为 stop 等待容器退出的秒数
+	StopTimeout int
+	// Network 为 disconnect 断开的网络
+	Network string
+	// Target 为 detach-volume 卸载的容器内挂载点
+	Target string
+}
+
+// trigger 返回触发条件的描述
+func (a ChaosAction) trigger() string {
+	if a.AtProgress > 0 {
+		return fmt.Sprintf("进度 %.0f%%", a.AtProgress*100)
+	}
+	return fmt.Sprintf("开始后 %s", a.After)
+}
+
+// describe 返回动作本身的描述
+func (a ChaosAction) describe() string {
+	switch a.Kind {
+	case ChaosPause:
+		return fmt.Sprintf("暂停 %s", a.Duration)
+	case ChaosKill:
+		return "发送 " + a.signal()
+	case ChaosStop:
+		return fmt.Sprintf("停止（超时 %ds）", a.StopTimeout)
+	case ChaosDisconnect:
+		if a.Duration > 0 {
+			return fmt.Sprintf("断开网络 %s %s", a.Network, a.Duration)
+		}
+		return "断开网络 " + a.Network
+	default:
+		return "摘除卷 " + a.Target
+	}
+}
+
+func (a ChaosAction) signal() string {
+	if a.Signal == "" {
+		return "SIGKILL"
+	}
+	return a.Signal
+}
+
+// ChaosPlan 为一组混沌动作，Progress 为按进度触发的动作提供进度
+type ChaosPlan struct {
+	Progress ProgressFunc
+	Actions  []ChaosAction
+}
+
+// validate 检查动作所需的参数是否齐全
+func (p ChaosPlan) validate() error {
+	for _, a := range p.Actions {
+		var problem string
+		switch a.Kind {
+		case ChaosPause:
+			if a.Duration <= 0 {
+				problem = "pause 需要正的 Duration"
+			}
+		case ChaosKill, ChaosStop:
+		case ChaosDisconnect:
+			if a.Network == "" {
+				problem = "disconnect 需要指定 Network"
+			}
+		case ChaosDetachVolume:
+			if a.Target == "" {
+				problem = "detach-volume 需要指定 Target"
+			}
+		default:
+			problem = fmt.Sprintf("未知的动作类型 %q", a.Kind)
+		}
+		if problem == "" && a.AtProgress > 0 && p.Progress == nil {
+			problem = "按进度触发需要 ChaosPlan.Progress"
+		}
+		if problem != "" {
+			return fmt.Errorf("混沌动作 %s: %s", a.Name, problem)
+		}
+	}
+	return nil
+}
+
+// ChaosResult 记录一次混沌动作的执行情况
+type ChaosResult struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Trigger string `json:"trigger"`
+	// Fired 为 false 表示容器在触发条件满足之前已退出
+	Fired bool      `json:"fired"`
+	At    time.Time `json:"at"`
+	// Progress 为触发时最后解析到的进度，没有解析到时为 0
+	Progress float64 `json:"progress,omitempty"`
+	// Took 为动作（含 pause/disconnect 的持续与恢复）的耗时
+	Took time.Duration `json:"took,omitempty"`
+	// State 为动作完成后容器的状态
+	State string `json:"state,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ChaosRunner 跟随容器输出解析进度，并按 ChaosPlan 依次对容器施加扰动
+type ChaosRunner struct {
+	cli    *client.Client
+	id     string
+	plan   ChaosPlan
+	report *Report
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu          sync.Mutex
+	progress    float64
+	hasProgress bool
+	results     []ChaosResult
+}
+
+// StartChaos 对运行中的容器开始执行混沌计划，时间触发以调用时刻为起点。
+// 每个动作执行后打印日志并记录到 ctx 中报告的时间线（chaos）；Stop 之后全部结果写入报告的 chaos
+func StartChaos(ctx context.Context, cli *client.Client, containerID string, plan ChaosPlan) (*ChaosRunner, error) {
+	if err := plan.validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c := &ChaosRunner{cli: cli, id: containerID, plan: plan, report: ReportFrom(ctx), cancel: cancel, done: make(chan struct{})}
+	var following sync.WaitGroup
+	if plan.Progress != nil {
+		following.Add(1)
+		go func() {
+			defer following.Done()
+			c.follow(ctx)
+		}()
+	}
+	start := time.Now()
+	go func() {
+		defer close(c.done)
+		defer following.Wait()
+		for i, a := range plan.Actions {
+			if err := c.wait(ctx, start, a); err != nil {
+				c.mu.Lock()
+				for _, rest := range plan.Actions[i:] {
+					c.results = append(c.results, ChaosResult{Name: rest.Name, Kind: rest.Kind, Trigger: rest.trigger()})
+				}
+				c.mu.Unlock()
+				return
+			}
+			result := c.apply(ctx, a)
+			c.mu.Lock()
+			c.results = append(c.results, result)
+			c.mu.Unlock()
+		}
+	}()
+	return c, nil
+}
+
+// follow 跟随容器的 stdout/stderr，逐行解析进度
+func (c *ChaosRunner) follow(ctx context.Context) {
+	logs, err := c.cli.ContainerLogs(ctx, c.id, client.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[chaos] 跟随容器输出失败: %v", err)
+		}
+		return
+	}
+	defer logs.Close()
+	r, w := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(w, w, logs)
+		w.CloseWithError(err)
+	}()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if p, ok := c.plan.Progress(scanner.Text()); ok {
+			c.mu.Lock()
+			c.progress, c.hasProgress = p, true
+			c.mu.Unlock()
+		}
+	}
+	r.Close()
+}
+
+// Progress 返回最后解析到的进度
+func (c *ChaosRunner) Progress() (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.progress, c.hasProgress
+}
+
+// wait 等待动作的触发条件
+func (c *ChaosRunner) wait(ctx context.Context, start time.Time, a ChaosAction) error {
+	if a.AtProgress <= 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(start.Add(a.After))):
+			return nil
+		}
+	}
+	for {
+		if p, ok := c.Progress(); ok && p >= a.AtProgress {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(chaosPoll):
+		}
+	}
+}
+
+// apply 执行一个动作。恢复操作（unpause、重新连接）不随 ctx 取消，避免容器停留在被扰动的状态
+func (c *ChaosRunner) apply(ctx context.Context, a ChaosAction) ChaosResult {
+	result := ChaosResult{Name: a.Name, Kind: a.Kind, Trigger: a.trigger(), Fired: true, At: time.Now()}
+	result.Progress, _ = c.Progress()
+	c.mark(result.At, "%s：%s 触发，%s", a.Name, result.Trigger, a.describe())
+
+	restoreCtx := context.WithoutCancel(ctx)
+	var err error
+	switch a.Kind {
+	case ChaosPause:
+		if _, err = c.cli.ContainerPause(ctx, c.id, client.ContainerPauseOptions{}); err == nil {
+			sleepCtx(ctx, a.Duration)
+			_, err = c.cli.ContainerUnpause(restoreCtx, c.id, client.ContainerUnpauseOptions{})
+		}
+	case ChaosKill:
+		_, err = c.cli.ContainerKill(ctx, c.id, client.ContainerKillOptions{Signal: a.signal()})
+	case ChaosStop:
+		timeout := a.StopTimeout
+		_, err = c.cli.ContainerStop(ctx, c.id, client.ContainerStopOptions{Timeout: &timeout})
+	case ChaosDisconnect:
+		_, err = c.cli.NetworkDisconnect(ctx, a.Network, client.NetworkDisconnectOptions{Container: c.id, Force: true})
+		if err == nil && a.Duration > 0 {
+			sleepCtx(ctx, a.Duration)
+			_, err = c.cli.NetworkConnect(restoreCtx, a.Network, client.NetworkConnectOptions{Container: c.id})
+		}
+	case ChaosDetachVolume:
+		err = c.detach(ctx, a.Target)
+	}
+	result.Took = time.Since(result.At)
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if inspect, ierr := c.cli.ContainerInspect(restoreCtx, c.id, client.ContainerInspectOptions{}); ierr == nil && inspect.Container.State != nil {
+		result.State = string(inspect.Container.State.Status)
+	}
+	outcome := "完成"
+	if err != nil {
+		outcome = "失败: " + err.Error()
+	}
+	c.mark(time.Now(), "%s %s（耗时 %s，容器状态 %s）", a.Name, outcome, result.Took.Round(time.Millisecond), result.State)
+	return result
+}
+
+// detach 在特权辅助容器中进入目标容器的 mount namespace，惰性卸载挂载点。辅助容器不属于场景本身，
+// 运行时摘掉 ctx 上的报告，既不计入报告的容器结果，也不打运行 label、不出现在事件中
+func (c *ChaosRunner) detach(ctx context.Context, target string) error {
+	inspect, err := c.cli.ContainerInspect(ctx, c.id, client.ContainerInspectOptions{})
+	if err != nil {
+		return fmt.Errorf("查询容器状态失败: %w", err)
+	}
+	if inspect.Container.State == nil || inspect.Container.State.Pid == 0 {
+		return errors.New("容器未在运行")
+	}
+	result, err := RunControlledContainer(WithReport(ctx, nil), c.cli, &container.Config{
+		Image: ProbeImage,
+		Cmd:   []string{"nsenter", "-t", strconv.Itoa(inspect.Container.State.Pid), "-m", "umount", "-l", target},
+	}, &container.HostConfig{Privileged: true, PidMode: "host"}, "chaos-detach")
+	if err != nil {
+		return fmt.Errorf("卸载 %s 失败: %w", target, err)
+	}
+	if result.StatusCode != 0 {
+		return fmt.Errorf("卸载 %s 失败（退出码 %d）: %s", target, result.StatusCode, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+func (c *ChaosRunner) mark(at time.Time, format string, args ...any) {
+	log.Printf("[chaos] "+format, args...)
+	if c.report != nil {
+		c.report.Mark(at, "chaos", format, args...)
+	}
+}
+
+// sleepCtx 等待 d 或 ctx 取消
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// Done 返回在全部动作执行完毕（含 pause/disconnect 的恢复）后关闭的 channel
+func (c *ChaosRunner) Done() <-chan struct{} {
+	return c.done
+}
+
+// Stop 结束混沌计划并返回各动作的结果，尚未触发的动作记为未触发。结果同时写入报告
+func (c *ChaosRunner) Stop() []ChaosResult {
+	c.cancel()
+	<-c.done
+	c.mu.Lock()
+	results := c.results
+	c.mu.Unlock()
+	if c.report != nil {
+		c.report.mu.Lock()
+		c.report.Chaos = append(c.report.Chaos, results...)
+		c.report.mu.Unlock()
+	}
+	return results
+}
+
+// RunWithChaos 与 RunControlledContainer 相同，容器启动后按 plan 施加扰动，容器退出时结束计划
+func RunWithChaos(ctx context.Context, cli *client.Client, cfg *container.Config, hostConfig *container.HostConfig, namePrefix string, plan ChaosPlan) (*RunResult, []ChaosResult, error) {
+	if err := plan.validate(); err != nil {
+		return nil, nil, err
+	}
+	var runner *ChaosRunner
+	var results []ChaosResult
+	result, err := runContainer(ctx, cli, cfg, hostConfig, namePrefix, func(id string) func() {
+		runner, _ = StartChaos(ctx, cli, id, plan)
+		return func() { results = runner.Stop() }
+	})
+	return result, results, err
+}
+
+// LogChaosResults 打印各动作的结果
+func LogChaosResults(results []ChaosResult) {
+	for _, r := range results {
+		switch {
+		case !r.Fired:
+			log.Printf("[%s] %s 未触发", r.Name, r.Trigger)
+		case r.Error != "":
+			log.Printf("[%s] %s 触发（进度 %.0f%%），失败: %s", r.Name, r.Trigger, r.Progress*100, r.Error)
+		default:
+			log.Printf("[%s] %s 触发（进度 %.0f%%），耗时 %s，容器状态 %s", r.Name, r.Trigger, r.Progress*100, r.Took.Round(time.Millisecond), r.State)
+		}
+	}
+}
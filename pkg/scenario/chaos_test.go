@@ -0,0 +1,49 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChaosPlanValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		plan   ChaosPlan
+		wantOK bool
+		want   string
+	}{
+		{"按时间触发", ChaosPlan{Actions: []ChaosAction{{Name: "kill", Kind: ChaosKill, After: time.Second}}}, true, ""},
+		{"按进度触发", ChaosPlan{Progress: FillProgress, Actions: []ChaosAction{{Name: "kill", Kind: ChaosKill, AtProgress: 0.5}}}, true, ""},
+		{"缺少进度来源", ChaosPlan{Actions: []ChaosAction{{Name: "kill", Kind: ChaosKill, AtProgress: 0.5}}}, false, "Progress"},
+		{"pause 缺少时长", ChaosPlan{Actions: []ChaosAction{{Name: "pause", Kind: ChaosPause}}}, false, "Duration"},
+		{"disconnect 缺少网络", ChaosPlan{Actions: []ChaosAction{{Name: "net", Kind: ChaosDisconnect}}}, false, "Network"},
+		{"detach 缺少挂载点", ChaosPlan{Actions: []ChaosAction{{Name: "detach", Kind: ChaosDetachVolume}}}, false, "Target"},
+		{"未知类型", ChaosPlan{Actions: []ChaosAction{{Name: "x", Kind: "reboot"}}}, false, "reboot"},
+	}
+	for _, c := range cases {
+		err := c.plan.validate()
+		if c.wantOK != (err == nil) || (err != nil && !strings.Contains(err.Error(), c.want)) {
+			t.Errorf("%s: validate = %v", c.name, err)
+		}
+	}
+}
+
+func TestChaosActionTrigger(t *testing.T) {
+	if got := (ChaosAction{AtProgress: 0.5}).trigger(); got != "进度 50%" {
+		t.Errorf("trigger = %q", got)
+	}
+	if got := (ChaosAction{After: 2 * time.Second}).trigger(); got != "开始后 2s" {
+		t.Errorf("trigger = %q", got)
+	}
+}
+
+func TestFillProgress(t *testing.T) {
+	p, ok := FillProgress("累计写入=16MiB 已用=16MiB 剩余=48MiB")
+	if !ok || p != 0.25 {
+		t.Errorf("FillProgress = %v, %v", p, ok)
+	}
+	if _, ok := FillProgress("写入失败：卷空间已耗尽"); ok {
+		t.Error("期望不含进度")
+	}
+}
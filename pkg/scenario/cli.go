@@ -0,0 +1,84 @@
+package scenario
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Main 为所有场景共用的命令行入口：解析共享参数后运行位置参数指定的已注册场景，
+// all 表示全部场景；未给出位置参数时运行 defaults。运行前由 GuardPlan 剔除超出宿主机余量的场景（-force 时保留），
+// 任一场景未通过或被剔除时以退出码 1 结束。
+//
+// 外部模块注册自己的场景后调用 Main 即可复用同一套参数、报告、历史与指标：
+//
+//	func main() {
+//		scenario.Register(&myScenario{})
+//		scenario.Main()
+//	}
+func Main(defaults ...string) {
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+	list := flag.Bool("list", false, "列出已注册的场景后退出")
+	timeout := flag.Duration("timeout", 10*time.Minute, "单个场景的运行超时")
+	force := flag.Bool("force", false, "跳过宿主机安全检查，运行占用超出宿主机余量的场景")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: %s [参数] [场景名... | all]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	ParseFlags()
+
+	if *list {
+		for _, name := range Names() {
+			fmt.Println(name)
+		}
+		return
+	}
+	names := flag.Args()
+	if len(names) == 0 {
+		names = defaults
+	}
+	if len(names) == 1 && names[0] == "all" {
+		names = Names()
+	}
+	if len(names) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	scenarios := make([]Scenario, 0, len(names))
+	for _, name := range names {
+		s, ok := Lookup(name)
+		if !ok {
+			log.Fatalf("未注册的场景 %s，可用 -list 查看", name)
+		}
+		scenarios = append(scenarios, s)
+	}
+
+	cli, err := NewDockerClient()
+	if err != nil {
+		log.Fatalf("创建 Docker 客户端失败: %v", err)
+	}
+	defer cli.Close()
+
+	guardCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	scenarios, failed := GuardPlan(guardCtx, cli, scenarios, *force)
+	cancel()
+	for _, s := range scenarios {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		report, err := Execute(ctx, cli, s)
+		cancel()
+		if err != nil {
+			log.Printf("保存场景 %s 的报告失败: %v", s.Name(), err)
+		}
+		if err != nil || !report.Passed {
+			failed = append(failed, s.Name())
+		}
+	}
+	if len(failed) > 0 {
+		log.Printf("未通过的场景: %v", failed)
+		cli.Close()
+		os.Exit(1)
+	}
+}
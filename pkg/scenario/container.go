@@ -0,0 +1,199 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/client"
+)
+
+// RunResult 记录一次受控容器从创建到退出的结果
+type RunResult struct {
+	Name        string    `json:"name"`
+	ContainerID string    `json:"containerId"`
+	StatusCode  int64     `json:"statusCode"`
+	OOMKilled   bool      `json:"oomKilled"`
+	Stdout      string    `json:"stdout"`
+	Stderr      string    `json:"stderr"`
+	StartedAt   time.Time `json:"startedAt"`
+	FinishedAt  time.Time `json:"finishedAt"`
+	Events      []Event   `json:"events,omitempty"`
+	Lines       []LogLine `json:"lines,omitempty"`
+}
+
+// Duration 返回容器的运行时长
+func (r *RunResult) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// BuildHostConfig 组装资源限制、根文件系统限额与挂载点，rootFsLimitBytes 为 0 时不设置 StorageOpt
+func BuildHostConfig(resources container.Resources, rootFsLimitBytes int64, mounts []mount.Mount) *container.HostConfig {
+	hostConfig := &container.HostConfig{
+		Resources: resources,
+		Mounts:    mounts,
+	}
+	if rootFsLimitBytes > 0 {
+		hostConfig.StorageOpt = map[string]string{
+			"size": fmt.Sprintf("%dM", rootFsLimitBytes/MiB),
+		}
+	}
+	return hostConfig
+}
+
+// containerName 为容器名追加时间后缀，避免多次运行互相冲突
+func containerName(prefix string) string {
+	return prefix + "-" + time.Now().Format("150405")
+}
+
+// RunControlledContainer 创建并启动受限容器，等待其退出后收集日志与退出状态，最后删除容器。
+// 如果 ctx 中携带了 Report，容器会打上运行 label，结果也会自动追加到报告中
+func RunControlledContainer(ctx context.Context, cli *client.Client, cfg *container.Config, hostConfig *container.HostConfig, namePrefix string) (*RunResult, error) {
+	return runContainer(ctx, cli, cfg, hostConfig, namePrefix, nil)
+}
+
+// runContainer 为 RunControlledContainer 的实现。started 不为 nil 时在容器启动后以容器 ID 调用，
+// 其返回的函数在容器退出后、收集日志之前调用
+func runContainer(ctx context.Context, cli *client.Client, cfg *container.Config, hostConfig *container.HostConfig, namePrefix string, started func(id string) func()) (*RunResult, error) {
+	LabelForRun(ctx, cfg)
+	name := containerName(namePrefix)
+	res, err := cli.ContainerCreate(ctx, client.ContainerCreateOptions{
+		Config:     cfg,
+		HostConfig: hostConfig,
+		Name:       name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建容器 %s 失败: %w", name, err)
+	}
+	defer removeContainer(cli, res.ID)
+
+	result := &RunResult{Name: name, ContainerID: res.ID, StartedAt: time.Now()}
+	if _, err := cli.ContainerStart(ctx, res.ID, client.ContainerStartOptions{}); err != nil {
+		return nil, fmt.Errorf("启动容器 %s 失败: %w", name, err)
+	}
+	stopTelemetry := StartTelemetry(ctx, cli, res.ID, name, namePrefix)
+	finish := func() {}
+	if started != nil {
+		finish = started(res.ID)
+	}
+
+	wait := cli.ContainerWait(ctx, res.ID, client.ContainerWaitOptions{
+		Condition: container.WaitConditionNotRunning,
+	})
+	select {
+	case err := <-wait.Error:
+		finish()
+		stopTelemetry()
+		return nil, fmt.Errorf("等待容器 %s 退出失败: %w", name, err)
+	case status := <-wait.Result:
+		result.StatusCode = status.StatusCode
+	}
+	result.FinishedAt = time.Now()
+	finish()
+	stopTelemetry()
+
+	if err := collectLogs(ctx, cli, result); err != nil {
+		return nil, err
+	}
+	inspect, err := cli.ContainerInspect(ctx, res.ID, client.ContainerInspectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("查询容器 %s 状态失败: %w", name, err)
+	}
+	if inspect.Container.State != nil {
+		result.OOMKilled = inspect.Container.State.OOMKilled
+	}
+
+	if report := ReportFrom(ctx); report != nil {
+		report.AddRun(result)
+	}
+	return result, nil
+}
+
+// LogLine 为带时间戳的一行容器输出
+type LogLine struct {
+	At     time.Time `json:"at"`
+	Stream string    `json:"stream"`
+	Text   string    `json:"text"`
+}
+
+// collectLogs 读取带时间戳的容器日志，Stdout/Stderr 保存去掉时间戳的原始输出，Lines 按时间排序
+func collectLogs(ctx context.Context, cli *client.Client, result *RunResult) error {
+	logs, err := cli.ContainerLogs(ctx, result.ContainerID, client.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+	})
+	if err != nil {
+		return fmt.Errorf("读取容器 %s 日志失败: %w", result.Name, err)
+	}
+	defer logs.Close()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, logs); err != nil {
+		return fmt.Errorf("解析容器 %s 日志失败: %w", result.Name, err)
+	}
+	var outLines, errLines []LogLine
+	result.Stdout, outLines = splitTimestamps(stdout.String(), "stdout")
+	result.Stderr, errLines = splitTimestamps(stderr.String(), "stderr")
+	result.Lines = append(outLines, errLines...)
+	sort.SliceStable(result.Lines, func(i, j int) bool {
+		return result.Lines[i].At.Before(result.Lines[j].At)
+	})
+	return nil
+}
+
+// splitTimestamps 拆出 docker logs 每行开头的 RFC3339Nano 时间戳，返回去掉时间戳的文本与逐行记录
+func splitTimestamps(raw, stream string) (string, []LogLine) {
+	var text strings.Builder
+	var lines []LogLine
+	for _, line := range strings.SplitAfter(raw, "\n") {
+		if line == "" {
+			continue
+		}
+		ts, rest, found := strings.Cut(line, " ")
+		at, err := time.Parse(time.RFC3339Nano, ts)
+		if !found || err != nil {
+			rest = line
+		}
+		text.WriteString(rest)
+		lines = append(lines, LogLine{At: at, Stream: stream, Text: strings.TrimRight(rest, "\n")})
+	}
+	return text.String(), lines
+}
+
+// removeContainer 使用独立的 context 删除容器，保证主流程超时后也能清理
+func removeContainer(cli *client.Client, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := cli.ContainerRemove(ctx, id, client.ContainerRemoveOptions{Force: true}); err != nil {
+		log.Printf("删除容器 %s 失败: %v", id, err)
+	}
+}
+
+// LogRunResult 打印容器的输出、退出码与运行时长
+func LogRunResult(title string, result *RunResult) {
+	logOutput(title, result.Stdout, result.Stderr)
+	log.Printf("[%s] 容器 %s 退出码=%d OOMKilled=%t 耗时=%s",
+		title, result.Name, result.StatusCode, result.OOMKilled, result.Duration().Round(time.Millisecond))
+}
+
+// logOutput 逐行打印标准输出与标准错误，空行会被忽略
+func logOutput(title, stdout, stderr string) {
+	for _, line := range strings.Split(stdout, "\n") {
+		if line != "" {
+			log.Printf("[%s] %s", title, line)
+		}
+	}
+	for _, line := range strings.Split(stderr, "\n") {
+		if line != "" {
+			log.Printf("[%s][stderr] %s", title, line)
+		}
+	}
+}
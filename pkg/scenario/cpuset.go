@@ -0,0 +1,124 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// Topology 为 daemon 所在宿主机的在线 CPU 与 NUMA 节点
+type Topology struct {
+	CPUs []int `json:"cpus"`
+	Mems []int `json:"mems"`
+}
+
+// topologyScript 读取宿主机在线 CPU 与内存节点；容器内的 sysfs 反映宿主机拓扑，
+// 没有 NUMA 信息的内核只有节点 0
+const topologyScript = `cat /sys/devices/system/cpu/online
+cat /sys/devices/system/node/online 2>/dev/null || echo 0`
+
+// HostTopology 在探测容器内读取宿主机拓扑，daemon 可以是远程主机
+func HostTopology(ctx context.Context, cli *client.Client, image string) (Topology, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := RunControlledContainer(ctx, cli, &container.Config{
+		Image: image,
+		Cmd:   []string{"sh", "-c", topologyScript},
+	}, &container.HostConfig{}, "topology")
+	if err != nil {
+		return Topology{}, err
+	}
+	if result.StatusCode != 0 {
+		return Topology{}, fmt.Errorf("探测容器退出码 %d: %s", result.StatusCode, strings.TrimSpace(result.Stderr))
+	}
+	lines := strings.Fields(result.Stdout)
+	if len(lines) != 2 {
+		return Topology{}, fmt.Errorf("无法解析拓扑输出 %q", result.Stdout)
+	}
+	var topo Topology
+	if topo.CPUs, err = ParseCPUList(lines[0]); err != nil {
+		return Topology{}, fmt.Errorf("解析在线 CPU 失败: %w", err)
+	}
+	if topo.Mems, err = ParseCPUList(lines[1]); err != nil {
+		return Topology{}, fmt.Errorf("解析在线内存节点失败: %w", err)
+	}
+	return topo, nil
+}
+
+// Validate 检查 CpusetCpus/CpusetMems 是否为合法的列表且只包含宿主机在线的 CPU 与节点，空串表示不限制
+func (t Topology) Validate(cpus, mems string) error {
+	for _, set := range []struct {
+		field, value string
+		online       []int
+	}{
+		{"CpusetCpus", cpus, t.CPUs},
+		{"CpusetMems", mems, t.Mems},
+	} {
+		if set.value == "" {
+			continue
+		}
+		ids, err := ParseCPUList(set.value)
+		if err != nil {
+			return fmt.Errorf("%s %q 不合法: %w", set.field, set.value, err)
+		}
+		for _, id := range ids {
+			if !slices.Contains(set.online, id) {
+				return fmt.Errorf("%s %q 包含宿主机不存在的 %d，在线范围为 %s",
+					set.field, set.value, id, FormatCPUList(set.online))
+			}
+		}
+	}
+	return nil
+}
+
+// ParseCPUList 解析内核 cpulist 格式（如 "0-3,8,10-11"），返回去重后升序排列的编号
+func ParseCPUList(s string) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("无效的编号 %q", part)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil || end < start {
+				return nil, fmt.Errorf("无效的范围 %q", part)
+			}
+		}
+		for id := start; id <= end; id++ {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// FormatCPUList 把编号列表格式化为 cpulist，连续编号合并为区间
+func FormatCPUList(ids []int) string {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	var parts []string
+	for i := 0; i < len(ids); {
+		j := i
+		for j+1 < len(ids) && ids[j+1] == ids[j]+1 {
+			j++
+		}
+		if j == i {
+			parts = append(parts, strconv.Itoa(ids[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", ids[i], ids[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,66 @@
+package scenario
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{in: "0-3,8,10-11", want: []int{0, 1, 2, 3, 8, 10, 11}},
+		{in: "3,1,1-2\n", want: []int{1, 2, 3}},
+		{in: "", want: nil},
+		{in: "2-1", wantErr: true},
+		{in: "a", wantErr: true},
+		{in: "1,", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCPUList(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCPUList(%q) err = %v", tt.in, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseCPUList(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		if !tt.wantErr && len(got) > 0 {
+			if back, _ := ParseCPUList(FormatCPUList(got)); !slices.Equal(back, got) {
+				t.Errorf("FormatCPUList(%v) = %q does not round-trip", got, FormatCPUList(got))
+			}
+		}
+	}
+	if got := FormatCPUList([]int{5, 0, 1, 2, 7, 8}); got != "0-2,5,7-8" {
+		t.Errorf("FormatCPUList = %q", got)
+	}
+}
+
+func TestTopologyValidate(t *testing.T) {
+	topo := Topology{CPUs: []int{0, 1, 2, 3}, Mems: []int{0}}
+	tests := []struct {
+		cpus, mems string
+		wantErr    string
+	}{
+		{cpus: "1-2", mems: "0"},
+		{cpus: "", mems: ""},
+		{cpus: "2-5", wantErr: "包含宿主机不存在的 4"},
+		{mems: "1", wantErr: "CpusetMems"},
+		{cpus: "0-", wantErr: "不合法"},
+	}
+	for _, tt := range tests {
+		err := topo.Validate(tt.cpus, tt.mems)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("Validate(%q, %q) = %v", tt.cpus, tt.mems, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Validate(%q, %q) = %v, want containing %q", tt.cpus, tt.mems, err, tt.wantErr)
+		}
+	}
+}
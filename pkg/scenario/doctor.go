@@ -0,0 +1,278 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// ProbeImage 为宿主机探测使用的轻量镜像
+const ProbeImage = "docker.io/library/alpine"
+
+// HostFacts 记录影响资源限制行为的宿主机信息
+type HostFacts struct {
+	DaemonVersion     string   `json:"daemonVersion"`
+	APIVersion        string   `json:"apiVersion"`
+	KernelVersion     string   `json:"kernelVersion"`
+	OperatingSystem   string   `json:"operatingSystem"`
+	CgroupVersion     string   `json:"cgroupVersion"`
+	CgroupDriver      string   `json:"cgroupDriver"`
+	StorageDriver     string   `json:"storageDriver"`
+	BackingFilesystem string   `json:"backingFilesystem,omitempty"`
+	NCPU              int      `json:"ncpu"`
+	MemTotal          int64    `json:"memTotal"`
+	MemoryLimit       bool     `json:"memoryLimit"`
+	SwapLimit         bool     `json:"swapLimit"`
+	CPUCfsQuota       bool     `json:"cpuCfsQuota"`
+	CPUShares         bool     `json:"cpuShares"`
+	CPUSet            bool     `json:"cpuSet"`
+	PidsLimit         bool     `json:"pidsLimit"`
+	Controllers       []string `json:"controllers,omitempty"`
+	ControllerErr     string   `json:"controllerErr,omitempty"`
+	StorageOptErr     string   `json:"storageOptErr,omitempty"`
+	Warnings          []string `json:"warnings,omitempty"`
+}
+
+// CollectHostFacts 查询 daemon 的 Info/Version，并用探测容器读取 cgroup 控制器、尝试 StorageOpt。
+// probeImage 需要提前拉取
+func CollectHostFacts(ctx context.Context, cli *client.Client, probeImage string) (*HostFacts, error) {
+	info, err := cli.Info(ctx, client.InfoOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("查询 daemon Info 失败: %w", err)
+	}
+	version, err := cli.ServerVersion(ctx, client.ServerVersionOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("查询 daemon Version 失败: %w", err)
+	}
+	i := info.Info
+	facts := &HostFacts{
+		DaemonVersion:   version.Version,
+		APIVersion:      version.APIVersion,
+		KernelVersion:   i.KernelVersion,
+		OperatingSystem: i.OperatingSystem,
+		CgroupVersion:   i.CgroupVersion,
+		CgroupDriver:    i.CgroupDriver,
+		StorageDriver:   i.Driver,
+		NCPU:            i.NCPU,
+		MemTotal:        i.MemTotal,
+		MemoryLimit:     i.MemoryLimit,
+		SwapLimit:       i.SwapLimit,
+		CPUCfsQuota:     i.CPUCfsQuota && i.CPUCfsPeriod,
+		CPUShares:       i.CPUShares,
+		CPUSet:          i.CPUSet,
+		PidsLimit:       i.PidsLimit,
+		Warnings:        i.Warnings,
+	}
+	for _, kv := range i.DriverStatus {
+		if kv[0] == "Backing Filesystem" {
+			facts.BackingFilesystem = kv[1]
+		}
+	}
+
+	controllers, err := probeControllers(ctx, cli, probeImage)
+	if err != nil {
+		facts.ControllerErr = err.Error()
+	}
+	facts.Controllers = controllers
+	if err := probeStorageOpt(ctx, cli, probeImage); err != nil {
+		facts.StorageOptErr = err.Error()
+	}
+	return facts, nil
+}
+
+// probeControllers 在探测容器内读取可用的 cgroup 控制器，v2 读取 cgroup.controllers，v1 列出层级目录
+func probeControllers(ctx context.Context, cli *client.Client, image string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := RunControlledContainer(ctx, cli, &container.Config{
+		Image: image,
+		Cmd:   []string{"sh", "-c", "cat /sys/fs/cgroup/cgroup.controllers 2>/dev/null || ls /sys/fs/cgroup"},
+	}, &container.HostConfig{}, "doctor-cgroup")
+	if err != nil {
+		return nil, err
+	}
+	if result.StatusCode != 0 {
+		return nil, fmt.Errorf("探测容器退出码 %d: %s", result.StatusCode, strings.TrimSpace(result.Stderr))
+	}
+	return parseControllers(result.Stdout), nil
+}
+
+// parseControllers 解析控制器列表，v1 下把 cpu,cpuacct 这样的合并目录拆开
+func parseControllers(out string) []string {
+	seen := map[string]bool{}
+	var controllers []string
+	for _, field := range strings.Fields(out) {
+		for _, name := range strings.Split(field, ",") {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				controllers = append(controllers, name)
+			}
+		}
+	}
+	return controllers
+}
+
+// probeStorageOpt 尝试创建一个带 StorageOpt size 的容器，daemon 拒绝时返回其错误
+func probeStorageOpt(ctx context.Context, cli *client.Client, image string) error {
+	res, err := cli.ContainerCreate(ctx, client.ContainerCreateOptions{
+		Config: &container.Config{Image: image, Cmd: []string{"true"}},
+		HostConfig: &container.HostConfig{
+			StorageOpt: map[string]string{"size": "64M"},
+		},
+		Name: containerName("doctor-storage-opt"),
+	})
+	if err != nil {
+		return err
+	}
+	removeContainer(cli, res.ID)
+	return nil
+}
+
+// CheckStatus 为单项检查的结论
+type CheckStatus string
+
+const (
+	CheckOK   CheckStatus = "ok"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+)
+
+// storageOptScenarios 为通过 StorageOpt size 限制系统盘的场景
+var storageOptScenarios = []string{"rootfs-probe", "volume-fill", "volume-expand"}
+
+// HostCheck 为一项宿主机能力检查，Required 的检查失败意味着依赖它的场景无法运行。
+// Scenarios 中的每一项可以是场景分组（如 cpu），也可以是单个场景名（如 rootfs-probe）
+type HostCheck struct {
+	Name      string      `json:"name"`
+	Status    CheckStatus `json:"status"`
+	Required  bool        `json:"required"`
+	Scenarios []string    `json:"scenarios"`
+	Detail    string      `json:"detail"`
+}
+
+// EvaluateHost 根据宿主机信息给出逐项检查结论
+func EvaluateHost(f *HostFacts) []HostCheck {
+	var checks []HostCheck
+	add := func(name string, status CheckStatus, required bool, scenarios []string, format string, args ...any) {
+		checks = append(checks, HostCheck{
+			Name:      name,
+			Status:    status,
+			Required:  required,
+			Scenarios: scenarios,
+			Detail:    fmt.Sprintf(format, args...),
+		})
+	}
+	pick := func(ok bool, otherwise CheckStatus) CheckStatus {
+		if ok {
+			return CheckOK
+		}
+		return otherwise
+	}
+
+	if f.CgroupVersion == "2" {
+		add("cgroup-version", CheckOK, false, []string{"cpu", "memory"}, "cgroup v2（驱动 %s）", f.CgroupDriver)
+	} else {
+		add("cgroup-version", CheckWarn, false, []string{"cpu", "memory"},
+			"cgroup v%s（驱动 %s），场景会回退读取 v1 的 cpu.cfs_*/memory.limit_in_bytes", f.CgroupVersion, f.CgroupDriver)
+	}
+
+	add("memory-limit", pick(f.MemoryLimit, CheckFail), true, []string{"memory", "volume", "health", "stack", "update", "cgroup"},
+		"daemon 报告 MemoryLimit=%t", f.MemoryLimit)
+	add("swap-limit", pick(f.SwapLimit, CheckWarn), false, []string{"memory"},
+		"SwapLimit=%t，未开启 swap accounting 时 MemorySwap 会被忽略，内存压测可能先换出而不是 OOM", f.SwapLimit)
+	add("cpu-cfs", pick(f.CPUCfsQuota, CheckFail), true, []string{"cpu", "volume", "health", "stack", "update", "cgroup"},
+		"CFS quota/period=%t，NanoCPUs 与 CPUQuota 依赖该能力", f.CPUCfsQuota)
+	add("cpu-shares", pick(f.CPUShares, CheckWarn), false, []string{"cpu"}, "CPUShares=%t", f.CPUShares)
+	add("cpuset", pick(f.CPUSet, CheckWarn), false, []string{"cpu"}, "CPUSet=%t，cpuset-pinning 依赖该能力", f.CPUSet)
+	add("pids-limit", pick(f.PidsLimit, CheckWarn), false, nil, "PidsLimit=%t", f.PidsLimit)
+
+	switch {
+	case f.ControllerErr != "":
+		add("cgroup-controllers", CheckFail, true, []string{"cpu", "memory"}, "探测容器运行失败: %s", f.ControllerErr)
+	default:
+		var missing []string
+		for _, want := range []string{"cpu", "memory"} {
+			if !slices.Contains(f.Controllers, want) {
+				missing = append(missing, want)
+			}
+		}
+		if len(missing) > 0 {
+			add("cgroup-controllers", CheckFail, true, []string{"cpu", "memory"},
+				"容器内缺少控制器 %s（可用: %s）", strings.Join(missing, ","), strings.Join(f.Controllers, " "))
+		} else {
+			add("cgroup-controllers", CheckOK, true, []string{"cpu", "memory"}, "容器内可用: %s", strings.Join(f.Controllers, " "))
+		}
+	}
+
+	// 只有这几个场景的容器通过 StorageOpt size 限制系统盘，daemon 拒绝时它们无法创建容器；
+	// 其余 rootfs/volume 场景不设置 StorageOpt，不受影响，因此按场景名而不是分组关联
+	switch {
+	case f.StorageOptErr == "":
+		add("storage-opt", CheckOK, false, storageOptScenarios, "驱动 %s 接受 StorageOpt size", f.StorageDriver)
+	case f.StorageDriver == "overlay2":
+		add("storage-opt", CheckWarn, false, storageOptScenarios,
+			"overlay2（后端 %s）未启用 pquota，StorageOpt size 不可用，系统盘限额无法复现（rootfs-quota-* 以软限额代替）: %s",
+			f.BackingFilesystem, f.StorageOptErr)
+	default:
+		add("storage-opt", CheckWarn, false, storageOptScenarios,
+			"驱动 %s 拒绝 StorageOpt size: %s", f.StorageDriver, f.StorageOptErr)
+	}
+
+	for _, w := range f.Warnings {
+		add("daemon-warning", CheckWarn, false, nil, "%s", w)
+	}
+	return checks
+}
+
+// SupportedScenarios 汇总 families 中每个场景分组是否可运行（通常传入 Families()），
+// 任意一项 fail 或 warn 的检查都会让相关分组标记为不完整；不在 families 中的分组会被忽略
+func SupportedScenarios(checks []HostCheck, families []string) map[string]CheckStatus {
+	support := make(map[string]CheckStatus, len(families))
+	for _, family := range families {
+		support[family] = CheckOK
+	}
+	for _, c := range checks {
+		for _, family := range c.Scenarios {
+			if _, ok := support[family]; !ok {
+				continue
+			}
+			if c.Status == CheckFail || (c.Status == CheckWarn && support[family] == CheckOK) {
+				support[family] = c.Status
+			}
+		}
+	}
+	return support
+}
+
+// Blockers 返回使 s 无法运行的检查：关联 s 所属分组且失败的检查，以及按场景名直接关联 s 且不为 ok 的检查。
+// 按场景名关联的检查只描述该场景自身的依赖，warn 同样意味着它无法得到有意义的结果
+func Blockers(checks []HostCheck, s Scenario) []HostCheck {
+	family, name := Family(s), s.Name()
+	var blockers []HostCheck
+	for _, c := range checks {
+		switch {
+		case c.Status == CheckOK:
+		case slices.Contains(c.Scenarios, name):
+			blockers = append(blockers, c)
+		case c.Status == CheckFail && slices.Contains(c.Scenarios, family):
+			blockers = append(blockers, c)
+		}
+	}
+	return blockers
+}
+
+// RequiredFailures 返回失败的必需检查
+func RequiredFailures(checks []HostCheck) []HostCheck {
+	var failed []HostCheck
+	for _, c := range checks {
+		if c.Required && c.Status == CheckFail {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
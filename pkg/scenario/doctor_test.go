@@ -0,0 +1,116 @@
+package scenario
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseControllers(t *testing.T) {
+	v2 := parseControllers("cpuset cpu io memory hugetlb pids rdma misc\n")
+	if !slices.Equal(v2, []string{"cpuset", "cpu", "io", "memory", "hugetlb", "pids", "rdma", "misc"}) {
+		t.Fatalf("v2 controllers = %v", v2)
+	}
+	v1 := parseControllers("blkio\ncpu\ncpu,cpuacct\ncpuacct\nmemory\npids\n")
+	if !slices.Equal(v1, []string{"blkio", "cpu", "cpuacct", "memory", "pids"}) {
+		t.Fatalf("v1 controllers = %v", v1)
+	}
+}
+
+func findCheck(checks []HostCheck, name string) HostCheck {
+	for _, c := range checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return HostCheck{}
+}
+
+func TestEvaluateHostOverlayWithoutPquota(t *testing.T) {
+	facts := &HostFacts{
+		CgroupVersion: "2",
+		StorageDriver: "overlay2",
+		MemoryLimit:   true,
+		SwapLimit:     false,
+		CPUCfsQuota:   true,
+		CPUShares:     true,
+		CPUSet:        true,
+		PidsLimit:     true,
+		Controllers:   []string{"cpu", "memory", "pids"},
+		StorageOptErr: "--storage-opt is supported only for overlay over xfs with 'pquota' mount option",
+	}
+	checks := EvaluateHost(facts)
+
+	if c := findCheck(checks, "storage-opt"); c.Status != CheckWarn || c.Required {
+		t.Fatalf("storage-opt = %q required=%t, want optional warn", c.Status, c.Required)
+	}
+	if c := findCheck(checks, "swap-limit"); c.Status != CheckWarn {
+		t.Fatalf("swap-limit status = %q, want warn", c.Status)
+	}
+	if failed := RequiredFailures(checks); len(failed) != 0 {
+		t.Fatalf("required failures = %+v, want none", failed)
+	}
+	support := SupportedScenarios(checks, []string{"cpu", "rootfs", "logging"})
+	want := map[string]CheckStatus{"cpu": CheckOK, "rootfs": CheckOK, "logging": CheckOK}
+	if !maps.Equal(support, want) {
+		t.Fatalf("support = %v, want %v", support, want)
+	}
+}
+
+func TestEvaluateHostMissingRequired(t *testing.T) {
+	facts := &HostFacts{
+		CgroupVersion: "1",
+		MemoryLimit:   false,
+		CPUCfsQuota:   true,
+		Controllers:   []string{"cpu", "cpuacct"},
+	}
+	checks := EvaluateHost(facts)
+	var names []string
+	for _, c := range RequiredFailures(checks) {
+		names = append(names, c.Name)
+	}
+	if !slices.Equal(names, []string{"memory-limit", "cgroup-controllers"}) {
+		t.Fatalf("required failures = %v", names)
+	}
+	if SupportedScenarios(checks, []string{"memory"})["memory"] != CheckFail {
+		t.Fatal("memory scenarios should be unsupported")
+	}
+}
+
+// familyScenario 的分组为本包名 scenario
+type familyScenario struct {
+	Scenario
+	name string
+}
+
+func (f familyScenario) Name() string { return f.name }
+
+func TestBlockers(t *testing.T) {
+	checks := []HostCheck{
+		{Name: "storage-opt", Status: CheckWarn, Scenarios: []string{"rootfs-probe"}},
+		{Name: "swap-limit", Status: CheckWarn, Scenarios: []string{"scenario"}},
+		{Name: "memory-limit", Status: CheckFail, Scenarios: []string{"other"}},
+		{Name: "cpu-cfs", Status: CheckOK, Scenarios: []string{"rootfs-quota-stop"}},
+	}
+	cases := []struct {
+		name string
+		want []string
+	}{
+		{"rootfs-probe", []string{"storage-opt"}},
+		{"rootfs-quota-stop", nil},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, b := range Blockers(checks, familyScenario{name: c.name}) {
+			got = append(got, b.Name)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("Blockers(%s) = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	checks = append(checks, HostCheck{Name: "cgroup-controllers", Status: CheckFail, Scenarios: []string{"scenario"}})
+	if got := Blockers(checks, familyScenario{name: "rootfs-quota-stop"}); len(got) != 1 || got[0].Name != "cgroup-controllers" {
+		t.Errorf("Blockers with failing family check = %+v", got)
+	}
+}
@@ -0,0 +1,206 @@
+package scenario
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/go-connections/tlsconfig"
+	"github.com/moby/moby/client"
+)
+
+// 与 docker CLI 一致的环境变量与默认值
+const (
+	envDockerConfig  = "DOCKER_CONFIG"
+	envDockerContext = "DOCKER_CONTEXT"
+	defaultContext   = "default"
+)
+
+// Endpoint 描述场景实际连接的 Docker daemon，会记录在每份报告中
+type Endpoint struct {
+	// Context 为 docker CLI context 名称，直接指定 host 时为空
+	Context string `json:"context,omitempty"`
+	// Host 为 daemon 地址，例如 unix:///var/run/docker.sock、tcp://10.0.0.2:2376、ssh://user@host
+	Host string `json:"host"`
+	// Source 说明 Host 的来源：flag、env、context 或 default
+	Source string `json:"source"`
+	// TLSCertPath 为包含 ca.pem/cert.pem/key.pem 的目录，为空时不启用 TLS
+	TLSCertPath   string `json:"tlsCertPath,omitempty"`
+	SkipTLSVerify bool   `json:"skipTLSVerify,omitempty"`
+}
+
+// String 返回便于日志输出的端点描述
+func (e Endpoint) String() string {
+	s := e.Host
+	if e.Context != "" {
+		s = fmt.Sprintf("%s (context %s)", s, e.Context)
+	}
+	if e.TLSCertPath != "" {
+		s += " TLS"
+	}
+	return s
+}
+
+// contextMeta 为 docker CLI context 存储中的 meta.json
+type contextMeta struct {
+	Name      string `json:"Name"`
+	Endpoints map[string]struct {
+		Host          string `json:"Host"`
+		SkipTLSVerify bool   `json:"SkipTLSVerify"`
+	} `json:"Endpoints"`
+}
+
+// dockerConfigDir 返回 docker CLI 配置目录，优先 DOCKER_CONFIG，其次 ~/.docker
+func dockerConfigDir() string {
+	if dir := os.Getenv(envDockerConfig); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".docker"
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// contextDirName 为 context 在存储中的目录名，即名称的 sha256
+func contextDirName(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// currentContext 读取 config.json 中的 currentContext，文件不存在时返回空
+func currentContext(configDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取 docker 配置失败: %w", err)
+	}
+	var cfg struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "", fmt.Errorf("解析 docker 配置失败: %w", err)
+	}
+	return cfg.CurrentContext, nil
+}
+
+// loadContext 从 contexts/meta 读取指定 context 的 docker 端点，并关联 contexts/tls 下的证书目录
+func loadContext(configDir, name string) (Endpoint, error) {
+	dir := contextDirName(name)
+	data, err := os.ReadFile(filepath.Join(configDir, "contexts", "meta", dir, "meta.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Endpoint{}, fmt.Errorf("docker context %q 不存在", name)
+	}
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("读取 docker context %q 失败: %w", name, err)
+	}
+	var meta contextMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return Endpoint{}, fmt.Errorf("解析 docker context %q 失败: %w", name, err)
+	}
+	docker, ok := meta.Endpoints["docker"]
+	if !ok || docker.Host == "" {
+		return Endpoint{}, fmt.Errorf("docker context %q 未配置 docker 端点", name)
+	}
+	ep := Endpoint{Context: name, Host: docker.Host, Source: "context", SkipTLSVerify: docker.SkipTLSVerify}
+	tlsDir := filepath.Join(configDir, "contexts", "tls", dir, "docker")
+	if _, err := os.Stat(tlsDir); err == nil {
+		ep.TLSCertPath = tlsDir
+	}
+	return ep, nil
+}
+
+// ResolveEndpoint 按 docker CLI 的优先级选择 daemon：
+// -host > -context > DOCKER_HOST > DOCKER_CONTEXT > config.json 的 currentContext > 默认 socket。
+// -tls-cert-path 会覆盖所选端点的证书目录
+func ResolveEndpoint(opts Options) (Endpoint, error) {
+	if opts.Host != "" && opts.Context != "" {
+		return Endpoint{}, errors.New("-host 与 -context 不能同时指定")
+	}
+	var ep Endpoint
+	switch {
+	case opts.Host != "":
+		ep = Endpoint{Host: opts.Host, Source: "flag"}
+	case opts.Context == "" && os.Getenv(client.EnvOverrideHost) != "":
+		ep = Endpoint{Host: os.Getenv(client.EnvOverrideHost), Source: "env"}
+		if certPath := os.Getenv(client.EnvOverrideCertPath); certPath != "" {
+			ep.TLSCertPath = certPath
+			ep.SkipTLSVerify = os.Getenv(client.EnvTLSVerify) == ""
+		}
+	default:
+		configDir := dockerConfigDir()
+		name := opts.Context
+		if name == "" {
+			name = os.Getenv(envDockerContext)
+		}
+		if name == "" {
+			cur, err := currentContext(configDir)
+			if err != nil {
+				return Endpoint{}, err
+			}
+			name = cur
+		}
+		if name == "" || name == defaultContext {
+			ep = Endpoint{Host: client.DefaultDockerHost, Source: "default"}
+			break
+		}
+		loaded, err := loadContext(configDir, name)
+		if err != nil {
+			return Endpoint{}, err
+		}
+		ep = loaded
+	}
+	if opts.TLSCertPath != "" {
+		ep.TLSCertPath = opts.TLSCertPath
+		ep.SkipTLSVerify = !opts.TLSVerify
+	}
+	return ep, nil
+}
+
+// clientOpts 把端点转换为 Docker 客户端选项；ssh:// 端点通过远端的 docker system dial-stdio 建立连接
+func (e Endpoint) clientOpts() ([]client.Opt, error) {
+	var opts []client.Opt
+	if e.TLSCertPath != "" {
+		tlsc, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:             existingFile(filepath.Join(e.TLSCertPath, "ca.pem")),
+			CertFile:           existingFile(filepath.Join(e.TLSCertPath, "cert.pem")),
+			KeyFile:            existingFile(filepath.Join(e.TLSCertPath, "key.pem")),
+			InsecureSkipVerify: e.SkipTLSVerify,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("加载 TLS 证书 %s 失败: %w", e.TLSCertPath, err)
+		}
+		opts = append(opts, client.WithHTTPClient(&http.Client{
+			Transport:     &http.Transport{TLSClientConfig: tlsc},
+			CheckRedirect: client.CheckRedirect,
+		}))
+	}
+	hostURL, err := client.ParseHostURL(e.Host)
+	if err != nil {
+		return nil, fmt.Errorf("解析 daemon 地址 %s 失败: %w", e.Host, err)
+	}
+	if hostURL.Scheme == "ssh" {
+		dial, err := sshDialer(e.Host)
+		if err != nil {
+			return nil, err
+		}
+		// 与 docker CLI 相同：HTTP 层使用占位地址，真正的连接由 ssh 子进程提供
+		return append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dial)), nil
+	}
+	return append(opts, client.WithHost(e.Host)), nil
+}
+
+// existingFile 在文件存在时返回其路径，否则返回空，缺省的证书由 tlsconfig 按默认行为处理
+func existingFile(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,191 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// writeDockerConfig 在临时目录中构造 docker CLI 配置：config.json 与若干 context
+func writeDockerConfig(t *testing.T, current string, contexts map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	data, _ := json.Marshal(map[string]string{"currentContext": current})
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for name, host := range contexts {
+		metaDir := filepath.Join(dir, "contexts", "meta", contextDirName(name))
+		if err := os.MkdirAll(metaDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		meta := map[string]any{
+			"Name":      name,
+			"Metadata":  map[string]string{},
+			"Endpoints": map[string]any{"docker": map[string]any{"Host": host, "SkipTLSVerify": false}},
+		}
+		data, _ := json.Marshal(meta)
+		if err := os.WriteFile(filepath.Join(metaDir, "meta.json"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	configDir := writeDockerConfig(t, "lab", map[string]string{
+		"lab":    "tcp://10.0.0.2:2376",
+		"remote": "ssh://ops@10.0.0.3",
+	})
+	tlsDir := filepath.Join(configDir, "contexts", "tls", contextDirName("lab"), "docker")
+	if err := os.MkdirAll(tlsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		opts       Options
+		env        map[string]string
+		wantHost   string
+		wantSource string
+		wantCtx    string
+		wantTLS    string
+		wantErr    string
+	}{
+		{name: "currentContext", wantHost: "tcp://10.0.0.2:2376", wantSource: "context", wantCtx: "lab", wantTLS: tlsDir},
+		{name: "context flag", opts: Options{Context: "remote"}, wantHost: "ssh://ops@10.0.0.3", wantSource: "context", wantCtx: "remote"},
+		{name: "DOCKER_CONTEXT", env: map[string]string{envDockerContext: "remote"}, wantHost: "ssh://ops@10.0.0.3", wantSource: "context", wantCtx: "remote"},
+		{name: "default context", opts: Options{Context: "default"}, wantHost: client.DefaultDockerHost, wantSource: "default"},
+		{name: "DOCKER_HOST beats currentContext", env: map[string]string{client.EnvOverrideHost: "tcp://1.2.3.4:2375"}, wantHost: "tcp://1.2.3.4:2375", wantSource: "env"},
+		{name: "context flag beats DOCKER_HOST", opts: Options{Context: "remote"}, env: map[string]string{client.EnvOverrideHost: "tcp://1.2.3.4:2375"}, wantHost: "ssh://ops@10.0.0.3", wantSource: "context", wantCtx: "remote"},
+		{name: "host flag", opts: Options{Host: "unix:///tmp/d.sock", TLSCertPath: "/certs", TLSVerify: true}, wantHost: "unix:///tmp/d.sock", wantSource: "flag", wantTLS: "/certs"},
+		{name: "host and context", opts: Options{Host: "tcp://x:1", Context: "lab"}, wantErr: "不能同时指定"},
+		{name: "unknown context", opts: Options{Context: "missing"}, wantErr: "不存在"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envDockerConfig, configDir)
+			t.Setenv(client.EnvOverrideHost, "")
+			t.Setenv(client.EnvOverrideCertPath, "")
+			t.Setenv(envDockerContext, "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			ep, err := ResolveEndpoint(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveEndpoint: %v", err)
+			}
+			if ep.Host != tt.wantHost || ep.Source != tt.wantSource || ep.Context != tt.wantCtx || ep.TLSCertPath != tt.wantTLS {
+				t.Fatalf("endpoint = %+v", ep)
+			}
+		})
+	}
+}
+
+// fakeDaemon 在 unix socket 上模拟 daemon 的 _ping、info 与 events 接口，并记录收到的请求路径
+type fakeDaemon struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (f *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.paths = append(f.paths, r.URL.Path)
+	f.mu.Unlock()
+	w.Header().Set("Api-Version", "1.44")
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/_ping":
+		w.Write([]byte("OK"))
+	case strings.HasSuffix(r.URL.Path, "/info"):
+		json.NewEncoder(w).Encode(map[string]any{"ID": "fake-id", "Name": "fake-host", "NCPU": 2, "CgroupVersion": "2"})
+	case strings.HasSuffix(r.URL.Path, "/events"):
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeDaemon) requested(suffix string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.paths {
+		if strings.HasSuffix(p, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// startFakeDaemon 在临时 unix socket 上启动 fakeDaemon，返回 socket 路径
+func startFakeDaemon(t *testing.T) (*fakeDaemon, string) {
+	t.Helper()
+	// unix socket 路径有长度限制，不使用 t.TempDir 的长路径
+	sockDir, err := os.MkdirTemp("", "ep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(sockDir) })
+	sock := filepath.Join(sockDir, "docker.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeDaemon{}
+	srv := &http.Server{Handler: fake}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return fake, sock
+}
+
+func TestNewDockerClientUsesContextSocket(t *testing.T) {
+	fake, sock := startFakeDaemon(t)
+	t.Setenv(envDockerConfig, writeDockerConfig(t, "", map[string]string{"fake": "unix://" + sock}))
+	t.Setenv(client.EnvOverrideHost, "")
+	t.Setenv(envDockerContext, "")
+	saved := Opts
+	t.Cleanup(func() { Opts = saved; ActiveEndpoint = Endpoint{} })
+	Opts = Options{Context: "fake"}
+
+	cli, err := NewDockerClient()
+	if err != nil {
+		t.Fatalf("NewDockerClient: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := cli.Ping(ctx, client.PingOptions{}); err != nil {
+		t.Fatalf("ping fake daemon: %v", err)
+	}
+	_, report := Begin(ctx, cli, "endpoint-test", nil)
+	report.stopMonitor()
+
+	if report.Endpoint.Context != "fake" || report.Endpoint.Host != "unix://"+sock {
+		t.Errorf("report endpoint = %+v", report.Endpoint)
+	}
+	if report.Host.Name != "fake-host" {
+		t.Errorf("host fingerprint = %+v, want fake-host", report.Host)
+	}
+	for _, path := range []string{"/_ping", "/info", "/events"} {
+		if !fake.requested(path) {
+			t.Errorf("fake daemon never received %s", path)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/client"
+)
+
+// LabelRunID 标记容器所属的场景运行，事件监听按该 label 过滤
+const LabelRunID = "test-docker.run-id"
+
+// eventSettle 为停止监听前的等待时间，容器删除后的 die/destroy 事件可能稍晚到达
+const eventSettle = 500 * time.Millisecond
+
+// watchedActions 为能够说明限额被触发的容器事件
+var watchedActions = []events.Action{
+	events.ActionOOM,
+	events.ActionDie,
+	events.ActionKill,
+	events.ActionDestroy,
+}
+
+// Event 为一条与场景运行相关的容器事件
+type Event struct {
+	At          time.Time         `json:"at"`
+	Action      string            `json:"action"`
+	ContainerID string            `json:"containerId"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+// EventMonitor 订阅带有指定运行 label 的容器事件
+type EventMonitor struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// WatchEvents 开始监听 runID 对应容器自 since 起的 oom/die/kill/destroy 事件，调用方需要调用 Stop
+func WatchEvents(ctx context.Context, cli *client.Client, runID string, since time.Time) *EventMonitor {
+	return watch(ctx, cli, make(client.Filters).Add("label", LabelRunID+"="+runID), since, watchedActions)
+}
+
+// WatchContainerEvents 开始监听单个容器自 since 起的指定事件，用于运行期间需要 watchedActions 之外事件的场景，
+// 调用方需要调用 Stop
+func WatchContainerEvents(ctx context.Context, cli *client.Client, containerID string, since time.Time, actions ...events.Action) *EventMonitor {
+	return watch(ctx, cli, make(client.Filters).Add("container", containerID), since, actions)
+}
+
+// watch 按 filters 订阅 actions 中的容器事件。订阅是异步建立的，很快失败的容器可能在连接建立之前
+// 就产生 oom/die 事件，因此从 since 开始回放，而不是只接收订阅之后的事件
+func watch(ctx context.Context, cli *client.Client, filters client.Filters, since time.Time, actions []events.Action) *EventMonitor {
+	ctx, cancel := context.WithCancel(ctx)
+	filters.Add("type", string(events.ContainerEventType))
+	for _, action := range actions {
+		filters.Add("event", string(action))
+	}
+	stream := cli.Events(ctx, client.EventsListOptions{Since: eventsSince(since), Filters: filters})
+
+	m := &EventMonitor{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(m.done)
+		for {
+			select {
+			case msg := <-stream.Messages:
+				m.mu.Lock()
+				m.events = append(m.events, Event{
+					At:          time.Unix(0, msg.TimeNano),
+					Action:      string(msg.Action),
+					ContainerID: msg.Actor.ID,
+					Attributes:  msg.Actor.Attributes,
+				})
+				m.mu.Unlock()
+			case err := <-stream.Err:
+				if err != nil && !errors.Is(err, context.Canceled) {
+					m.mu.Lock()
+					m.err = err
+					m.mu.Unlock()
+				}
+				return
+			}
+		}
+	}()
+	return m
+}
+
+// eventsSince 把时间格式化为事件 API 接受的 “秒.纳秒” 时间戳
+func eventsSince(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// Stop 等待尾部事件到达后停止监听，返回收集到的事件
+func (m *EventMonitor) Stop() ([]Event, error) {
+	time.Sleep(eventSettle)
+	m.cancel()
+	<-m.done
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Event(nil), m.events...), m.err
+}
+
+// Snapshot 返回目前已收到的事件，不停止监听
+func (m *EventMonitor) Snapshot() []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Event(nil), m.events...)
+}
+
+// LabelForRun 为容器打上 ctx 中报告的运行 label，便于事件监听过滤；自行创建容器的场景应在创建前调用
+func LabelForRun(ctx context.Context, cfg *container.Config) {
+	report := ReportFrom(ctx)
+	if report == nil {
+		return
+	}
+	if cfg.Labels == nil {
+		cfg.Labels = map[string]string{}
+	}
+	cfg.Labels[LabelRunID] = report.RunID
+}
+
+// Begin 创建场景报告并挂到 ctx 上，记录目标 daemon，同时开始监听本次运行的容器事件。
+// 事件会在 Publish 时合并进容器结果与报告时间线；配置了 -metrics-addr 时同时启动指标服务
+func Begin(ctx context.Context, cli *client.Client, name string, params map[string]string) (context.Context, *Report) {
+	startMetricsServer()
+	report := NewReport(name, params)
+	if id, _ := ctx.Value(runIDKey{}).(string); id != "" {
+		report.RunID = id
+	}
+	report.Endpoint = ActiveEndpoint
+	if report.Endpoint.Host == "" {
+		report.Endpoint = Endpoint{Host: cli.DaemonHost()}
+	}
+	if host, err := FingerprintHost(ctx, cli); err != nil {
+		log.Printf("计算宿主机指纹失败: %v", err)
+	} else {
+		report.Host = host
+	}
+	// 监听不随运行的 ctx 取消，运行超时后 Cleanup 删除容器产生的 destroy 等事件同样会被收集，
+	// 由 Publish 中的 stopMonitor 结束
+	report.monitor = WatchEvents(context.WithoutCancel(ctx), cli, report.RunID, report.StartedAt)
+	return WithReport(ctx, report), report
+}
+
+// stopMonitor 停止事件监听并把事件合并进报告
+func (r *Report) stopMonitor() {
+	if r.monitor == nil {
+		return
+	}
+	evts, err := r.monitor.Stop()
+	r.monitor = nil
+	if err != nil {
+		log.Printf("监听容器事件失败: %v", err)
+	}
+	r.AddEvents(evts)
+}
+
+// ContainerEvents 返回指定容器目前已收到的事件，按时间排序。运行中的事件在 Publish 前
+// 还没有合并进报告，场景的 Verify 阶段应通过它读取
+func (r *Report) ContainerEvents(containerID string) []Event {
+	r.mu.Lock()
+	evts := append([]Event(nil), r.Events...)
+	monitor := r.monitor
+	r.mu.Unlock()
+	if monitor != nil {
+		evts = append(evts, monitor.Snapshot()...)
+	}
+	var matched []Event
+	for _, evt := range evts {
+		if evt.ContainerID == containerID {
+			matched = append(matched, evt)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].At.Before(matched[j].At) })
+	return matched
+}
+
+// AddEvents 把事件挂到对应的容器结果上，并追加到时间线
+func (r *Report) AddEvents(evts []Event) {
+	r.mu.Lock()
+	r.Events = append(r.Events, evts...)
+	for _, run := range r.Runs {
+		for _, evt := range evts {
+			if evt.ContainerID == run.ContainerID {
+				run.Events = append(run.Events, evt)
+			}
+		}
+	}
+	r.mu.Unlock()
+	for _, evt := range evts {
+		detail := evt.Attributes["name"]
+		if code, ok := evt.Attributes["exitCode"]; ok {
+			detail += " exitCode=" + code
+		}
+		if sig, ok := evt.Attributes["signal"]; ok {
+			detail += " signal=" + sig
+		}
+		r.Mark(evt.At, "event", "%s %s", evt.Action, detail)
+	}
+}
@@ -0,0 +1,89 @@
+package scenario
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+func TestAddEventsAttachesToRuns(t *testing.T) {
+	r := NewReport("demo", nil)
+	start := r.StartedAt
+	run := &RunResult{Name: "fill", ContainerID: "abc", StartedAt: start, FinishedAt: start.Add(time.Second)}
+	r.AddRun(run)
+
+	r.AddEvents([]Event{
+		{At: start.Add(500 * time.Millisecond), Action: "oom", ContainerID: "abc", Attributes: map[string]string{"name": "fill"}},
+		{At: start.Add(600 * time.Millisecond), Action: "die", ContainerID: "abc", Attributes: map[string]string{"name": "fill", "exitCode": "137"}},
+		{At: start.Add(700 * time.Millisecond), Action: "die", ContainerID: "other"},
+	})
+
+	if len(run.Events) != 2 {
+		t.Fatalf("run has %d events, want 2", len(run.Events))
+	}
+	if len(r.Events) != 3 {
+		t.Fatalf("report has %d events, want 3", len(r.Events))
+	}
+	var found bool
+	for _, entry := range r.sortedTimeline() {
+		if entry.Kind == "event" && entry.Detail == "die fill exitCode=137" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("die event missing from timeline: %+v", r.Timeline)
+	}
+}
+
+func TestLabelForRun(t *testing.T) {
+	cfg := &container.Config{}
+	LabelForRun(context.Background(), cfg)
+	if cfg.Labels != nil {
+		t.Fatalf("labels set without a report: %v", cfg.Labels)
+	}
+
+	r := NewReport("demo", nil)
+	LabelForRun(WithReport(context.Background(), r), cfg)
+	if got := cfg.Labels[LabelRunID]; got != r.RunID {
+		t.Fatalf("run label = %q, want %q", got, r.RunID)
+	}
+}
+
+func TestContainerEventsIncludesLiveMonitor(t *testing.T) {
+	r := NewReport("demo", nil)
+	start := r.StartedAt
+	r.monitor = &EventMonitor{events: []Event{
+		{At: start.Add(2 * time.Second), Action: "start", ContainerID: "abc"},
+		{At: start.Add(time.Second), Action: "die", ContainerID: "abc"},
+		{At: start.Add(time.Second), Action: "die", ContainerID: "other"},
+	}}
+	r.Events = []Event{{At: start, Action: "oom", ContainerID: "abc"}}
+
+	got := r.ContainerEvents("abc")
+	var actions []string
+	for _, e := range got {
+		actions = append(actions, e.Action)
+	}
+	if want := "oom die start"; strings.Join(actions, " ") != want {
+		t.Errorf("ContainerEvents actions = %v, want %s", actions, want)
+	}
+}
+
+func TestEventsSince(t *testing.T) {
+	cases := []struct {
+		at   time.Time
+		want string
+	}{
+		{time.Unix(1700000000, 0), "1700000000.000000000"},
+		{time.Unix(1700000000, 5000), "1700000000.000005000"},
+		{time.Unix(1700000000, 999999999), "1700000000.999999999"},
+	}
+	for _, c := range cases {
+		if got := eventsSince(c.at); got != c.want {
+			t.Errorf("eventsSince(%v) = %q, want %q", c.at, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/network"
+	"github.com/moby/moby/client"
+)
+
+// KeepAliveCmd 让容器常驻，探测步骤全部通过 exec 在容器内执行
+var KeepAliveCmd = []string{"sh", "-c", "while :; do sleep 3600; done"}
+
+// ProbeStep 描述在常驻容器内执行的一个探测步骤
+type ProbeStep struct {
+	Name    string
+	Cmd     []string
+	Timeout time.Duration
+	// User 为执行步骤的用户，为空时使用容器配置的用户
+	User string
+}
+
+// ShellStep 构造一个通过 sh -c 执行脚本的探测步骤
+func ShellStep(name, script string) ProbeStep {
+	return ProbeStep{Name: name, Cmd: []string{"sh", "-c", script}}
+}
+
+// ReadFilesStep 构造一个读取文件内容的探测步骤，不存在的文件会被跳过，
+// 便于同时兼容 cgroup v1 与 v2 的文件布局
+func ReadFilesStep(name string, paths ...string) ProbeStep {
+	var b strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&b, "if [ -r %[1]q ]; then echo \"== %[1]s\"; cat %[1]q; fi; ", p)
+	}
+	return ShellStep(name, b.String())
+}
+
+// ProbeResult 记录一个探测步骤的退出码与输出
+type ProbeResult struct {
+	Name       string    `json:"name"`
+	Cmd        []string  `json:"cmd"`
+	ExitCode   int       `json:"exitCode"`
+	Stdout     string    `json:"stdout"`
+	Stderr     string    `json:"stderr"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+// Session 表示一个保持运行的受限容器，可以在其中多次执行探测步骤
+type Session struct {
+	cli           *client.Client
+	stopTelemetry func()
+	ID            string
+	Name          string
+}
+
+// StartSession 创建并启动一个常驻的受限容器，cfg.Cmd 为空时使用 KeepAliveCmd。
+// 调用方需要在结束时调用 Close 删除容器
+func StartSession(ctx context.Context, cli *client.Client, cfg *container.Config, hostConfig *container.HostConfig, namePrefix string) (*Session, error) {
+	return StartNetworkedSession(ctx, cli, cfg, hostConfig, nil, namePrefix)
+}
+
+// StartNetworkedSession 与 StartSession 相同，创建时额外指定各网络的端点配置（如网络内的别名）
+func StartNetworkedSession(ctx context.Context, cli *client.Client, cfg *container.Config, hostConfig *container.HostConfig, netConfig *network.NetworkingConfig, namePrefix string) (*Session, error) {
+	if len(cfg.Cmd) == 0 {
+		cfg.Cmd = KeepAliveCmd
+	}
+	LabelForRun(ctx, cfg)
+	name := containerName(namePrefix)
+	res, err := cli.ContainerCreate(ctx, client.ContainerCreateOptions{
+		Config:           cfg,
+		HostConfig:       hostConfig,
+		NetworkingConfig: netConfig,
+		Name:             name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建容器 %s 失败: %w", name, err)
+	}
+	s := &Session{cli: cli, ID: res.ID, Name: name}
+	if _, err := cli.ContainerStart(ctx, res.ID, client.ContainerStartOptions{}); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("启动容器 %s 失败: %w", name, err)
+	}
+	inspect, err := cli.ContainerInspect(ctx, res.ID, client.ContainerInspectOptions{})
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("查询容器 %s 状态失败: %w", name, err)
+	}
+	if inspect.Container.State == nil || !inspect.Container.State.Running {
+		s.Close()
+		return nil, fmt.Errorf("容器 %s 启动后未处于运行状态", name)
+	}
+	s.stopTelemetry = StartTelemetry(ctx, cli, res.ID, name, namePrefix)
+	return s, nil
+}
+
+// Exec 在容器内执行一个探测步骤，等待其结束并收集退出码与输出。
+// 非 0 退出码属于探测结果的一部分，只有 API 调用失败才返回 error
+func (s *Session) Exec(ctx context.Context, step ProbeStep) (ProbeResult, error) {
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+	result := ProbeResult{Name: step.Name, Cmd: step.Cmd, StartedAt: time.Now()}
+
+	created, err := s.cli.ExecCreate(ctx, s.ID, client.ExecCreateOptions{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          step.Cmd,
+		User:         step.User,
+	})
+	if err != nil {
+		return result, fmt.Errorf("创建探测步骤 %s 失败: %w", step.Name, err)
+	}
+	attach, err := s.cli.ExecAttach(ctx, created.ID, client.ExecAttachOptions{})
+	if err != nil {
+		return result, fmt.Errorf("连接探测步骤 %s 失败: %w", step.Name, err)
+	}
+	defer attach.Close()
+
+	// hijack 后的连接不感知 ctx，超时时主动关闭连接以结束读取
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			attach.Close()
+		case <-done:
+		}
+	}()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, attach.Reader); err != nil && ctx.Err() == nil {
+		return result, fmt.Errorf("读取探测步骤 %s 输出失败: %w", step.Name, err)
+	}
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("探测步骤 %s 超时: %w", step.Name, ctx.Err())
+	}
+
+	// 输出流结束后 exec 可能仍短暂处于 Running 状态，轮询到真正退出为止
+	for {
+		inspect, err := s.cli.ExecInspect(ctx, created.ID, client.ExecInspectOptions{})
+		if err != nil {
+			return result, fmt.Errorf("查询探测步骤 %s 状态失败: %w", step.Name, err)
+		}
+		if !inspect.Running {
+			result.ExitCode = inspect.ExitCode
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return result, fmt.Errorf("探测步骤 %s 超时: %w", step.Name, ctx.Err())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	result.FinishedAt = time.Now()
+
+	if report := ReportFrom(ctx); report != nil {
+		report.AddProbe(result)
+	}
+	return result, nil
+}
+
+// RunProbes 依次执行多个探测步骤，遇到 API 错误时停止并返回已完成的结果
+func (s *Session) RunProbes(ctx context.Context, steps []ProbeStep) ([]ProbeResult, error) {
+	results := make([]ProbeResult, 0, len(steps))
+	for _, step := range steps {
+		result, err := s.Exec(ctx, step)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Close 停止资源采样并强制删除常驻容器
+func (s *Session) Close() {
+	if s.stopTelemetry != nil {
+		s.stopTelemetry()
+	}
+	removeContainer(s.cli, s.ID)
+}
+
+// LogProbeResult 打印探测步骤的输出与退出码
+func LogProbeResult(result ProbeResult) {
+	logOutput(result.Name, result.Stdout, result.Stderr)
+	log.Printf("[%s] 退出码=%d 耗时=%s", result.Name, result.ExitCode, result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond))
+}
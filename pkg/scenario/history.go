@@ -0,0 +1,336 @@
+package scenario
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// HostFingerprint 标识运行场景的宿主机，Hash 相同的运行才具有可比性
+type HostFingerprint struct {
+	Name          string `json:"name"`
+	Kernel        string `json:"kernel"`
+	CgroupVersion string `json:"cgroupVersion"`
+	StorageDriver string `json:"storageDriver"`
+	NCPU          int    `json:"ncpu"`
+	MemTotal      int64  `json:"memTotal"`
+	Hash          string `json:"hash"`
+}
+
+// FingerprintHost 根据 daemon Info 计算宿主机指纹
+func FingerprintHost(ctx context.Context, cli *client.Client) (HostFingerprint, error) {
+	info, err := cli.Info(ctx, client.InfoOptions{})
+	if err != nil {
+		return HostFingerprint{}, fmt.Errorf("查询 daemon Info 失败: %w", err)
+	}
+	i := info.Info
+	fp := HostFingerprint{
+		Name:          i.Name,
+		Kernel:        i.KernelVersion,
+		CgroupVersion: i.CgroupVersion,
+		StorageDriver: i.Driver,
+		NCPU:          i.NCPU,
+		MemTotal:      i.MemTotal,
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%d|%d|%s",
+		fp.Name, fp.Kernel, fp.CgroupVersion, fp.StorageDriver, fp.NCPU, fp.MemTotal, i.ID)))
+	fp.Hash = hex.EncodeToString(sum[:6])
+	return fp, nil
+}
+
+// HistoryRecord 为历史库中的一条运行记录
+type HistoryRecord struct {
+	RunID      string             `json:"runId"`
+	Scenario   string             `json:"scenario"`
+	Params     map[string]string  `json:"params,omitempty"`
+	Host       HostFingerprint    `json:"host"`
+	StartedAt  time.Time          `json:"startedAt"`
+	FinishedAt time.Time          `json:"finishedAt"`
+	Passed     bool               `json:"passed"`
+	Summary    string             `json:"summary"`
+	Metrics    map[string]float64 `json:"metrics"`
+}
+
+// 历史记录中的指标名
+const (
+	MetricDuration     = "duration_seconds"
+	MetricTimeToOOM    = "time_to_oom_seconds"
+	MetricOOMCount     = "oom_count"
+	MetricPeakMemory   = "peak_memory_bytes"
+	MetricPeakCPU      = "peak_vcpu"
+	MetricAchievedCPU  = "achieved_vcpu"
+	MetricThrottled    = "throttled_seconds"
+	MetricExitCode     = "exit_code"
+	MetricProbeFailure = "probe_failures"
+)
+
+// oomBaseline 返回计算 OOM 用时的起点：优先取发生 OOM 的容器的启动时间，其次是 Run 阶段的开始时间，
+// 都没有时才退回报告的开始时间。调用方需持有 r.mu
+func oomBaseline(r *Report, containerID string) time.Time {
+	for _, run := range r.Runs {
+		if run.ContainerID == containerID && !run.StartedAt.IsZero() {
+			return run.StartedAt
+		}
+	}
+	if !r.RunStartedAt.IsZero() {
+		return r.RunStartedAt
+	}
+	return r.StartedAt
+}
+
+// RecordFromReport 从报告中提炼可比较的指标
+func RecordFromReport(r *Report) HistoryRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m := map[string]float64{
+		MetricDuration: r.FinishedAt.Sub(r.StartedAt).Seconds(),
+	}
+	var ooms float64
+	for _, evt := range r.Events {
+		if evt.Action != "oom" {
+			continue
+		}
+		if ooms == 0 {
+			m[MetricTimeToOOM] = evt.At.Sub(oomBaseline(r, evt.ContainerID)).Seconds()
+		}
+		ooms++
+	}
+	m[MetricOOMCount] = ooms
+
+	var peakMem uint64
+	var peakCPU, achieved, throttled float64
+	for _, u := range r.Usage {
+		peakMem = max(peakMem, u.PeakMemory)
+		peakCPU = max(peakCPU, u.PeakCPU)
+		achieved = max(achieved, meanCPU(u.Samples))
+		throttled += float64(u.ThrottledNanos) / 1e9
+	}
+	if len(r.Usage) > 0 {
+		m[MetricPeakMemory] = float64(peakMem)
+		m[MetricPeakCPU] = peakCPU
+		m[MetricAchievedCPU] = achieved
+		m[MetricThrottled] = throttled
+	}
+	if len(r.Runs) > 0 {
+		m[MetricExitCode] = float64(r.Runs[len(r.Runs)-1].StatusCode)
+	}
+	if len(r.Probes) > 0 {
+		var failures float64
+		for _, p := range r.Probes {
+			if p.ExitCode != 0 {
+				failures++
+			}
+		}
+		m[MetricProbeFailure] = failures
+	}
+
+	return HistoryRecord{
+		RunID:      r.RunID,
+		Scenario:   r.Scenario,
+		Params:     r.Params,
+		Host:       r.Host,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+		Passed:     r.Passed,
+		Summary:    r.Summary,
+		Metrics:    m,
+	}
+}
+
+// meanCPU 返回采样期间的平均 vCPU，第一条采样没有 CPU 换算，不计入
+func meanCPU(samples []Sample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, s := range samples[1:] {
+		sum += s.CPU
+	}
+	return sum / float64(len(samples)-1)
+}
+
+// AppendHistory 以 JSONL 追加写入一条记录
+func AppendHistory(path string, rec HistoryRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建历史目录失败: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开历史文件失败: %w", err)
+	}
+	defer f.Close()
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("序列化历史记录失败: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入历史文件失败: %w", err)
+	}
+	return nil
+}
+
+// LoadHistory 读取全部历史记录，按开始时间排序；文件不存在时返回空列表
+func LoadHistory(path string) ([]HistoryRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开历史文件失败: %w", err)
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*MiB)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("解析历史文件第 %d 行失败: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取历史文件失败: %w", err)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartedAt.Before(records[j].StartedAt)
+	})
+	return records, nil
+}
+
+// PreviousRun 返回 records 中位于 current 之前、同场景同宿主机的最近一次运行
+func PreviousRun(records []HistoryRecord, current HistoryRecord) (HistoryRecord, bool) {
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		if rec.RunID == current.RunID || !rec.StartedAt.Before(current.StartedAt) {
+			continue
+		}
+		if rec.Scenario == current.Scenario && rec.Host.Hash == current.Host.Hash {
+			return rec, true
+		}
+	}
+	return HistoryRecord{}, false
+}
+
+// MetricDirection 表示指标朝哪个方向变化算作变差
+type MetricDirection int
+
+const (
+	// ReferenceOnly 的指标只作参考，不参与回归判定
+	ReferenceOnly MetricDirection = iota
+	// HigherIsWorse 的指标上升算作回归
+	HigherIsWorse
+	// LowerIsWorse 的指标下降算作回归
+	LowerIsWorse
+)
+
+// metricDirections 为各指标变差的方向。耗时天然存在抖动，退出码是类别值，其变化由结论变化体现，
+// 两者只作参考；OOM 用时变长说明限额生效得更晚；未列出的指标同样只作参考
+var metricDirections = map[string]MetricDirection{
+	MetricDuration:     ReferenceOnly,
+	MetricTimeToOOM:    HigherIsWorse,
+	MetricOOMCount:     HigherIsWorse,
+	MetricPeakMemory:   HigherIsWorse,
+	MetricPeakCPU:      HigherIsWorse,
+	MetricAchievedCPU:  LowerIsWorse,
+	MetricThrottled:    HigherIsWorse,
+	MetricExitCode:     ReferenceOnly,
+	MetricProbeFailure: HigherIsWorse,
+}
+
+// MetricDiff 为两次运行之间单个指标的变化，Change 为相对变化；
+// 某一侧缺失该指标或上一次为 0 时相对变化无法计算，Undefined 为 true
+type MetricDiff struct {
+	Name       string  `json:"name"`
+	Previous   float64 `json:"previous"`
+	Current    float64 `json:"current"`
+	Change     float64 `json:"change"`
+	Undefined  bool    `json:"undefined"`
+	Regression bool    `json:"regression"`
+}
+
+// Comparison 为一次运行与上一次运行的对比结果
+type Comparison struct {
+	Previous      HistoryRecord `json:"previous"`
+	Current       HistoryRecord `json:"current"`
+	Diffs         []MetricDiff  `json:"diffs"`
+	VerdictChange bool          `json:"verdictChange"`
+	ParamsChanged bool          `json:"paramsChanged"`
+}
+
+// Regressed 判断对比中是否存在回归
+func (c Comparison) Regressed() bool {
+	if c.VerdictChange && !c.Current.Passed {
+		return true
+	}
+	for _, d := range c.Diffs {
+		if d.Regression {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareRuns 逐项比较两次运行的指标，按 metricDirections 朝变差方向的相对变化超过 threshold
+// （例如 0.1 表示 10%）即标记为回归；上一次为 0 时只要朝变差方向变化就标记。
+// 某一侧缺失的指标（例如只有一次运行触发了 OOM）无法比较，只标记为 Undefined
+func CompareRuns(prev, cur HistoryRecord, threshold float64) Comparison {
+	c := Comparison{
+		Previous:      prev,
+		Current:       cur,
+		VerdictChange: prev.Passed != cur.Passed,
+		ParamsChanged: paramsLabel(prev.Params) != paramsLabel(cur.Params),
+	}
+	names := map[string]bool{}
+	for name := range prev.Metrics {
+		names[name] = true
+	}
+	for name := range cur.Metrics {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		p, okPrev := prev.Metrics[name]
+		v, okCur := cur.Metrics[name]
+		d := MetricDiff{Name: name, Previous: p, Current: v}
+		worse := v - p
+		if metricDirections[name] == LowerIsWorse {
+			worse = -worse
+		}
+		switch {
+		case okPrev != okCur:
+			d.Undefined = true
+		case p == v:
+		case p == 0:
+			d.Undefined = true
+			d.Regression = worse > 0
+		default:
+			d.Change = (v - p) / math.Abs(p)
+			d.Regression = worse/math.Abs(p) > threshold
+		}
+		if metricDirections[name] == ReferenceOnly {
+			d.Regression = false
+		}
+		c.Diffs = append(c.Diffs, d)
+	}
+	return c
+}
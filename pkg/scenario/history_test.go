@@ -0,0 +1,135 @@
+package scenario
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRoundTripAndCompare(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	host := HostFingerprint{Hash: "abc"}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	records := []HistoryRecord{
+		{RunID: "mem-1", Scenario: "memory", Host: host, StartedAt: start, Passed: true,
+			Metrics: map[string]float64{MetricTimeToOOM: 10, MetricAchievedCPU: 1, MetricDuration: 20}},
+		{RunID: "mem-other-host", Scenario: "memory", Host: HostFingerprint{Hash: "zzz"}, StartedAt: start.Add(time.Minute),
+			Metrics: map[string]float64{MetricTimeToOOM: 1}},
+		{RunID: "cpu-1", Scenario: "cpu", Host: host, StartedAt: start.Add(2 * time.Minute)},
+		{RunID: "mem-2", Scenario: "memory", Host: host, StartedAt: start.Add(3 * time.Minute), Passed: true,
+			Metrics: map[string]float64{MetricTimeToOOM: 12.5, MetricAchievedCPU: 1.05, MetricDuration: 40}},
+	}
+	for _, rec := range records {
+		if err := AppendHistory(path, rec); err != nil {
+			t.Fatalf("AppendHistory: %v", err)
+		}
+	}
+
+	loaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if len(loaded) != len(records) {
+		t.Fatalf("loaded %d records, want %d", len(loaded), len(records))
+	}
+
+	prev, ok := PreviousRun(loaded, loaded[3])
+	if !ok || prev.RunID != "mem-1" {
+		t.Fatalf("PreviousRun = %q, %t; want mem-1", prev.RunID, ok)
+	}
+
+	c := CompareRuns(prev, loaded[3], 0.1)
+	if !c.Regressed() {
+		t.Fatal("expected a regression for a 25% time-to-OOM shift")
+	}
+	for _, d := range c.Diffs {
+		switch d.Name {
+		case MetricTimeToOOM:
+			if !d.Regression || d.Change != 0.25 {
+				t.Errorf("time-to-OOM diff = %+v", d)
+			}
+		case MetricAchievedCPU, MetricDuration:
+			if d.Regression {
+				t.Errorf("%s should not regress: %+v", d.Name, d)
+			}
+		}
+	}
+}
+
+func TestCompareRunsMissingMetricAndVerdict(t *testing.T) {
+	prev := HistoryRecord{Passed: true, Metrics: map[string]float64{MetricOOMCount: 0}}
+	cur := HistoryRecord{Passed: false, Metrics: map[string]float64{MetricOOMCount: 0, MetricTimeToOOM: 3}}
+	c := CompareRuns(prev, cur, 0.5)
+	if !c.VerdictChange || !c.Regressed() {
+		t.Fatalf("expected verdict regression: %+v", c)
+	}
+	for _, d := range c.Diffs {
+		if d.Name == MetricTimeToOOM && (!d.Undefined || d.Regression) {
+			t.Errorf("new metric should be undefined but not a regression: %+v", d)
+		}
+	}
+}
+
+func TestCompareRunsDirection(t *testing.T) {
+	prev := HistoryRecord{Passed: true, Metrics: map[string]float64{
+		MetricPeakMemory: 100, MetricThrottled: 2, MetricAchievedCPU: 1, MetricOOMCount: 0, MetricProbeFailure: 1, MetricExitCode: 0,
+	}}
+	cur := HistoryRecord{Passed: true, Metrics: map[string]float64{
+		MetricPeakMemory: 50, MetricThrottled: 0.5, MetricAchievedCPU: 0.5, MetricOOMCount: 1, MetricProbeFailure: 0, MetricExitCode: 137,
+	}}
+	want := map[string]bool{
+		MetricPeakMemory:   false,
+		MetricThrottled:    false,
+		MetricAchievedCPU:  true,
+		MetricOOMCount:     true,
+		MetricProbeFailure: false,
+		MetricExitCode:     false,
+	}
+	for _, d := range CompareRuns(prev, cur, 0.1).Diffs {
+		if d.Regression != want[d.Name] {
+			t.Errorf("%s: regression = %t, want %t (%+v)", d.Name, d.Regression, want[d.Name], d)
+		}
+	}
+}
+
+func TestRecordFromReport(t *testing.T) {
+	r := NewReport("memory", nil)
+	r.RunStartedAt = r.StartedAt.Add(30 * time.Second)
+	r.Events = []Event{
+		{Action: "oom", ContainerID: "c1", At: r.StartedAt.Add(42 * time.Second)},
+		{Action: "oom", ContainerID: "c1", At: r.StartedAt.Add(43 * time.Second)},
+	}
+	r.Usage = []*Usage{{PeakMemory: 64, Samples: []Sample{{CPU: 0}, {CPU: 0.5}, {CPU: 1.5}}}}
+	r.Runs = []*RunResult{{ContainerID: "c1", StatusCode: 137, StartedAt: r.StartedAt.Add(40 * time.Second)}}
+	r.Finish(true, "ok")
+
+	rec := RecordFromReport(r)
+	if rec.Metrics[MetricTimeToOOM] != 2 || rec.Metrics[MetricOOMCount] != 2 {
+		t.Fatalf("oom metrics = %v", rec.Metrics)
+	}
+	if rec.Metrics[MetricAchievedCPU] != 1 || rec.Metrics[MetricExitCode] != 137 {
+		t.Fatalf("usage metrics = %v", rec.Metrics)
+	}
+}
+
+func TestOOMBaseline(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		run  time.Time
+		runs []*RunResult
+		want time.Time
+	}{
+		{"容器启动时间", start.Add(5 * time.Second), []*RunResult{{ContainerID: "c1", StartedAt: start.Add(8 * time.Second)}}, start.Add(8 * time.Second)},
+		{"其他容器", start.Add(5 * time.Second), []*RunResult{{ContainerID: "c2", StartedAt: start.Add(8 * time.Second)}}, start.Add(5 * time.Second)},
+		{"Run 阶段开始", start.Add(5 * time.Second), nil, start.Add(5 * time.Second)},
+		{"报告开始", time.Time{}, nil, start},
+	}
+	for _, tt := range tests {
+		r := &Report{StartedAt: start, RunStartedAt: tt.run, Runs: tt.runs}
+		if got := oomBaseline(r, "c1"); !got.Equal(tt.want) {
+			t.Errorf("%s: oomBaseline = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
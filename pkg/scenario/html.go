@@ -0,0 +1,249 @@
+package scenario
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// marker 为图表上叠加的事件竖线
+type marker struct {
+	At    time.Time
+	Label string
+	Color string
+}
+
+// chartLine 为图表中的一条折线
+type chartLine struct {
+	Name   string
+	Points []Point
+}
+
+// chart 描述一张带上限线的时间序列图
+type chart struct {
+	Title string
+	Unit  string
+	Limit float64
+	Lines []chartLine
+}
+
+const (
+	chartWidth   = 760
+	chartHeight  = 220
+	chartPadLeft = 56
+	chartPadTop  = 24
+	chartPadBot  = 28
+	chartPadRite = 16
+)
+
+var lineColors = []string{"#1f77b4", "#2ca02c", "#9467bd", "#8c564b", "#17becf"}
+
+// svg 把图表渲染为内联 SVG，横轴为相对 t0 的秒数
+func (c chart) svg(t0, t1 time.Time, markers []marker) template.HTML {
+	plotW := float64(chartWidth - chartPadLeft - chartPadRite)
+	plotH := float64(chartHeight - chartPadTop - chartPadBot)
+	span := t1.Sub(t0).Seconds()
+	if span <= 0 {
+		span = 1
+	}
+	yMax := c.Limit
+	for _, l := range c.Lines {
+		for _, p := range l.Points {
+			yMax = max(yMax, p.Value)
+		}
+	}
+	if yMax <= 0 {
+		yMax = 1
+	}
+	yMax *= 1.1
+	x := func(at time.Time) float64 {
+		return chartPadLeft + plotW*at.Sub(t0).Seconds()/span
+	}
+	y := func(v float64) float64 {
+		return chartPadTop + plotH*(1-v/yMax)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="11">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="14" font-size="13" font-weight="bold">%s</text>`, chartPadLeft, html.EscapeString(c.Title))
+	// 坐标轴与刻度
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.0f" height="%.0f" fill="none" stroke="#ccc"/>`, chartPadLeft, chartPadTop, plotW, plotH)
+	for _, frac := range []float64{0, 0.5, 1} {
+		v := yMax * frac
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%.4g %s</text>`, chartPadLeft-4, y(v)+4, v, html.EscapeString(c.Unit))
+		at := t0.Add(time.Duration(span * frac * float64(time.Second)))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%.1fs</text>`, x(at), chartHeight-8, span*frac)
+	}
+	if c.Limit > 0 {
+		fmt.Fprintf(&b, `<line x1="%d" x2="%.1f" y1="%.1f" y2="%.1f" stroke="#d62728" stroke-dasharray="6 3"/>`,
+			chartPadLeft, chartPadLeft+plotW, y(c.Limit), y(c.Limit))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="end" fill="#d62728">上限 %.4g %s</text>`,
+			chartPadLeft+plotW-4, y(c.Limit)-4, c.Limit, html.EscapeString(c.Unit))
+	}
+	for _, m := range markers {
+		if m.At.Before(t0) || m.At.After(t1) {
+			continue
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" x2="%.1f" y1="%d" y2="%.0f" stroke="%s" stroke-dasharray="2 2"/>`,
+			x(m.At), x(m.At), chartPadTop, chartPadTop+plotH, m.Color)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" fill="%s">%s</text>`, x(m.At)+2, chartPadTop+10, m.Color, html.EscapeString(m.Label))
+	}
+	for i, l := range c.Lines {
+		if len(l.Points) == 0 {
+			continue
+		}
+		color := lineColors[i%len(lineColors)]
+		pts := make([]string, 0, len(l.Points))
+		for _, p := range l.Points {
+			pts = append(pts, fmt.Sprintf("%.1f,%.1f", x(p.At), y(p.Value)))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`, color, strings.Join(pts, " "))
+		fmt.Fprintf(&b, `<text x="%d" y="14" text-anchor="end" fill="%s">%s</text>`, chartWidth-chartPadRite-i*160, color, html.EscapeString(l.Name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// reportMarkers 从事件与时间线中提取需要叠加到图表上的标记：OOM、kill、ENOSPC、限额调整与容器退出
+func reportMarkers(r *Report) []marker {
+	var markers []marker
+	for _, evt := range r.Events {
+		switch evt.Action {
+		case "oom":
+			markers = append(markers, marker{At: evt.At, Label: "OOM", Color: "#d62728"})
+		case "kill":
+			markers = append(markers, marker{At: evt.At, Label: "kill " + evt.Attributes["signal"], Color: "#9467bd"})
+		}
+	}
+	for _, entry := range r.Timeline {
+		switch entry.Kind {
+		case "enospc":
+			markers = append(markers, marker{At: entry.At, Label: "ENOSPC", Color: "#ff7f0e"})
+		case "update":
+			name, _, _ := strings.Cut(entry.Detail, " ")
+			markers = append(markers, marker{At: entry.At, Label: "update " + name, Color: "#17becf"})
+		}
+	}
+	for _, run := range r.Runs {
+		markers = append(markers, marker{At: run.FinishedAt, Label: fmt.Sprintf("exit %d", run.StatusCode), Color: "#7f7f7f"})
+	}
+	return markers
+}
+
+// reportCharts 为每个容器生成内存与 CPU 图，为每条自定义序列生成一张图
+func reportCharts(r *Report) []chart {
+	var charts []chart
+	for _, u := range r.Usage {
+		mem := chartLine{Name: u.Container}
+		memLimit := chartLine{Name: "限额"}
+		cpu := chartLine{Name: u.Container}
+		limitChanged := false
+		for i, s := range u.Samples {
+			mem.Points = append(mem.Points, Point{At: s.At, Value: float64(s.MemoryUsage) / MiB})
+			memLimit.Points = append(memLimit.Points, Point{At: s.At, Value: float64(s.MemoryLimit) / MiB})
+			if i > 0 {
+				cpu.Points = append(cpu.Points, Point{At: s.At, Value: s.CPU})
+				limitChanged = limitChanged || s.MemoryLimit != u.Samples[i-1].MemoryLimit
+			}
+		}
+		memChart := chart{Title: u.Container + " 内存用量 / 限额", Unit: "MiB", Limit: float64(u.MemoryLimit) / MiB, Lines: []chartLine{mem}}
+		// 运行中调整过内存限额时，用采样到的限额折线代替固定的上限线
+		if limitChanged {
+			memChart.Limit = 0
+			memChart.Lines = append(memChart.Lines, memLimit)
+		}
+		charts = append(charts,
+			memChart,
+			chart{Title: u.Container + " CPU 用量 / 配额", Unit: "vCPU", Limit: u.CPULimit, Lines: []chartLine{cpu}},
+		)
+	}
+	for _, s := range r.Series {
+		charts = append(charts, chart{
+			Title: s.Name + " 用量 / 容量",
+			Unit:  s.Unit,
+			Limit: s.Limit,
+			Lines: []chartLine{{Name: s.Name, Points: s.Points}},
+		})
+	}
+	return charts
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"offset": func(at, start time.Time) string {
+		return at.Sub(start).Round(time.Millisecond).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.R.Scenario}} - {{.R.RunID}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+table { border-collapse: collapse; margin: 12px 0; }
+td, th { border: 1px solid #ddd; padding: 4px 8px; text-align: left; font-size: 13px; }
+.pass { color: #2ca02c; } .fail { color: #d62728; }
+pre { background: #f6f6f6; padding: 8px; font-size: 12px; max-height: 320px; overflow: auto; }
+</style>
+</head>
+<body>
+<h1>{{.R.Scenario}}</h1>
+<table>
+<tr><th>RunID</th><td>{{.R.RunID}}</td></tr>
+<tr><th>开始</th><td>{{.R.StartedAt.Format "2006-01-02 15:04:05"}}</td></tr>
+<tr><th>daemon</th><td>{{.R.Endpoint}}</td></tr>
+<tr><th>宿主机</th><td>{{.R.Host.Name}} {{.R.Host.Kernel}} cgroup v{{.R.Host.CgroupVersion}} {{.R.Host.StorageDriver}} ({{.R.Host.Hash}})</td></tr>
+{{range $k, $v := .R.Params}}<tr><th>{{$k}}</th><td>{{$v}}</td></tr>
+{{end}}<tr><th>结论</th><td class="{{if .R.Passed}}pass{{else}}fail{{end}}">{{if .R.Passed}}通过{{else}}未通过{{end}}：{{.R.Summary}}</td></tr>
+</table>
+{{range .Charts}}<div>{{.}}</div>
+{{end}}
+<h2>时间线</h2>
+<table>
+<tr><th>时间</th><th>类型</th><th>详情</th></tr>
+{{range .R.Timeline}}<tr><td>+{{offset .At $.R.StartedAt}}</td><td>{{.Kind}}</td><td>{{.Detail}}</td></tr>
+{{end}}</table>
+{{range .R.Runs}}<h2>容器 {{.Name}}（退出码 {{.StatusCode}}）</h2>
+<pre>{{.Stdout}}{{.Stderr}}</pre>
+{{end}}{{range .R.Probes}}<h2>探测 {{.Name}}（退出码 {{.ExitCode}}）</h2>
+<pre>{{.Stdout}}{{.Stderr}}</pre>
+{{end}}</body>
+</html>
+`))
+
+// WriteHTML 把报告渲染为单个离线 HTML 文件，图表以内联 SVG 嵌入，不依赖任何外部资源
+func WriteHTML(w io.Writer, r *Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// 横轴覆盖整个运行，以及运行结束后才到达的采样与事件
+	t0, t1 := r.StartedAt, r.FinishedAt
+	extend := func(at time.Time) {
+		if at.After(t1) {
+			t1 = at
+		}
+	}
+	for _, u := range r.Usage {
+		if n := len(u.Samples); n > 0 {
+			extend(u.Samples[n-1].At)
+		}
+	}
+	for _, s := range r.Series {
+		if n := len(s.Points); n > 0 {
+			extend(s.Points[n-1].At)
+		}
+	}
+	markers := reportMarkers(r)
+	for _, m := range markers {
+		extend(m.At)
+	}
+	var charts []template.HTML
+	for _, c := range reportCharts(r) {
+		charts = append(charts, c.svg(t0, t1, markers))
+	}
+	return htmlTemplate.Execute(w, struct {
+		R      *Report
+		Charts []template.HTML
+	}{R: r, Charts: charts})
+}
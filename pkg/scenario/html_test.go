@@ -0,0 +1,61 @@
+package scenario
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTMLEmbedsChartsAndMarkers(t *testing.T) {
+	r := NewReport("volume-fill", map[string]string{"volumeLimit": "32MiB"})
+	start := r.StartedAt
+	r.Usage = []*Usage{{
+		Container:   "volume-fill-1",
+		MemoryLimit: 128 * MiB,
+		CPULimit:    1,
+		Samples: []Sample{
+			{At: start.Add(time.Second), MemoryUsage: 10 * MiB},
+			{At: start.Add(2 * time.Second), MemoryUsage: 20 * MiB, CPU: 0.8},
+		},
+	}}
+	r.AddRun(&RunResult{Name: "volume-fill-1", StartedAt: start, FinishedAt: start.Add(3 * time.Second), StatusCode: 42,
+		Stderr: "写入失败：卷空间已耗尽 <script>"})
+	r.AddSeries(&Series{Name: "volume", Unit: "MiB", Limit: 32, Points: []Point{{At: start.Add(time.Second), Value: 4}}})
+	r.Events = []Event{{At: start.Add(2500 * time.Millisecond), Action: "oom"}}
+	r.Mark(start.Add(2900*time.Millisecond), "enospc", "写入失败")
+	r.Finish(true, "ok")
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, r); err != nil {
+		t.Fatalf("WriteHTML: %v", err)
+	}
+	out := buf.String()
+	if got := strings.Count(out, "<svg"); got != 3 {
+		t.Fatalf("got %d charts, want 3 (memory, cpu, volume)", got)
+	}
+	for _, want := range []string{">OOM<", ">ENOSPC<", ">exit 42<", "上限 128 MiB", "上限 32 MiB", "&lt;script&gt;"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML missing %q", want)
+		}
+	}
+	if strings.Contains(out, "<script>") || strings.Contains(out, "http://") && !strings.Contains(out, `xmlns="http://www.w3.org/2000/svg"`) {
+		t.Error("HTML must be self-contained and escaped")
+	}
+}
+
+func TestSplitTimestampsAndFillSeries(t *testing.T) {
+	raw := "2026-01-01T00:00:01.5Z 累计写入=4MiB 已用=4MiB 剩余=28MiB\n" +
+		"2026-01-01T00:00:02Z 累计写入=8MiB 已用=8MiB 剩余=24MiB\n"
+	text, lines := splitTimestamps(raw, "stdout")
+	if strings.Contains(text, "2026-") || len(lines) != 2 {
+		t.Fatalf("text=%q lines=%+v", text, lines)
+	}
+	series := FillSeries("volume", &RunResult{Lines: lines})
+	if series.Limit != 32 || len(series.Points) != 2 || series.Points[1].Value != 8 {
+		t.Fatalf("series = %+v", series)
+	}
+	if !series.Points[0].At.Equal(time.Date(2026, 1, 1, 0, 0, 1, 5e8, time.UTC)) {
+		t.Fatalf("first point at %v", series.Points[0].At)
+	}
+}
@@ -0,0 +1,166 @@
+package scenario
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics 把场景报告转换为 Prometheus 指标，指标按 scenario 与 params 打标签，
+// params 为按 key 排序后的 k=v 列表，保证同一组参数的多次运行落在同一条序列上
+type Metrics struct {
+	registry *prometheus.Registry
+
+	runs       *prometheus.CounterVec
+	duration   *prometheus.GaugeVec
+	passed     *prometheus.GaugeVec
+	oomEvents  *prometheus.CounterVec
+	memLimit   *prometheus.GaugeVec
+	memPeak    *prometheus.GaugeVec
+	cpuLimit   *prometheus.GaugeVec
+	cpuPeak    *prometheus.GaugeVec
+	throttled  *prometheus.GaugeVec
+	pidsLimit  *prometheus.GaugeVec
+	lastRunEnd *prometheus.GaugeVec
+}
+
+// NewMetrics 创建独立 registry 上的场景指标
+func NewMetrics() *Metrics {
+	runLabels := []string{"scenario", "params"}
+	ctrLabels := []string{"scenario", "params", "role"}
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "scenario_runs_total",
+			Help: "场景运行次数，按结论区分",
+		}, append(runLabels, "verdict")),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scenario_run_duration_seconds",
+			Help: "最近一次运行的耗时",
+		}, runLabels),
+		passed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scenario_run_passed",
+			Help: "最近一次运行是否通过，1 为通过",
+		}, runLabels),
+		oomEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "scenario_oom_events_total",
+			Help: "运行期间观察到的 oom 事件数",
+		}, runLabels),
+		memLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scenario_container_memory_limit_bytes",
+			Help: "容器内存限额",
+		}, ctrLabels),
+		memPeak: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scenario_container_memory_peak_bytes",
+			Help: "采样到的容器内存峰值",
+		}, ctrLabels),
+		cpuLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scenario_container_cpu_limit_vcpu",
+			Help: "容器 CPU 限额（vCPU）",
+		}, ctrLabels),
+		cpuPeak: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scenario_container_cpu_peak_vcpu",
+			Help: "采样到的容器 CPU 峰值（vCPU）",
+		}, ctrLabels),
+		throttled: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scenario_container_cpu_throttled_seconds",
+			Help: "容器被 CFS 限流的累计时间",
+		}, ctrLabels),
+		pidsLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scenario_container_pids_limit",
+			Help: "容器 pids 限额",
+		}, ctrLabels),
+		lastRunEnd: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "scenario_last_run_timestamp_seconds",
+			Help: "最近一次运行结束的 Unix 时间",
+		}, runLabels),
+	}
+	m.registry.MustRegister(m.runs, m.duration, m.passed, m.oomEvents, m.memLimit, m.memPeak,
+		m.cpuLimit, m.cpuPeak, m.throttled, m.pidsLimit, m.lastRunEnd)
+	return m
+}
+
+// paramsLabel 把参数编码为稳定的标签值
+func paramsLabel(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+params[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Observe 把一份已完成的报告写入指标
+func (m *Metrics) Observe(r *Report) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	params := paramsLabel(r.Params)
+	verdict, passed := "fail", 0.0
+	if r.Passed {
+		verdict, passed = "pass", 1.0
+	}
+	m.runs.WithLabelValues(r.Scenario, params, verdict).Inc()
+	m.duration.WithLabelValues(r.Scenario, params).Set(r.FinishedAt.Sub(r.StartedAt).Seconds())
+	m.passed.WithLabelValues(r.Scenario, params).Set(passed)
+	m.lastRunEnd.WithLabelValues(r.Scenario, params).Set(float64(r.FinishedAt.Unix()))
+
+	ooms := m.oomEvents.WithLabelValues(r.Scenario, params)
+	for _, evt := range r.Events {
+		if evt.Action == "oom" {
+			ooms.Inc()
+		}
+	}
+	for _, u := range r.Usage {
+		m.memLimit.WithLabelValues(r.Scenario, params, u.Role).Set(float64(u.MemoryLimit))
+		m.memPeak.WithLabelValues(r.Scenario, params, u.Role).Set(float64(u.PeakMemory))
+		m.cpuLimit.WithLabelValues(r.Scenario, params, u.Role).Set(u.CPULimit)
+		m.cpuPeak.WithLabelValues(r.Scenario, params, u.Role).Set(u.PeakCPU)
+		m.throttled.WithLabelValues(r.Scenario, params, u.Role).Set(float64(u.ThrottledNanos) / 1e9)
+		m.pidsLimit.WithLabelValues(r.Scenario, params, u.Role).Set(float64(u.PidsLimit))
+	}
+}
+
+// Handler 返回暴露指标的 HTTP handler
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+var (
+	defaultMetricsOnce sync.Once
+	defaultMetrics     *Metrics
+)
+
+// startMetricsServer 在 Opts.MetricsAddr 上启动 /metrics，未配置地址时返回 nil
+func startMetricsServer() *Metrics {
+	if Opts.MetricsAddr == "" {
+		return nil
+	}
+	defaultMetricsOnce.Do(func() {
+		defaultMetrics = NewMetrics()
+		ln, err := net.Listen("tcp", Opts.MetricsAddr)
+		if err != nil {
+			log.Printf("监听 metrics 地址 %s 失败: %v", Opts.MetricsAddr, err)
+			return
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", defaultMetrics.Handler())
+		go func() {
+			if err := http.Serve(ln, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("metrics 服务退出: %v", err)
+			}
+		}()
+		log.Printf("Prometheus 指标暴露在 http://%s/metrics", ln.Addr())
+	})
+	return defaultMetrics
+}
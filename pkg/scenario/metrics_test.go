@@ -0,0 +1,74 @@
+package scenario
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsScrape(t *testing.T) {
+	r := NewReport("memory-probe", map[string]string{"memoryLimit": "64MiB", "fill": "48MiB"})
+	r.Events = []Event{{Action: "oom"}, {Action: "die"}, {Action: "oom"}}
+	r.Usage = []*Usage{{
+		Container:      "mem-test-150405",
+		Role:           "mem-test",
+		MemoryLimit:    64 * MiB,
+		CPULimit:       1,
+		PeakMemory:     60 * MiB,
+		PeakCPU:        0.5,
+		ThrottledNanos: 1_500_000_000,
+	}}
+	r.Finish(true, "ok")
+	r.FinishedAt = r.StartedAt.Add(3 * time.Second)
+
+	m := NewMetrics()
+	m.Observe(r)
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("scrape: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const labels = `params="fill=48MiB,memoryLimit=64MiB",scenario="memory-probe"`
+	const ctrLabels = `params="fill=48MiB,memoryLimit=64MiB",role="mem-test",scenario="memory-probe"`
+	for _, want := range []string{
+		`scenario_runs_total{` + labels + `,verdict="pass"} 1`,
+		`scenario_run_duration_seconds{` + labels + `} 3`,
+		`scenario_run_passed{` + labels + `} 1`,
+		`scenario_oom_events_total{` + labels + `} 2`,
+		`scenario_container_memory_limit_bytes{` + ctrLabels + `} 6.7108864e+07`,
+		`scenario_container_cpu_throttled_seconds{` + ctrLabels + `} 1.5`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("scrape output missing %q", want)
+		}
+	}
+}
+
+func TestUsageAddComputesCPU(t *testing.T) {
+	var u Usage
+	start := time.Now()
+	u.add(Sample{At: start, CPUUsageNanos: 0, MemoryUsage: 10})
+	u.add(Sample{At: start.Add(time.Second), CPUUsageNanos: 500_000_000, MemoryUsage: 30, ThrottledNanos: 7})
+	u.add(Sample{At: start.Add(2 * time.Second), CPUUsageNanos: 1_500_000_000, MemoryUsage: 20, ThrottledNanos: 9})
+
+	if u.PeakMemory != 30 {
+		t.Fatalf("PeakMemory = %d, want 30", u.PeakMemory)
+	}
+	if u.PeakCPU != 1 {
+		t.Fatalf("PeakCPU = %v, want 1", u.PeakCPU)
+	}
+	if u.Samples[1].CPU != 0.5 || u.ThrottledNanos != 9 {
+		t.Fatalf("unexpected samples %+v", u.Samples)
+	}
+}
@@ -0,0 +1,53 @@
+package scenario
+
+import (
+	"flag"
+	"time"
+)
+
+// Options 为所有场景共享的命令行参数
+type Options struct {
+	// MetricsAddr 不为空时在该地址暴露 Prometheus /metrics
+	MetricsAddr string
+	// MetricsLinger 为报告发布后继续暴露指标的时间，便于 Prometheus 抓取短生命周期的场景
+	MetricsLinger time.Duration
+	// HistoryPath 为运行历史的 JSONL 文件，为空时不记录
+	HistoryPath string
+
+	// Context 为要使用的 docker CLI context，与 Host 互斥
+	Context string
+	// Host 直接指定 daemon 地址，优先级最高
+	Host string
+	// TLSCertPath 为包含 ca.pem/cert.pem/key.pem 的证书目录，覆盖所选端点的证书
+	TLSCertPath string
+	// TLSVerify 为 true 时校验 daemon 证书，仅在指定 TLSCertPath 时生效
+	TLSVerify bool
+}
+
+// DefaultHistoryPath 为运行历史的默认位置
+const DefaultHistoryPath = "reports/history.jsonl"
+
+// Opts 为当前进程的场景参数，由 ParseFlags 填充
+var Opts = Options{HistoryPath: DefaultHistoryPath}
+
+// RegisterFlags 把共享参数注册到 fs 上
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&Opts.MetricsAddr, "metrics-addr", "", "暴露 Prometheus /metrics 的地址，例如 :9464，为空时不启用")
+	fs.DurationVar(&Opts.MetricsLinger, "metrics-linger", 0, "报告发布后继续暴露指标的时间")
+	fs.StringVar(&Opts.HistoryPath, "history", DefaultHistoryPath, "运行历史文件，为空时不记录")
+	RegisterEndpointFlags(fs)
+}
+
+// RegisterEndpointFlags 只注册选择 Docker daemon 的参数，供不发布报告的工具命令使用
+func RegisterEndpointFlags(fs *flag.FlagSet) {
+	fs.StringVar(&Opts.Context, "context", "", "使用的 docker context，默认取 DOCKER_CONTEXT 或 ~/.docker/config.json 的 currentContext")
+	fs.StringVar(&Opts.Host, "host", "", "daemon 地址，例如 tcp://10.0.0.2:2376、ssh://user@host，优先于 -context 与 DOCKER_HOST")
+	fs.StringVar(&Opts.TLSCertPath, "tls-cert-path", "", "包含 ca.pem/cert.pem/key.pem 的证书目录")
+	fs.BoolVar(&Opts.TLSVerify, "tls-verify", true, "校验 daemon 证书，仅在指定 -tls-cert-path 时生效")
+}
+
+// ParseFlags 注册共享参数并解析命令行，场景的 main 函数应当首先调用
+func ParseFlags() {
+	RegisterFlags(flag.CommandLine)
+	flag.Parse()
+}
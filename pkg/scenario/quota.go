@@ -0,0 +1,180 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/client"
+)
+
+// 超过软限额后的处置方式
+const (
+	QuotaActionStop = "stop"
+	QuotaActionKill = "kill"
+)
+
+// RootfsQuota 为容器可写层的软限额，用于 overlay2 未启用 pquota、StorageOpt size 不可用的宿主机：
+// 定时通过带 size 的 ContainerInspect 读取 SizeRw，超过 Warn 时告警，超过 Limit 时按 Action 停止或杀死容器
+type RootfsQuota struct {
+	Warn  int64
+	Limit int64
+	// Action 为 QuotaActionStop 或 QuotaActionKill
+	Action string
+	// Interval 为轮询间隔，SizeRw 需要 daemon 遍历可写层，间隔不宜过短
+	Interval time.Duration
+	// StopTimeout 为 stop 时等待容器自行退出的秒数，超时后 daemon 发送 SIGKILL
+	StopTimeout int
+}
+
+// quotaDecision 为一次轮询后的处置
+type quotaDecision int
+
+const (
+	quotaNone quotaDecision = iota
+	quotaWarn
+	quotaEnforce
+	// quotaWarnEnforce 表示一次轮询同时越过告警线与限额，先记录告警再处置
+	quotaWarnEnforce
+)
+
+// decide 根据可写层大小与是否已告警决定处置，每次越过告警线只告警一次
+func (q RootfsQuota) decide(size int64, warned bool) quotaDecision {
+	switch {
+	case q.Limit > 0 && size >= q.Limit && q.Warn > 0 && !warned:
+		return quotaWarnEnforce
+	case q.Limit > 0 && size >= q.Limit:
+		return quotaEnforce
+	case q.Warn > 0 && size >= q.Warn && !warned:
+		return quotaWarn
+	default:
+		return quotaNone
+	}
+}
+
+// QuotaOutcome 为软限额执行的结果，大小均为字节
+type QuotaOutcome struct {
+	Samples    []Point
+	Peak       int64
+	WarnedAt   time.Time
+	WarnSize   int64
+	EnforcedAt time.Time
+	// EnforcedSize 为触发处置时的可写层大小，超出 Limit 的部分反映轮询间隔内的写入量
+	EnforcedSize int64
+	// Err 为轮询或处置过程中的错误
+	Err error
+}
+
+// QuotaEnforcer 在后台执行可写层软限额
+type QuotaEnforcer struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	mu      sync.Mutex
+	outcome QuotaOutcome
+}
+
+// StartQuotaEnforcer 开始轮询容器的可写层大小并执行软限额，容器被处置、退出或被删除后轮询自动结束。
+// 告警与处置会记录到 ctx 中报告的时间线（quota），可写层大小的采样（MiB）在 QuotaOutcome.Samples 中
+func StartQuotaEnforcer(ctx context.Context, cli *client.Client, id, name string, q RootfsQuota) *QuotaEnforcer {
+	if q.Interval <= 0 {
+		q.Interval = time.Second
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	e := &QuotaEnforcer{cancel: cancel, done: make(chan struct{})}
+	report := ReportFrom(ctx)
+	mark := func(at time.Time, format string, args ...any) {
+		log.Printf("[quota] %s: "+format, append([]any{name}, args...)...)
+		if report != nil {
+			report.Mark(at, "quota", "%s: "+format, append([]any{name}, args...)...)
+		}
+	}
+
+	go func() {
+		defer close(e.done)
+		for {
+			inspect, err := cli.ContainerInspect(ctx, id, client.ContainerInspectOptions{Size: true})
+			if err != nil {
+				if ctx.Err() == nil && !errdefs.IsNotFound(err) {
+					e.fail(fmt.Errorf("查询容器 %s 可写层大小失败: %w", name, err))
+				}
+				return
+			}
+			now := time.Now()
+			var size int64
+			if inspect.Container.SizeRw != nil {
+				size = *inspect.Container.SizeRw
+			}
+			e.mu.Lock()
+			e.outcome.Samples = append(e.outcome.Samples, Point{At: now, Value: float64(size) / MiB})
+			e.outcome.Peak = max(e.outcome.Peak, size)
+			warned := !e.outcome.WarnedAt.IsZero()
+			e.mu.Unlock()
+
+			decision := q.decide(size, warned)
+			if decision == quotaWarn || decision == quotaWarnEnforce {
+				e.mu.Lock()
+				e.outcome.WarnedAt, e.outcome.WarnSize = now, size
+				e.mu.Unlock()
+				mark(now, "可写层 %dMiB 超过告警线 %dMiB", size/MiB, q.Warn/MiB)
+			}
+			if decision == quotaEnforce || decision == quotaWarnEnforce {
+				e.mu.Lock()
+				e.outcome.EnforcedAt, e.outcome.EnforcedSize = now, size
+				e.mu.Unlock()
+				mark(now, "可写层 %dMiB 超过软限额 %dMiB，执行 %s", size/MiB, q.Limit/MiB, q.Action)
+				if err := enforceQuota(ctx, cli, id, q); err != nil {
+					e.fail(fmt.Errorf("处置容器 %s 失败: %w", name, err))
+				}
+				return
+			}
+			if inspect.Container.State != nil && !inspect.Container.State.Running {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(q.Interval):
+			}
+		}
+	}()
+	return e
+}
+
+// enforceQuota 按 Action 停止或杀死容器
+func enforceQuota(ctx context.Context, cli *client.Client, id string, q RootfsQuota) error {
+	if q.Action == QuotaActionKill {
+		_, err := cli.ContainerKill(ctx, id, client.ContainerKillOptions{Signal: "KILL"})
+		return err
+	}
+	timeout := q.StopTimeout
+	_, err := cli.ContainerStop(ctx, id, client.ContainerStopOptions{Timeout: &timeout})
+	return err
+}
+
+func (e *QuotaEnforcer) fail(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.outcome.Err = err
+}
+
+// Wait 等待轮询自行结束（容器被处置或退出），ctx 结束时提前返回
+func (e *QuotaEnforcer) Wait(ctx context.Context) error {
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop 结束轮询并返回执行结果
+func (e *QuotaEnforcer) Stop() QuotaOutcome {
+	e.cancel()
+	<-e.done
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.outcome
+}
@@ -0,0 +1,31 @@
+package scenario
+
+import "testing"
+
+func TestRootfsQuotaDecide(t *testing.T) {
+	q := RootfsQuota{Warn: 192 * MiB, Limit: 256 * MiB}
+	cases := []struct {
+		size   int64
+		warned bool
+		want   quotaDecision
+	}{
+		{100 * MiB, false, quotaNone},
+		{192 * MiB, false, quotaWarn},
+		{200 * MiB, true, quotaNone},
+		{256 * MiB, true, quotaEnforce},
+		// 一次轮询越过两条线时先告警再处置
+		{300 * MiB, false, quotaWarnEnforce},
+	}
+	for _, c := range cases {
+		if got := q.decide(c.size, c.warned); got != c.want {
+			t.Errorf("decide(%dMiB, %v) = %d, want %d", c.size/MiB, c.warned, got, c.want)
+		}
+	}
+
+	if got := (RootfsQuota{Limit: 256 * MiB}).decide(200*MiB, false); got != quotaNone {
+		t.Errorf("未设置告警线时 decide = %d", got)
+	}
+	if got := (RootfsQuota{Limit: 256 * MiB}).decide(300*MiB, false); got != quotaEnforce {
+		t.Errorf("未设置告警线时越过限额 decide = %d", got)
+	}
+}
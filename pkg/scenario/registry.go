@@ -0,0 +1,164 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// Scenario 为一个资源限制实验。Execute 依次调用 Prepare、Run、Verify，无论成败最后都会调用 Cleanup。
+// 各阶段拿到的 ctx 上挂着本次运行的报告，通过本包的容器与探测辅助函数运行的容器会自动记录到报告中；
+// 阶段之间的状态（容器、会话、运行结果）由实现自行保存在结构体上
+type Scenario interface {
+	// Name 为场景的唯一名称，同时作为报告与历史记录中的场景名
+	Name() string
+	// Prepare 准备运行条件，例如拉取镜像、创建 volume
+	Prepare(ctx context.Context, env *Env) error
+	// Run 运行受限容器并施加负载
+	Run(ctx context.Context, env *Env) error
+	// Verify 检查运行结果，通过时返回结论，未通过时返回说明原因的 error
+	Verify(ctx context.Context, env *Env) (string, error)
+	// Cleanup 删除 Prepare/Run 创建的资源，ctx 不受运行超时影响
+	Cleanup(ctx context.Context, env *Env) error
+}
+
+// Parameterized 为可选接口，实现后其参数会记录在报告与历史中，便于不同参数的运行分开比较
+type Parameterized interface {
+	Params() map[string]string
+}
+
+// LocalImaged 为可选接口，由依赖本地构建镜像（Prepare 不拉取）的场景实现，
+// 调用方可以在运行之前通过 MissingImages 检查这些镜像是否存在
+type LocalImaged interface {
+	LocalImages() []string
+}
+
+// Env 为场景运行时可用的依赖
+type Env struct {
+	Client *client.Client
+	Report *Report
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Scenario{}
+)
+
+// Register 注册一个场景，通常在场景包的 init 中调用；名称为空或重复时 panic
+func Register(s Scenario) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	name := s.Name()
+	if name == "" {
+		panic("scenario: Register 的场景名称为空")
+	}
+	if _, dup := registry[name]; dup {
+		panic("scenario: 场景 " + name + " 重复注册")
+	}
+	registry[name] = s
+}
+
+// Lookup 按名称查找已注册的场景
+func Lookup(name string) (Scenario, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	s, ok := registry[name]
+	return s, ok
+}
+
+// Names 返回已注册的场景名称，按字典序排列
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Family 返回场景所属的分组，即实现该场景的包名（如 scenarios/cpu 下的场景属于 cpu），
+// doctor 按分组汇报宿主机是否支持
+func Family(s Scenario) string {
+	t := reflect.TypeOf(s)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return path.Base(t.PkgPath())
+}
+
+// Families 返回已注册场景的分组，按字典序排列
+func Families() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	seen := map[string]bool{}
+	var families []string
+	for _, s := range registry {
+		if family := Family(s); !seen[family] {
+			seen[family] = true
+			families = append(families, family)
+		}
+	}
+	sort.Strings(families)
+	return families
+}
+
+// cleanupTimeout 为 Cleanup 阶段的超时，独立于运行的 ctx
+const cleanupTimeout = time.Minute
+
+// Execute 按生命周期运行一个场景并发布报告。某个阶段失败时跳过后续阶段，结论记为未通过；
+// 返回的 error 只表示报告发布失败
+func Execute(ctx context.Context, cli *client.Client, s Scenario) (*Report, error) {
+	var params map[string]string
+	if p, ok := s.(Parameterized); ok {
+		params = p.Params()
+	}
+	ctx, report := Begin(ctx, cli, s.Name(), params)
+	env := &Env{Client: cli, Report: report}
+
+	err := runPhase(report, "prepare", func() error { return s.Prepare(ctx, env) })
+	if err == nil {
+		report.RunStartedAt = time.Now()
+		err = runPhase(report, "run", func() error { return s.Run(ctx, env) })
+	}
+	if err != nil {
+		report.Finish(false, "%v", err)
+	} else {
+		var summary string
+		verr := runPhase(report, "verify", func() (err error) {
+			summary, err = s.Verify(ctx, env)
+			return err
+		})
+		if verr != nil {
+			report.Finish(false, "%v", verr)
+		} else {
+			report.Finish(true, "%s", summary)
+		}
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+	defer cancel()
+	if err := runPhase(report, "cleanup", func() error { return s.Cleanup(cleanupCtx, env) }); err != nil {
+		log.Printf("场景 %s 清理失败: %v", s.Name(), err)
+	}
+	return report, Publish(report)
+}
+
+// runPhase 执行一个阶段并在时间线上记录其起止
+func runPhase(report *Report, phase string, fn func() error) error {
+	report.Mark(time.Now(), "phase", "%s 开始", phase)
+	if err := fn(); err != nil {
+		report.Mark(time.Now(), "phase", "%s 失败: %v", phase, err)
+		return fmt.Errorf("%s: %w", phase, err)
+	}
+	report.Mark(time.Now(), "phase", "%s 完成", phase)
+	return nil
+}
@@ -0,0 +1,126 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// phaseScenario 记录被调用的阶段，failAt 指定的阶段返回错误
+type phaseScenario struct {
+	name   string
+	failAt string
+	calls  []string
+}
+
+func (s *phaseScenario) Name() string { return s.name }
+
+func (s *phaseScenario) Params() map[string]string { return map[string]string{"k": "v"} }
+
+func (s *phaseScenario) phase(name string) error {
+	s.calls = append(s.calls, name)
+	if s.failAt == name {
+		return errors.New(name + " boom")
+	}
+	return nil
+}
+
+func (s *phaseScenario) Prepare(context.Context, *Env) error { return s.phase("prepare") }
+func (s *phaseScenario) Run(context.Context, *Env) error     { return s.phase("run") }
+func (s *phaseScenario) Verify(context.Context, *Env) (string, error) {
+	return "all good", s.phase("verify")
+}
+func (s *phaseScenario) Cleanup(ctx context.Context, _ *Env) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return s.phase("cleanup")
+}
+
+func TestRegistry(t *testing.T) {
+	s := &phaseScenario{name: "registry-test"}
+	Register(s)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, s.name)
+		registryMu.Unlock()
+	})
+
+	if got, ok := Lookup("registry-test"); !ok || got != s {
+		t.Fatalf("Lookup = %v, %v", got, ok)
+	}
+	if got := Family(s); got != "scenario" {
+		t.Errorf("Family = %q, want scenario", got)
+	}
+	if got := Families(); !reflect.DeepEqual(got, []string{"scenario"}) {
+		t.Errorf("Families() = %v", got)
+	}
+	found := false
+	for _, name := range Names() {
+		found = found || name == "registry-test"
+	}
+	if !found {
+		t.Errorf("Names() = %v, missing registry-test", Names())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate Register did not panic")
+		}
+	}()
+	Register(&phaseScenario{name: "registry-test"})
+}
+
+func TestExecuteLifecycle(t *testing.T) {
+	_, sock := startFakeDaemon(t)
+	cli, err := client.New(client.WithHost("unix://" + sock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	savedDir, savedOpts := ReportDir, Opts
+	t.Cleanup(func() { ReportDir, Opts = savedDir, savedOpts })
+	ReportDir = t.TempDir()
+	Opts = Options{HistoryPath: filepath.Join(ReportDir, "history.jsonl")}
+
+	tests := []struct {
+		failAt     string
+		wantCalls  []string
+		wantPassed bool
+		wantPrefix string
+	}{
+		{wantCalls: []string{"prepare", "run", "verify", "cleanup"}, wantPassed: true, wantPrefix: "all good"},
+		{failAt: "prepare", wantCalls: []string{"prepare", "cleanup"}, wantPrefix: "prepare: prepare boom"},
+		{failAt: "run", wantCalls: []string{"prepare", "run", "cleanup"}, wantPrefix: "run: run boom"},
+		{failAt: "verify", wantCalls: []string{"prepare", "run", "verify", "cleanup"}, wantPrefix: "verify: verify boom"},
+	}
+	for _, tt := range tests {
+		t.Run("fail-at-"+tt.failAt, func(t *testing.T) {
+			s := &phaseScenario{name: "lifecycle", failAt: tt.failAt}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			report, err := Execute(ctx, cli, s)
+			cancel()
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if !reflect.DeepEqual(s.calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", s.calls, tt.wantCalls)
+			}
+			if report.Passed != tt.wantPassed || report.Summary != tt.wantPrefix {
+				t.Errorf("verdict = %v %q", report.Passed, report.Summary)
+			}
+			if report.Params["k"] != "v" {
+				t.Errorf("params = %v", report.Params)
+			}
+		})
+	}
+	records, err := LoadHistory(Opts.HistoryPath)
+	if err != nil || len(records) != len(tests) {
+		t.Fatalf("history = %d records, err %v", len(records), err)
+	}
+}
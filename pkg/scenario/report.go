@@ -0,0 +1,252 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReportDir 为场景报告的默认输出目录
+var ReportDir = "reports"
+
+// TimelineEntry 为报告时间线上的一条记录
+type TimelineEntry struct {
+	At     time.Time `json:"at"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail"`
+}
+
+// Point 为时间序列上的一个点
+type Point struct {
+	At    time.Time `json:"at"`
+	Value float64   `json:"value"`
+}
+
+// Series 为场景自定义的时间序列（例如卷的已用空间），Limit 为对应的容量上限，0 表示无上限
+type Series struct {
+	Name   string  `json:"name"`
+	Unit   string  `json:"unit"`
+	Limit  float64 `json:"limit,omitempty"`
+	Points []Point `json:"points"`
+}
+
+// Report 汇总一次场景运行中的全部容器结果、探测步骤与时间线，最终以 JSON 形式落盘
+type Report struct {
+	mu      sync.Mutex
+	monitor *EventMonitor
+
+	RunID     string            `json:"runId"`
+	Scenario  string            `json:"scenario"`
+	Params    map[string]string `json:"params,omitempty"`
+	Endpoint  Endpoint          `json:"endpoint"`
+	Host      HostFingerprint   `json:"host"`
+	StartedAt time.Time         `json:"startedAt"`
+	// RunStartedAt 为 Run 阶段的开始时间，不含 Prepare 中拉取镜像等准备工作
+	RunStartedAt time.Time       `json:"runStartedAt,omitempty"`
+	FinishedAt   time.Time       `json:"finishedAt"`
+	Runs         []*RunResult    `json:"runs,omitempty"`
+	Probes       []ProbeResult   `json:"probes,omitempty"`
+	Updates      []UpdateResult  `json:"updates,omitempty"`
+	Chaos        []ChaosResult   `json:"chaos,omitempty"`
+	Events       []Event         `json:"events,omitempty"`
+	Usage        []*Usage        `json:"usage,omitempty"`
+	Series       []*Series       `json:"series,omitempty"`
+	Timeline     []TimelineEntry `json:"timeline"`
+	Passed       bool            `json:"passed"`
+	Summary      string          `json:"summary"`
+}
+
+// NewReport 创建场景报告，RunID 由场景名、启动时间与随机后缀组成，同一秒内的多次运行不会互相覆盖
+func NewReport(scenario string, params map[string]string) *Report {
+	return &Report{
+		RunID:     NewRunID(scenario),
+		Scenario:  scenario,
+		Params:    params,
+		StartedAt: time.Now(),
+	}
+}
+
+// NewRunID 生成一个运行 ID：场景名、时间与随机后缀
+func NewRunID(scenario string) string {
+	return fmt.Sprintf("%s-%s-%06x", scenario, time.Now().Format("20060102-150405"), rand.Uint32()&0xffffff)
+}
+
+type reportKey struct{}
+
+type runIDKey struct{}
+
+// WithRunID 指定下一次 Begin 使用的运行 ID，便于调用方在运行开始之前按运行 label 注册清理
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// WithReport 把报告挂到 ctx 上，后续的容器运行与探测步骤会自动记录到该报告
+func WithReport(ctx context.Context, r *Report) context.Context {
+	return context.WithValue(ctx, reportKey{}, r)
+}
+
+// ReportFrom 取出 ctx 上的报告，没有时返回 nil
+func ReportFrom(ctx context.Context) *Report {
+	r, _ := ctx.Value(reportKey{}).(*Report)
+	return r
+}
+
+// Mark 在时间线上追加一条记录
+func (r *Report) Mark(at time.Time, kind, format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Timeline = append(r.Timeline, TimelineEntry{At: at, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+}
+
+// AddRun 记录一次容器运行，并在时间线上标记启动与退出
+func (r *Report) AddRun(result *RunResult) {
+	r.mu.Lock()
+	r.Runs = append(r.Runs, result)
+	r.mu.Unlock()
+	r.Mark(result.StartedAt, "container", "%s 启动", result.Name)
+	r.Mark(result.FinishedAt, "container", "%s 退出，退出码=%d OOMKilled=%t", result.Name, result.StatusCode, result.OOMKilled)
+}
+
+// AddProbe 记录一个探测步骤的结果
+func (r *Report) AddProbe(result ProbeResult) {
+	r.mu.Lock()
+	r.Probes = append(r.Probes, result)
+	r.mu.Unlock()
+	r.Mark(result.FinishedAt, "probe", "%s 退出码=%d", result.Name, result.ExitCode)
+}
+
+// addUsage 登记一个容器的资源采样汇总
+func (r *Report) addUsage(u *Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Usage = append(r.Usage, u)
+}
+
+// UsageOf 返回容器的资源采样，没有采样时返回 nil
+func (r *Report) UsageOf(containerID string) *Usage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.Usage {
+		if u.ContainerID == containerID {
+			return u
+		}
+	}
+	return nil
+}
+
+// MeanCPU 返回容器在 [from, to) 区间内的平均 vCPU，没有采样时返回 0
+func (r *Report) MeanCPU(containerID string, from, to time.Time) float64 {
+	u := r.UsageOf(containerID)
+	if u == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return u.MeanCPU(from, to)
+}
+
+// AddSeries 登记一条自定义时间序列
+func (r *Report) AddSeries(series *Series) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Series = append(r.Series, series)
+}
+
+// Finish 记录场景结论
+func (r *Report) Finish(passed bool, format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FinishedAt = time.Now()
+	r.Passed = passed
+	r.Summary = fmt.Sprintf(format, args...)
+}
+
+// sortedTimeline 返回按时间排序后的时间线副本
+func (r *Report) sortedTimeline() []TimelineEntry {
+	timeline := append([]TimelineEntry(nil), r.Timeline...)
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].At.Before(timeline[j].At)
+	})
+	return timeline
+}
+
+// Log 打印报告的时间线与结论
+func (r *Report) Log() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	log.Printf("==== 场景 %s 报告（%s）====", r.Scenario, r.RunID)
+	log.Printf("daemon：%s", r.Endpoint)
+	for _, entry := range r.sortedTimeline() {
+		log.Printf("  +%-8s [%s] %s", entry.At.Sub(r.StartedAt).Round(time.Millisecond), entry.Kind, entry.Detail)
+	}
+	verdict := "通过"
+	if !r.Passed {
+		verdict = "未通过"
+	}
+	log.Printf("结论：%s，%s", verdict, r.Summary)
+}
+
+// Save 把报告写入 dir/<RunID>.json，返回文件路径
+func (r *Report) Save(dir string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Timeline = r.sortedTimeline()
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("序列化报告失败: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("创建报告目录失败: %w", err)
+	}
+	path := filepath.Join(dir, r.RunID+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("写入报告失败: %w", err)
+	}
+	return path, nil
+}
+
+// LoadReport 读取 Save 保存的报告
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取报告失败: %w", err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("解析报告 %s 失败: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Publish 停止事件监听后打印报告并保存到 ReportDir，追加历史记录，启用指标时同步更新 /metrics
+func Publish(r *Report) error {
+	r.stopMonitor()
+	r.Log()
+	path, err := r.Save(ReportDir)
+	if err != nil {
+		return err
+	}
+	log.Printf("报告已保存到 %s", path)
+
+	if Opts.HistoryPath != "" {
+		if err := AppendHistory(Opts.HistoryPath, RecordFromReport(r)); err != nil {
+			return err
+		}
+	}
+
+	if m := startMetricsServer(); m != nil {
+		m.Observe(r)
+		if Opts.MetricsLinger > 0 {
+			log.Printf("继续暴露指标 %s", Opts.MetricsLinger)
+			time.Sleep(Opts.MetricsLinger)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReportFromContext(t *testing.T) {
+	if ReportFrom(context.Background()) != nil {
+		t.Fatal("expected no report on a bare context")
+	}
+	r := NewReport("demo", nil)
+	if got := ReportFrom(WithReport(context.Background(), r)); got != r {
+		t.Fatalf("ReportFrom returned %p, want %p", got, r)
+	}
+}
+
+func TestReportRunIDUnique(t *testing.T) {
+	a, b := NewReport("demo", nil), NewReport("demo", nil)
+	if a.RunID == b.RunID {
+		t.Fatalf("two reports in the same second share RunID %q", a.RunID)
+	}
+}
+
+func TestReportSaveSortsTimeline(t *testing.T) {
+	r := NewReport("demo", map[string]string{"limit": "1"})
+	start := r.StartedAt
+	r.AddProbe(ProbeResult{Name: "late", StartedAt: start.Add(2 * time.Second), FinishedAt: start.Add(3 * time.Second)})
+	r.AddRun(&RunResult{Name: "early", StartedAt: start, FinishedAt: start.Add(time.Second), StatusCode: 42})
+	r.Finish(true, "ok")
+
+	path, err := r.Save(t.TempDir())
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Report
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("unmarshal report: %v", err)
+	}
+	if len(saved.Timeline) != 3 {
+		t.Fatalf("timeline has %d entries, want 3", len(saved.Timeline))
+	}
+	for i := 1; i < len(saved.Timeline); i++ {
+		if saved.Timeline[i].At.Before(saved.Timeline[i-1].At) {
+			t.Fatalf("timeline not sorted: %+v", saved.Timeline)
+		}
+	}
+	if saved.Timeline[2].Kind != "probe" {
+		t.Fatalf("last entry kind = %q, want probe", saved.Timeline[2].Kind)
+	}
+}
+
+func TestReadFilesStepSkipsMissingFiles(t *testing.T) {
+	step := ReadFilesStep("cgroup", "/sys/fs/cgroup/cpu.max", "/sys/fs/cgroup/cpu/cpu.cfs_quota_us")
+	if len(step.Cmd) != 3 || step.Cmd[0] != "sh" {
+		t.Fatalf("unexpected cmd %q", step.Cmd)
+	}
+	want := `if [ -r "/sys/fs/cgroup/cpu.max" ]; then echo "== /sys/fs/cgroup/cpu.max"; cat "/sys/fs/cgroup/cpu.max"; fi; `
+	if got := step.Cmd[2][:len(want)]; got != want {
+		t.Fatalf("script prefix = %q, want %q", got, want)
+	}
+}
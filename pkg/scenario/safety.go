@@ -0,0 +1,188 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// SafetyRatio 为计划占用允许达到宿主机余量的比例，留出的部分供 daemon 与宿主机上的其他进程使用
+const SafetyRatio = 0.8
+
+// ContainerOverhead 为每个运行中的容器在限额之外占用的宿主机内存估计：containerd-shim、
+// 容器内 1 号进程之外的运行时进程以及内核中的 cgroup、网络命名空间等结构都不计入容器的内存 cgroup
+const ContainerOverhead = 32 * MiB
+
+// Footprint 为场景运行期间对宿主机资源的最大占用估计，同时运行的容器累加计算。
+// 计划中的场景严格依次运行，占用只在单个场景内累加
+type Footprint struct {
+	// Memory 为同时运行的容器内存限额之和
+	Memory int64
+	// Tmpfs 为 tmpfs 卷与 /dev/shm 的容量之和。tmpfs 页会计入写入者的内存 cgroup，
+	// 但卷在容器退出后仍占用宿主机内存，这里按最坏情况与 Memory 一起计入
+	Tmpfs int64
+	// Disk 为写入宿主机磁盘（可写层、日志）的上限之和
+	Disk int64
+	// Containers 为同时运行的容器数，每个容器另计 ContainerOverhead
+	Containers int
+}
+
+// HostMemory 返回计划占用的宿主机内存，包括每个容器在限额之外的开销
+func (f Footprint) HostMemory() int64 {
+	return f.Memory + f.Tmpfs + int64(f.Containers)*ContainerOverhead
+}
+
+func (f Footprint) String() string {
+	return fmt.Sprintf("内存限额 %dMiB + tmpfs %dMiB + %d 个容器的开销 %dMiB，磁盘 %dMiB",
+		f.Memory/MiB, f.Tmpfs/MiB, f.Containers, int64(f.Containers)*ContainerOverhead/MiB, f.Disk/MiB)
+}
+
+// Footprinted 为可选接口，会消耗宿主机内存或磁盘的场景实现后，运行前由 GuardPlan 与宿主机余量比较
+type Footprinted interface {
+	Footprint() Footprint
+}
+
+// HostCapacity 为 daemon 宿主机的内存与磁盘余量（字节）
+type HostCapacity struct {
+	MemTotal     int64
+	MemAvailable int64
+	// DiskFree 为 daemon 数据目录（容器可写层所在文件系统）的剩余空间
+	DiskFree int64
+}
+
+// capacityScript 输出宿主机 /proc/meminfo 中的 MemAvailable 与容器根文件系统所在文件系统的剩余空间（KiB）
+const capacityScript = `awk '/^MemAvailable:/ {print "mem_available_kib", $2}' /proc/meminfo
+df -k -P / | awk 'NR == 2 {print "disk_free_kib", $4}'`
+
+// CollectHostCapacity 查询 daemon Info 的 MemTotal，并在探测容器内读取宿主机可用内存与
+// 可写层所在文件系统的剩余空间。image 需要提前拉取
+func CollectHostCapacity(ctx context.Context, cli *client.Client, image string) (HostCapacity, error) {
+	info, err := cli.Info(ctx, client.InfoOptions{})
+	if err != nil {
+		return HostCapacity{}, fmt.Errorf("查询 daemon Info 失败: %w", err)
+	}
+	capacity := HostCapacity{MemTotal: info.Info.MemTotal}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	result, err := RunControlledContainer(ctx, cli, &container.Config{
+		Image: image,
+		Cmd:   []string{"sh", "-c", capacityScript},
+	}, &container.HostConfig{}, "host-capacity")
+	if err != nil {
+		return capacity, fmt.Errorf("探测宿主机余量失败: %w", err)
+	}
+	if result.StatusCode != 0 {
+		return capacity, fmt.Errorf("探测宿主机余量的容器退出码 %d: %s", result.StatusCode, strings.TrimSpace(result.Stderr))
+	}
+	values := parseCapacity(result.Stdout)
+	capacity.MemAvailable = values["mem_available_kib"] * 1024
+	capacity.DiskFree = values["disk_free_kib"] * 1024
+	return capacity, nil
+}
+
+// parseCapacity 解析 "<key> <value>" 形式的输出
+func parseCapacity(out string) map[string]int64 {
+	values := map[string]int64{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			values[key] = n
+		}
+	}
+	return values
+}
+
+// SafetyVerdict 为一个场景的安全检查结果，Problems 为空表示可以运行
+type SafetyVerdict struct {
+	Name      string
+	Footprint Footprint
+	Problems  []string
+}
+
+// Safe 表示场景的占用在宿主机余量之内
+func (v SafetyVerdict) Safe() bool {
+	return len(v.Problems) == 0
+}
+
+// CheckFootprint 比较一个场景的占用与宿主机余量。场景依次运行，占用不在场景之间累加；
+// 余量为 0 表示未能读取，对应的比较被跳过
+func CheckFootprint(name string, f Footprint, host HostCapacity) SafetyVerdict {
+	v := SafetyVerdict{Name: name, Footprint: f}
+	mem := f.HostMemory()
+	if host.MemTotal > 0 && float64(mem) > SafetyRatio*float64(host.MemTotal) {
+		v.Problems = append(v.Problems, fmt.Sprintf("计划占用内存 %dMiB，超过宿主机总内存 %dMiB 的 %.0f%%",
+			mem/MiB, host.MemTotal/MiB, SafetyRatio*100))
+	}
+	if host.MemAvailable > 0 && float64(mem) > SafetyRatio*float64(host.MemAvailable) {
+		v.Problems = append(v.Problems, fmt.Sprintf("计划占用内存 %dMiB，超过宿主机可用内存 %dMiB 的 %.0f%%",
+			mem/MiB, host.MemAvailable/MiB, SafetyRatio*100))
+	}
+	if host.DiskFree > 0 && float64(f.Disk) > SafetyRatio*float64(host.DiskFree) {
+		v.Problems = append(v.Problems, fmt.Sprintf("计划占用磁盘 %dMiB，超过宿主机剩余空间 %dMiB 的 %.0f%%",
+			f.Disk/MiB, host.DiskFree/MiB, SafetyRatio*100))
+	}
+	return v
+}
+
+// GuardPlan 在运行前检查计划中声明了 Footprint 的场景：超出宿主机余量的场景从计划中剔除，
+// 其余场景照常运行；force 为 true 时只打印警告，全部保留。无法读取宿主机余量时，
+// 声明了占用的场景在非 force 模式下一律剔除。返回保留的场景与被剔除的场景名
+func GuardPlan(ctx context.Context, cli *client.Client, plan []Scenario, force bool) ([]Scenario, []string) {
+	footprints := map[string]Footprint{}
+	for _, s := range plan {
+		if f, ok := s.(Footprinted); ok {
+			footprints[s.Name()] = f.Footprint()
+		}
+	}
+	if len(footprints) == 0 {
+		return plan, nil
+	}
+
+	var host HostCapacity
+	err := PullImage(ctx, cli, ProbeImage)
+	if err == nil {
+		host, err = CollectHostCapacity(ctx, cli, ProbeImage)
+	}
+	if err != nil {
+		log.Printf("宿主机安全检查：%v", err)
+	} else {
+		log.Printf("宿主机安全检查：总内存 %dMiB，可用内存 %dMiB，剩余磁盘 %dMiB，允许占用 %.0f%%",
+			host.MemTotal/MiB, host.MemAvailable/MiB, host.DiskFree/MiB, SafetyRatio*100)
+	}
+
+	var allowed []Scenario
+	var refused []string
+	for _, s := range plan {
+		f, ok := footprints[s.Name()]
+		if !ok {
+			allowed = append(allowed, s)
+			continue
+		}
+		v := CheckFootprint(s.Name(), f, host)
+		if err != nil {
+			v.Problems = append(v.Problems, "无法读取宿主机余量")
+		}
+		switch {
+		case v.Safe():
+			log.Printf("  %s：%s", s.Name(), f)
+			allowed = append(allowed, s)
+		case force:
+			log.Printf("  %s：%s，%s；已指定 -force，仍然运行", s.Name(), f, strings.Join(v.Problems, "；"))
+			allowed = append(allowed, s)
+		default:
+			log.Printf("  %s：%s，%s；跳过（可用 -force 强制运行）", s.Name(), f, strings.Join(v.Problems, "；"))
+			refused = append(refused, s.Name())
+		}
+	}
+	return allowed, refused
+}
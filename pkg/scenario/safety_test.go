@@ -0,0 +1,51 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckFootprint(t *testing.T) {
+	host := HostCapacity{MemTotal: 4096 * MiB, MemAvailable: 1024 * MiB, DiskFree: 2048 * MiB}
+	cases := []struct {
+		name     string
+		f        Footprint
+		problems []string
+	}{
+		{"within", Footprint{Memory: 256 * MiB, Tmpfs: 192 * MiB, Disk: 1024 * MiB}, nil},
+		// 内存限额与 tmpfs 合计超过可用内存的 80%
+		{"memory", Footprint{Memory: 512 * MiB, Tmpfs: 512 * MiB}, []string{"可用内存"}},
+		{"disk", Footprint{Disk: 1800 * MiB}, []string{"剩余空间"}},
+		{"one container", Footprint{Memory: 600 * MiB, Containers: 1}, nil},
+		// 限额本身在余量之内，加上每个容器的开销后超出
+		{"containers", Footprint{Memory: 600 * MiB, Containers: 8}, []string{"可用内存"}},
+		{"both", Footprint{Memory: 4000 * MiB, Disk: 4096 * MiB}, []string{"总内存", "可用内存", "剩余空间"}},
+	}
+	for _, c := range cases {
+		v := CheckFootprint(c.name, c.f, host)
+		if len(v.Problems) != len(c.problems) {
+			t.Errorf("%s: Problems = %v", c.name, v.Problems)
+			continue
+		}
+		for i, want := range c.problems {
+			if !strings.Contains(v.Problems[i], want) {
+				t.Errorf("%s: Problems[%d] = %q, want %q", c.name, i, v.Problems[i], want)
+			}
+		}
+		if v.Safe() != (len(c.problems) == 0) {
+			t.Errorf("%s: Safe = %v", c.name, v.Safe())
+		}
+	}
+
+	// 未能读取的余量不参与比较
+	if v := CheckFootprint("unknown", Footprint{Memory: 1 << 40}, HostCapacity{}); !v.Safe() {
+		t.Errorf("余量未知时 Problems = %v", v.Problems)
+	}
+}
+
+func TestParseCapacity(t *testing.T) {
+	values := parseCapacity("mem_available_kib 2048000\ndisk_free_kib 10485760\nnoise\n")
+	if values["mem_available_kib"] != 2048000 || values["disk_free_kib"] != 10485760 || len(values) != 2 {
+		t.Fatalf("parseCapacity = %v", values)
+	}
+}
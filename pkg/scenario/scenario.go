@@ -0,0 +1,68 @@
+// Package scenario 收敛各个资源限制场景共用的逻辑：Docker 客户端、镜像拉取、
+// 受限容器的运行与日志采集、Volume 复建以及场景报告。
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/client"
+)
+
+const MiB = 1024 * 1024
+
+// ActiveEndpoint 为最近一次 NewDockerClient 选择的 daemon，Begin 会把它写入报告
+var ActiveEndpoint Endpoint
+
+// NewDockerClient 按 Opts 中的 -host/-context/-tls-cert-path 以及 DOCKER_* 环境变量、
+// docker CLI 的 context 配置选择 daemon 并创建客户端，同时自动协商 API 版本
+func NewDockerClient() (*client.Client, error) {
+	ep, err := ResolveEndpoint(Opts)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := ep.clientOpts()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, client.WithVersionFromEnv(), client.WithAPIVersionNegotiation())
+	cli, err := client.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("连接 %s 失败: %w", ep, err)
+	}
+	ActiveEndpoint = ep
+	return cli, nil
+}
+
+// MissingImages 返回 s 通过 LocalImaged 声明、但 daemon 上不存在的镜像
+func MissingImages(ctx context.Context, cli *client.Client, s Scenario) ([]string, error) {
+	l, ok := s.(LocalImaged)
+	if !ok {
+		return nil, nil
+	}
+	var missing []string
+	for _, ref := range l.LocalImages() {
+		if _, err := cli.ImageInspect(ctx, ref); err != nil {
+			if !errdefs.IsNotFound(err) {
+				return nil, fmt.Errorf("查询镜像 %s 失败: %w", ref, err)
+			}
+			missing = append(missing, ref)
+		}
+	}
+	return missing, nil
+}
+
+// PullImage 拉取镜像并等待拉取完成，进度输出直接丢弃
+func PullImage(ctx context.Context, cli *client.Client, ref string) error {
+	resp, err := cli.ImagePull(ctx, ref, client.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	if _, err := io.Copy(io.Discard, resp); err != nil {
+		return fmt.Errorf("读取镜像 %s 拉取进度失败: %w", ref, err)
+	}
+	return nil
+}
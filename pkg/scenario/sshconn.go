@@ -0,0 +1,85 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// sshDialer 为 ssh://[user@]host[:port] 端点返回拨号函数：每次拨号启动一个
+// ssh 子进程执行远端的 docker system dial-stdio，子进程的 stdin/stdout 即为连接
+func sshDialer(host string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("解析 ssh 地址 %s 失败: %w", host, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("ssh 地址 %s 缺少主机名", host)
+	}
+	var args []string
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, "--", u.Hostname(), "docker", "system", "dial-stdio")
+
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		// 连接的生命周期独立于拨号 ctx，由 Close 结束子进程
+		cmd := exec.Command("ssh", args...)
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("启动 ssh 失败: %w", err)
+		}
+		return &commandConn{cmd: cmd, stdin: stdin, stdout: stdout, remote: u.Host}, nil
+	}, nil
+}
+
+// commandConn 把子进程的标准输入输出包装为 net.Conn，不支持读写超时
+type commandConn struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    io.ReadCloser
+	remote    string
+	closeOnce sync.Once
+}
+
+func (c *commandConn) Read(p []byte) (int, error)  { return c.stdout.Read(p) }
+func (c *commandConn) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+// Close 关闭管道并结束子进程
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		c.stdout.Close()
+		_ = c.cmd.Process.Kill()
+		_ = c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr  { return commandAddr("local") }
+func (c *commandConn) RemoteAddr() net.Addr { return commandAddr(c.remote) }
+
+func (c *commandConn) SetDeadline(time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(time.Time) error { return nil }
+
+// commandAddr 为 commandConn 的占位地址
+type commandAddr string
+
+func (a commandAddr) Network() string { return "ssh" }
+func (a commandAddr) String() string  { return string(a) }
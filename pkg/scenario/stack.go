@@ -0,0 +1,264 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/api/types/network"
+	"github.com/moby/moby/client"
+)
+
+// defaultReadyTimeout 为成员未指定 ReadyTimeout 时等待就绪的上限
+const defaultReadyTimeout = 30 * time.Second
+
+// StackVolume 为 Stack 内共享的 volume，TmpfsSize 大于 0 时创建为限额 tmpfs，否则为普通 local volume
+type StackVolume struct {
+	Name      string
+	TmpfsSize int64
+}
+
+// StackMember 为 Stack 中的一个命名容器，资源限额与挂载写在 Host 上；
+// 成员自动加入 Stack 的网络，并以 Name 作为网络内的别名
+type StackMember struct {
+	Name   string
+	Config container.Config
+	Host   container.HostConfig
+	// DependsOn 为需要先就绪的成员
+	DependsOn []string
+	// Ready 为就绪探测，退出码为 0 即就绪；为空时容器启动即就绪
+	Ready        *ProbeStep
+	ReadyTimeout time.Duration
+}
+
+// VolumeMount 返回把共享 volume 挂载到 target 的挂载点
+func VolumeMount(volume, target string, readOnly bool) mount.Mount {
+	return mount.Mount{Type: mount.TypeVolume, Source: volume, Target: target, ReadOnly: readOnly}
+}
+
+// Stack 把多个命名容器、共享 volume 与一个 bridge 网络作为一个整体管理：Up 按依赖顺序启动成员并等待就绪，
+// Down 删除全部资源。成员都是 Session，通过本包的探测辅助函数执行的步骤与资源采样记录在同一份报告中
+type Stack struct {
+	// Name 用作网络名与容器名前缀
+	Name    string
+	Volumes []StackVolume
+	Members []StackMember
+
+	// network 为创建的网络 ID
+	network  string
+	sessions map[string]*Session
+	order    []string
+}
+
+// StartOrder 按 DependsOn 返回成员的启动顺序，依赖相同层级的成员保持声明顺序；
+// 依赖未知成员或存在循环依赖时返回错误
+func (s *Stack) StartOrder() ([]string, error) {
+	members := map[string]StackMember{}
+	for _, m := range s.Members {
+		if _, dup := members[m.Name]; dup {
+			return nil, fmt.Errorf("成员 %s 重复", m.Name)
+		}
+		members[m.Name] = m
+	}
+	var order []string
+	state := map[string]int{} // 1 为访问中，2 为已排序
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("循环依赖: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+		m, ok := members[name]
+		if !ok {
+			return fmt.Errorf("成员 %s 依赖未知成员 %s", path[len(path)-1], name)
+		}
+		state[name] = 1
+		for _, dep := range m.DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+	for _, m := range s.Members {
+		if err := visit(m.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Up 创建共享 volume 与网络（先删除遗留的同名网络），按依赖顺序启动成员，每个成员就绪后才启动依赖它的成员。
+// 失败时已创建的资源保留，由调用方通过 Down 清理
+func (s *Stack) Up(ctx context.Context, cli *client.Client) error {
+	order, err := s.StartOrder()
+	if err != nil {
+		return err
+	}
+	report := ReportFrom(ctx)
+	mark := func(format string, args ...any) {
+		if report != nil {
+			report.Mark(time.Now(), "stack", format, args...)
+		}
+	}
+
+	for _, v := range s.Volumes {
+		if v.TmpfsSize > 0 {
+			err = RecreateTmpfsVolume(ctx, cli, v.Name, v.TmpfsSize)
+		} else {
+			_, err = cli.VolumeCreate(ctx, client.VolumeCreateOptions{Name: v.Name, Driver: "local"})
+		}
+		if err != nil {
+			return fmt.Errorf("创建共享 volume %s 失败: %w", v.Name, err)
+		}
+	}
+	// 中途崩溃的运行会遗留同名网络，使 NetworkCreate 因名称冲突失败，先删除；仍有容器连接时删除失败并报错
+	if _, err := cli.NetworkRemove(ctx, s.Name, client.NetworkRemoveOptions{}); err == nil {
+		mark("删除遗留的网络 %s", s.Name)
+	} else if !errdefs.IsNotFound(err) {
+		return fmt.Errorf("删除遗留的网络 %s 失败: %w", s.Name, err)
+	}
+	created, err := cli.NetworkCreate(ctx, s.Name, client.NetworkCreateOptions{Driver: "bridge"})
+	if err != nil {
+		return fmt.Errorf("创建网络 %s 失败: %w", s.Name, err)
+	}
+	s.network = created.ID
+	mark("创建网络 %s 与 %d 个共享 volume", s.Name, len(s.Volumes))
+
+	s.sessions = map[string]*Session{}
+	s.order = nil
+	members := map[string]StackMember{}
+	for _, m := range s.Members {
+		members[m.Name] = m
+	}
+	for _, name := range order {
+		m := members[name]
+		cfg, host := m.Config, m.Host
+		host.NetworkMode = container.NetworkMode(s.network)
+		sess, err := StartNetworkedSession(ctx, cli, &cfg, &host, &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{s.network: {Aliases: []string{m.Name}}},
+		}, s.Name+"-"+m.Name)
+		if err != nil {
+			return fmt.Errorf("启动成员 %s 失败: %w", m.Name, err)
+		}
+		s.sessions[m.Name] = sess
+		s.order = append(s.order, m.Name)
+		mark("成员 %s 已启动", m.Name)
+
+		if m.Ready != nil {
+			waited, err := s.waitReady(ctx, sess, m)
+			if err != nil {
+				return err
+			}
+			mark("成员 %s 已就绪（%s）", m.Name, waited.Round(time.Millisecond))
+		}
+	}
+	return nil
+}
+
+// waitReady 反复执行成员的就绪探测，直到退出码为 0 或超时
+func (s *Stack) waitReady(ctx context.Context, sess *Session, m StackMember) (time.Duration, error) {
+	timeout := m.ReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	start := time.Now()
+	var last ProbeResult
+	for time.Since(start) < timeout {
+		result, err := sess.Exec(ctx, *m.Ready)
+		if err != nil {
+			return 0, fmt.Errorf("成员 %s 的就绪探测中断: %w", m.Name, err)
+		}
+		if result.ExitCode == 0 {
+			return time.Since(start), nil
+		}
+		last = result
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	return 0, fmt.Errorf("成员 %s 在 %s 内未就绪，最后一次探测退出码 %d: %s",
+		m.Name, timeout, last.ExitCode, strings.TrimSpace(last.Stderr))
+}
+
+// Session 返回成员对应的会话，成员未启动时返回 nil
+func (s *Stack) Session(name string) *Session {
+	return s.sessions[name]
+}
+
+// Down 按启动的逆序删除成员，再删除网络与共享 volume
+func (s *Stack) Down(ctx context.Context, cli *client.Client) error {
+	for i := len(s.order) - 1; i >= 0; i-- {
+		s.sessions[s.order[i]].Close()
+	}
+	s.sessions, s.order = nil, nil
+
+	var errs []error
+	if s.network != "" {
+		if _, err := cli.NetworkRemove(ctx, s.network, client.NetworkRemoveOptions{}); err != nil && !errdefs.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("删除网络 %s 失败: %w", s.Name, err))
+		}
+		s.network = ""
+	}
+	for _, v := range s.Volumes {
+		if err := RemoveVolume(ctx, cli, v.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MemberCheck 为针对单个成员的断言：在成员内执行 Step，由 Want 判断结果
+type MemberCheck struct {
+	Member string
+	Name   string
+	Step   ProbeStep
+	Want   func(ProbeResult) error
+}
+
+// CheckResult 为一个断言的结果，Err 为 nil 表示通过
+type CheckResult struct {
+	Member string
+	Name   string
+	Output string
+	Err    error
+}
+
+// Check 依次执行各成员的断言并打印结果；只有 API 调用失败才返回 error
+func (s *Stack) Check(ctx context.Context, checks []MemberCheck) ([]CheckResult, error) {
+	results := make([]CheckResult, 0, len(checks))
+	for _, c := range checks {
+		r := CheckResult{Member: c.Member, Name: c.Name}
+		sess := s.Session(c.Member)
+		if sess == nil {
+			r.Err = fmt.Errorf("成员 %s 未启动", c.Member)
+			results = append(results, r)
+			continue
+		}
+		result, err := sess.Exec(ctx, c.Step)
+		if err != nil {
+			return results, fmt.Errorf("成员 %s 的断言 %s 中断: %w", c.Member, c.Name, err)
+		}
+		r.Output = strings.TrimSpace(result.Stdout)
+		r.Err = c.Want(result)
+		verdict := "通过"
+		if r.Err != nil {
+			verdict = "失败: " + r.Err.Error()
+		}
+		log.Printf("[%s] %s：%s（%s）", c.Member, c.Name, verdict, r.Output)
+		results = append(results, r)
+	}
+	return results, nil
+}
@@ -0,0 +1,47 @@
+package scenario
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func stackOf(deps map[string][]string, names ...string) *Stack {
+	s := &Stack{Name: "test"}
+	for _, name := range names {
+		s.Members = append(s.Members, StackMember{Name: name, DependsOn: deps[name]})
+	}
+	return s
+}
+
+func TestStackStartOrder(t *testing.T) {
+	s := stackOf(map[string][]string{
+		"writer": {"reader"},
+		"reader": {"server"},
+	}, "writer", "reader", "server", "idle")
+	order, err := s.StartOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"server", "reader", "writer", "idle"}; !slices.Equal(order, want) {
+		t.Errorf("StartOrder = %v, want %v", order, want)
+	}
+}
+
+func TestStackStartOrderErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		stack *Stack
+		want  string
+	}{
+		{"循环依赖", stackOf(map[string][]string{"a": {"b"}, "b": {"a"}}, "a", "b"), "a -> b -> a"},
+		{"未知成员", stackOf(map[string][]string{"a": {"missing"}}, "a"), "未知成员 missing"},
+		{"重复成员", stackOf(nil, "a", "a"), "重复"},
+	}
+	for _, c := range cases {
+		_, err := c.stack.StartOrder()
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want 包含 %q", c.name, err, c.want)
+		}
+	}
+}
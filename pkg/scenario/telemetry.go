@@ -0,0 +1,155 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// Sample 为一次容器资源采样，CPU 为与上一次采样之间的平均 vCPU 用量
+type Sample struct {
+	At               time.Time `json:"at"`
+	MemoryUsage      uint64    `json:"memoryUsage"`
+	MemoryLimit      uint64    `json:"memoryLimit,omitempty"`
+	CPU              float64   `json:"cpu"`
+	CPUUsageNanos    uint64    `json:"cpuUsageNanos"`
+	ThrottledPeriods uint64    `json:"throttledPeriods"`
+	ThrottledNanos   uint64    `json:"throttledNanos"`
+	Pids             uint64    `json:"pids"`
+}
+
+// Usage 汇总单个容器的资源限额与采样结果，限额为最近一次生效的值
+type Usage struct {
+	Container string `json:"container"`
+	// Role 为容器名去掉时间戳后的前缀，同一场景的多次运行保持不变，用作指标标签
+	Role             string   `json:"role"`
+	ContainerID      string   `json:"containerId"`
+	MemoryLimit      int64    `json:"memoryLimit,omitempty"`
+	CPULimit         float64  `json:"cpuLimit,omitempty"`
+	PidsLimit        int64    `json:"pidsLimit,omitempty"`
+	PeakMemory       uint64   `json:"peakMemory"`
+	PeakCPU          float64  `json:"peakCpu"`
+	ThrottledPeriods uint64   `json:"throttledPeriods"`
+	ThrottledNanos   uint64   `json:"throttledNanos"`
+	Samples          []Sample `json:"samples,omitempty"`
+}
+
+// limitsFromHostConfig 从 HostConfig 中换算内存、vCPU 与 pids 限额，未设置时为 0
+func limitsFromHostConfig(u *Usage, hc *container.HostConfig) {
+	if hc == nil {
+		return
+	}
+	u.MemoryLimit = hc.Memory
+	switch {
+	case hc.NanoCPUs > 0:
+		u.CPULimit = float64(hc.NanoCPUs) / 1e9
+	case hc.CPUQuota > 0:
+		period := hc.CPUPeriod
+		if period == 0 {
+			period = 100000
+		}
+		u.CPULimit = float64(hc.CPUQuota) / float64(period)
+	}
+	if hc.PidsLimit != nil {
+		u.PidsLimit = *hc.PidsLimit
+	}
+}
+
+// add 追加一次采样，根据上一次采样换算 CPU 用量并更新峰值
+func (u *Usage) add(s Sample) {
+	if n := len(u.Samples); n > 0 {
+		prev := u.Samples[n-1]
+		if elapsed := s.At.Sub(prev.At); elapsed > 0 && s.CPUUsageNanos >= prev.CPUUsageNanos {
+			s.CPU = float64(s.CPUUsageNanos-prev.CPUUsageNanos) / float64(elapsed.Nanoseconds())
+		}
+	}
+	u.Samples = append(u.Samples, s)
+	u.PeakMemory = max(u.PeakMemory, s.MemoryUsage)
+	u.PeakCPU = max(u.PeakCPU, s.CPU)
+	u.ThrottledPeriods = max(u.ThrottledPeriods, s.ThrottledPeriods)
+	u.ThrottledNanos = max(u.ThrottledNanos, s.ThrottledNanos)
+}
+
+// MeanCPU 返回 [from, to) 区间内采样的平均 vCPU，区间内没有可换算的采样时返回 0
+func (u *Usage) MeanCPU(from, to time.Time) float64 {
+	var sum float64
+	var n int
+	for i, s := range u.Samples {
+		if i == 0 || s.At.Before(from) || !s.At.Before(to) {
+			continue
+		}
+		sum += s.CPU
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+func sampleFromStats(stats *container.StatsResponse) Sample {
+	return Sample{
+		At:               stats.Read,
+		MemoryUsage:      stats.MemoryStats.Usage,
+		MemoryLimit:      stats.MemoryStats.Limit,
+		CPUUsageNanos:    stats.CPUStats.CPUUsage.TotalUsage,
+		ThrottledPeriods: stats.CPUStats.ThrottlingData.ThrottledPeriods,
+		ThrottledNanos:   stats.CPUStats.ThrottlingData.ThrottledTime,
+		Pids:             stats.PidsStats.Current,
+	}
+}
+
+// StartTelemetry 订阅容器的 stats 流并把采样记录到 ctx 中的报告，返回的函数用于停止采样。
+// role 为不含时间戳的容器名前缀。ctx 中没有报告时不做任何事
+func StartTelemetry(ctx context.Context, cli *client.Client, id, name, role string) func() {
+	report := ReportFrom(ctx)
+	if report == nil {
+		return func() {}
+	}
+	usage := &Usage{Container: name, Role: role, ContainerID: id}
+	if inspect, err := cli.ContainerInspect(ctx, id, client.ContainerInspectOptions{}); err == nil {
+		limitsFromHostConfig(usage, inspect.Container.HostConfig)
+	}
+	report.addUsage(usage)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stats, err := cli.ContainerStats(ctx, id, client.ContainerStatsOptions{Stream: true})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("订阅容器 %s 资源采样失败: %v", name, err)
+			}
+			return
+		}
+		defer stats.Body.Close()
+		dec := json.NewDecoder(stats.Body)
+		for {
+			var resp container.StatsResponse
+			if err := dec.Decode(&resp); err != nil {
+				if ctx.Err() == nil && !errors.Is(err, io.EOF) {
+					log.Printf("读取容器 %s 资源采样失败: %v", name, err)
+				}
+				return
+			}
+			// 容器退出后 daemon 会返回读取时间为零值的空采样
+			if resp.Read.IsZero() {
+				continue
+			}
+			report.mu.Lock()
+			usage.add(sampleFromStats(&resp))
+			report.mu.Unlock()
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
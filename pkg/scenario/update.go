@@ -0,0 +1,250 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// cfsPeriod 为 docker 换算 NanoCPUs 时使用的 CFS 周期（微秒）
+const cfsPeriod = 100000
+
+// LimitUpdate 描述在时间线上对运行中容器施加的一次资源调整，After 为相对会话开始的偏移。
+// Resources 中为 0 的字段保持不变；修改 Memory 时通常需要同时修改 MemorySwap
+type LimitUpdate struct {
+	Name      string
+	After     time.Duration
+	Resources container.Resources
+}
+
+// UpdateResult 记录一次调整的生效时间，以及从容器内 cgroup 文件读回的值与期望值的差异
+type UpdateResult struct {
+	Name       string            `json:"name"`
+	At         time.Time         `json:"at"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	Cgroup     map[string]string `json:"cgroup"`
+	Mismatches []string          `json:"mismatches,omitempty"`
+}
+
+// cgroupExpect 为一项限额在 cgroup v2 与 v1 中对应的文件及期望内容，任一文件存在且相等即视为生效
+type cgroupExpect struct {
+	Field string
+	Want  map[string]string
+}
+
+// expectedCgroup 把 Resources 中设置的字段换算为容器内 cgroup 文件的期望内容
+func expectedCgroup(res container.Resources) []cgroupExpect {
+	var expects []cgroupExpect
+	if res.Memory > 0 {
+		v := strconv.FormatInt(res.Memory, 10)
+		expects = append(expects, cgroupExpect{Field: "Memory", Want: map[string]string{
+			"/sys/fs/cgroup/memory.max":                   v,
+			"/sys/fs/cgroup/memory/memory.limit_in_bytes": v,
+		}})
+	}
+	if res.NanoCPUs > 0 {
+		quota := res.NanoCPUs * cfsPeriod / 1e9
+		expects = append(expects, cgroupExpect{Field: "NanoCPUs", Want: map[string]string{
+			"/sys/fs/cgroup/cpu.max":              fmt.Sprintf("%d %d", quota, cfsPeriod),
+			"/sys/fs/cgroup/cpu/cpu.cfs_quota_us": strconv.FormatInt(quota, 10),
+		}})
+	}
+	if res.CPUQuota > 0 {
+		period := res.CPUPeriod
+		if period == 0 {
+			period = cfsPeriod
+		}
+		expects = append(expects, cgroupExpect{Field: "CPUQuota", Want: map[string]string{
+			"/sys/fs/cgroup/cpu.max":              fmt.Sprintf("%d %d", res.CPUQuota, period),
+			"/sys/fs/cgroup/cpu/cpu.cfs_quota_us": strconv.FormatInt(res.CPUQuota, 10),
+		}})
+	}
+	if res.CPUShares > 0 {
+		expects = append(expects, cgroupExpect{Field: "CPUShares", Want: map[string]string{
+			"/sys/fs/cgroup/cpu.weight":     strconv.FormatInt(sharesToWeight(res.CPUShares), 10),
+			"/sys/fs/cgroup/cpu/cpu.shares": strconv.FormatInt(res.CPUShares, 10),
+		}})
+	}
+	if res.PidsLimit != nil && *res.PidsLimit > 0 {
+		v := strconv.FormatInt(*res.PidsLimit, 10)
+		expects = append(expects, cgroupExpect{Field: "PidsLimit", Want: map[string]string{
+			"/sys/fs/cgroup/pids.max":      v,
+			"/sys/fs/cgroup/pids/pids.max": v,
+		}})
+	}
+	return expects
+}
+
+// sharesToWeight 按 runc 的换算规则把 cgroup v1 的 cpu.shares（2~262144）映射为 v2 的 cpu.weight（1~10000）
+func sharesToWeight(shares int64) int64 {
+	return 1 + (shares-2)*9999/262142
+}
+
+// ParseReadFiles 解析 ReadFilesStep 的输出（“== path” 后跟文件内容），返回路径到内容的映射
+func ParseReadFiles(out string) map[string]string {
+	files := map[string]string{}
+	var path string
+	var content []string
+	flush := func() {
+		if path != "" {
+			files[path] = strings.TrimSpace(strings.Join(content, "\n"))
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if p, ok := strings.CutPrefix(line, "== "); ok {
+			flush()
+			path, content = p, nil
+			continue
+		}
+		content = append(content, line)
+	}
+	flush()
+	return files
+}
+
+// checkCgroup 比较读回的 cgroup 文件与期望值，返回不一致的描述
+func checkCgroup(expects []cgroupExpect, files map[string]string) []string {
+	var mismatches []string
+	for _, e := range expects {
+		var found []string
+		matched := false
+		for path, want := range e.Want {
+			got, ok := files[path]
+			if !ok {
+				continue
+			}
+			found = append(found, fmt.Sprintf("%s=%q", path, got))
+			if got == want {
+				matched = true
+			}
+		}
+		switch {
+		case matched:
+		case len(found) == 0:
+			mismatches = append(mismatches, e.Field+": 未找到对应的 cgroup 文件")
+		default:
+			mismatches = append(mismatches, fmt.Sprintf("%s: 期望 %v，读到 %s", e.Field, e.Want, strings.Join(found, " ")))
+		}
+	}
+	return mismatches
+}
+
+// CheckCgroup 比较 ReadFilesStep 读回的 cgroup 文件与 res 中已设置字段的期望值，返回不一致的描述
+func CheckCgroup(res container.Resources, files map[string]string) []string {
+	return checkCgroup(expectedCgroup(res), files)
+}
+
+// VerifyCgroup 在容器内读取 res 中已设置字段对应的 cgroup 文件（兼容 v1 与 v2），
+// 返回读到的文件内容以及与期望值不一致的描述
+func (s *Session) VerifyCgroup(ctx context.Context, step string, res container.Resources) (map[string]string, []string, error) {
+	expects := expectedCgroup(res)
+	var paths []string
+	for _, e := range expects {
+		for path := range e.Want {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	probe, err := s.Exec(ctx, ReadFilesStep(step, paths...))
+	if err != nil {
+		return nil, nil, err
+	}
+	files := ParseReadFiles(probe.Stdout)
+	return files, checkCgroup(expects, files), nil
+}
+
+// Update 通过 ContainerUpdate 调整运行中容器的限额，随后在容器内读回 cgroup 文件核对是否生效。
+// 调整会记录到报告的 updates 与时间线，资源采样中的限额同步更新
+func (s *Session) Update(ctx context.Context, u LimitUpdate) (UpdateResult, error) {
+	result := UpdateResult{Name: u.Name}
+	res := u.Resources
+	resp, err := s.cli.ContainerUpdate(ctx, s.ID, client.ContainerUpdateOptions{Resources: &res})
+	if err != nil {
+		return result, fmt.Errorf("调整容器 %s 限额 %s 失败: %w", s.Name, u.Name, err)
+	}
+	result.At = time.Now()
+	result.Warnings = resp.Warnings
+	report := ReportFrom(ctx)
+	if report != nil {
+		report.Mark(result.At, "update", "%s %s", u.Name, describeResources(res))
+	}
+
+	result.Cgroup, result.Mismatches, err = s.VerifyCgroup(ctx, "verify-"+u.Name, res)
+	if err != nil {
+		return result, err
+	}
+
+	if report != nil {
+		report.mu.Lock()
+		report.Updates = append(report.Updates, result)
+		report.mu.Unlock()
+		if inspect, err := s.cli.ContainerInspect(ctx, s.ID, client.ContainerInspectOptions{}); err == nil {
+			if usage := report.UsageOf(s.ID); usage != nil {
+				report.mu.Lock()
+				limitsFromHostConfig(usage, inspect.Container.HostConfig)
+				report.mu.Unlock()
+			}
+		}
+	}
+	return result, nil
+}
+
+// ApplyTimeline 按 After 偏移依次执行调整，start 为时间线的起点
+func (s *Session) ApplyTimeline(ctx context.Context, start time.Time, updates []LimitUpdate) ([]UpdateResult, error) {
+	results := make([]UpdateResult, 0, len(updates))
+	for _, u := range updates {
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		case <-time.After(time.Until(start.Add(u.After))):
+		}
+		result, err := s.Update(ctx, u)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// describeResources 列出 Resources 中被设置的限额
+func describeResources(res container.Resources) string {
+	var parts []string
+	if res.Memory > 0 {
+		parts = append(parts, fmt.Sprintf("memory=%dMiB", res.Memory/MiB))
+	}
+	switch {
+	case res.MemorySwap < 0:
+		parts = append(parts, "memorySwap=unlimited")
+	case res.MemorySwap > 0:
+		parts = append(parts, fmt.Sprintf("memorySwap=%dMiB", res.MemorySwap/MiB))
+	}
+	if res.NanoCPUs > 0 {
+		parts = append(parts, fmt.Sprintf("cpus=%.2f", float64(res.NanoCPUs)/1e9))
+	}
+	if res.PidsLimit != nil {
+		parts = append(parts, fmt.Sprintf("pids=%d", *res.PidsLimit))
+	}
+	return strings.Join(parts, " ")
+}
+
+// LogUpdateResult 打印一次调整的核对结果
+func LogUpdateResult(result UpdateResult) {
+	for _, w := range result.Warnings {
+		log.Printf("[%s] daemon 警告: %s", result.Name, w)
+	}
+	if len(result.Mismatches) == 0 {
+		log.Printf("[%s] cgroup 已生效 %v", result.Name, result.Cgroup)
+		return
+	}
+	for _, m := range result.Mismatches {
+		log.Printf("[%s] 未生效: %s", result.Name, m)
+	}
+}
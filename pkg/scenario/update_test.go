@@ -0,0 +1,87 @@
+package scenario
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+func TestCheckCgroupCPUQuota(t *testing.T) {
+	res := container.Resources{CPUQuota: 100000}
+	if got := CheckCgroup(res, ParseReadFiles("== /sys/fs/cgroup/cpu.max\n100000 100000\n")); len(got) != 0 {
+		t.Errorf("v2 mismatches = %q", got)
+	}
+	if got := CheckCgroup(res, ParseReadFiles("== /sys/fs/cgroup/cpu.max\nmax 100000\n")); len(got) != 1 {
+		t.Errorf("unlimited cpu.max mismatches = %q, want 1", got)
+	}
+}
+
+func TestCheckCgroupAfterUpdate(t *testing.T) {
+	pids := int64(32)
+	expects := expectedCgroup(container.Resources{Memory: 192 * MiB, NanoCPUs: 500_000_000, PidsLimit: &pids})
+	if len(expects) != 3 {
+		t.Fatalf("got %d expectations, want 3", len(expects))
+	}
+
+	tests := []struct {
+		name string
+		out  string
+		want []string
+	}{
+		{
+			name: "cgroup v2 applied",
+			out:  "== /sys/fs/cgroup/cpu.max\n50000 100000\n== /sys/fs/cgroup/memory.max\n201326592\n== /sys/fs/cgroup/pids.max\n32\n",
+		},
+		{
+			name: "cgroup v1 applied",
+			out:  "== /sys/fs/cgroup/cpu/cpu.cfs_quota_us\n50000\n== /sys/fs/cgroup/memory/memory.limit_in_bytes\n201326592\n== /sys/fs/cgroup/pids/pids.max\n32\n",
+		},
+		{
+			name: "memory not applied and pids missing",
+			out:  "== /sys/fs/cgroup/cpu.max\n50000 100000\n== /sys/fs/cgroup/memory.max\n67108864\n",
+			want: []string{"Memory: 期望", "PidsLimit: 未找到"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkCgroup(expects, ParseReadFiles(tt.out))
+			if len(got) != len(tt.want) {
+				t.Fatalf("mismatches = %q, want %d", got, len(tt.want))
+			}
+			for i, prefix := range tt.want {
+				if !strings.HasPrefix(got[i], prefix) {
+					t.Errorf("mismatch %d = %q, want prefix %q", i, got[i], prefix)
+				}
+			}
+		})
+	}
+}
+
+func TestExpectedCPUWeight(t *testing.T) {
+	expects := expectedCgroup(container.Resources{CPUShares: 512})
+	files := ParseReadFiles("== /sys/fs/cgroup/cpu.weight\n20\n")
+	if got := checkCgroup(expects, files); len(got) != 0 {
+		t.Errorf("mismatches = %q, want none", got)
+	}
+	for shares, want := range map[int64]int64{2: 1, 1024: 39, 262144: 10000} {
+		if got := sharesToWeight(shares); got != want {
+			t.Errorf("sharesToWeight(%d) = %d, want %d", shares, got, want)
+		}
+	}
+}
+
+func TestUsageMeanCPUWindow(t *testing.T) {
+	start := time.Now()
+	var u Usage
+	for i, nanos := range []uint64{0, 1e9, 2e9, 2.5e9, 3e9} {
+		u.add(Sample{At: start.Add(time.Duration(i) * time.Second), CPUUsageNanos: nanos})
+	}
+	if got := u.MeanCPU(start, start.Add(2500*time.Millisecond)); got != 1 {
+		t.Errorf("mean before = %v, want 1", got)
+	}
+	if got := u.MeanCPU(start.Add(3*time.Second), start.Add(5*time.Second)); got != 0.5 {
+		t.Errorf("mean after = %v, want 0.5", got)
+	}
+}
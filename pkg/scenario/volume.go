@@ -0,0 +1,73 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/client"
+)
+
+// RecreateTmpfsVolume 删除同名 Volume 后重新创建一个 tmpfs Volume，
+// 容量由 size 选项限制，用来模拟“受限数据盘”
+func RecreateTmpfsVolume(ctx context.Context, cli *client.Client, name string, sizeBytes int64) error {
+	if _, err := cli.VolumeRemove(ctx, name, client.VolumeRemoveOptions{Force: true}); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("删除旧 volume %s 失败: %w", name, err)
+	}
+	_, err := cli.VolumeCreate(ctx, client.VolumeCreateOptions{
+		Name:   name,
+		Driver: "local",
+		DriverOpts: map[string]string{
+			"type":   "tmpfs",
+			"device": "tmpfs",
+			"o":      fmt.Sprintf("size=%d", sizeBytes),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("创建 volume %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+// fillProgress 匹配写满脚本输出的 “已用=<X>MiB 剩余=<Y>MiB”
+var fillProgress = regexp.MustCompile(`已用=(\d+)MiB 剩余=(\d+)MiB`)
+
+// FillSeries 从写满脚本的带时间戳输出中提取已用空间序列，容量取已用与剩余之和的最大值
+func FillSeries(name string, result *RunResult) *Series {
+	series := &Series{Name: name, Unit: "MiB"}
+	for _, line := range result.Lines {
+		m := fillProgress.FindStringSubmatch(line.Text)
+		if m == nil || line.At.IsZero() {
+			continue
+		}
+		used, _ := strconv.ParseFloat(m[1], 64)
+		avail, _ := strconv.ParseFloat(m[2], 64)
+		series.Points = append(series.Points, Point{At: line.At, Value: used})
+		series.Limit = max(series.Limit, used+avail)
+	}
+	return series
+}
+
+// FillProgress 为写满脚本输出的 ProgressFunc，进度为已用占容量（已用与剩余之和）的比例
+func FillProgress(line string) (float64, bool) {
+	m := fillProgress.FindStringSubmatch(line)
+	if m == nil {
+		return 0, false
+	}
+	used, _ := strconv.ParseFloat(m[1], 64)
+	avail, _ := strconv.ParseFloat(m[2], 64)
+	if used+avail <= 0 {
+		return 0, false
+	}
+	return used / (used + avail), true
+}
+
+// RemoveVolume 强制删除 Volume，不存在时视为成功
+func RemoveVolume(ctx context.Context, cli *client.Client, name string) error {
+	if _, err := cli.VolumeRemove(ctx, name, client.VolumeRemoveOptions{Force: true}); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("删除 volume %s 失败: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/client"
+)
+
+// watchMount 为看门狗辅助容器内被监视卷的挂载点
+const watchMount = "/watched"
+
+// 看门狗的决策
+const (
+	WatchGrow  = "grow"
+	WatchAlert = "alert"
+	// WatchMax 表示使用率越过阈值但容量已达上限，无法继续扩容
+	WatchMax = "max"
+)
+
+// VolumePolicy 为卷使用率的处置策略：使用率达到 Threshold 时按 Step 扩容直到 Max，
+// AlertOnly 为 true 时只告警不扩容
+type VolumePolicy struct {
+	// Threshold 为触发处置的使用率（0-1）
+	Threshold float64
+	Step      int64
+	Max       int64
+	AlertOnly bool
+	Interval  time.Duration
+}
+
+// decide 根据已用与容量决定处置，返回决策与扩容后的容量；alerted 表示本次越过阈值后已告警过，
+// 告警与达到上限只在每次越过阈值时记录一次
+func (p VolumePolicy) decide(used, size int64, alerted bool) (string, int64) {
+	if size <= 0 || float64(used) < p.Threshold*float64(size) {
+		return "", size
+	}
+	switch {
+	case p.AlertOnly:
+		if alerted {
+			return "", size
+		}
+		return WatchAlert, size
+	case size < p.Max:
+		return WatchGrow, min(size+p.Step, p.Max)
+	case alerted:
+		return "", size
+	default:
+		return WatchMax, size
+	}
+}
+
+// WatchDecision 为看门狗的一次处置记录
+type WatchDecision struct {
+	At      time.Time
+	Action  string
+	Used    int64
+	Size    int64
+	NewSize int64
+}
+
+// WatchOutcome 为看门狗的运行结果，Samples 为使用量序列（MiB）
+type WatchOutcome struct {
+	Decisions []WatchDecision
+	Samples   []Point
+	// Size 为最后一次观测到的容量
+	Size int64
+	Err  error
+}
+
+// VolumeWatchdog 在挂载了被监视卷的特权辅助容器内定时执行 df，按 VolumePolicy 告警或通过
+// remount 调整 tmpfs 卷的 size。辅助容器同时保持卷处于挂载状态：local 驱动在没有容器使用时会卸载卷，
+// 重新挂载后容量会恢复为创建时的 size
+type VolumeWatchdog struct {
+	helper  *Session
+	cancel  context.CancelFunc
+	done    chan struct{}
+	mu      sync.Mutex
+	outcome WatchOutcome
+}
+
+// StartVolumeWatchdog 启动辅助容器并开始监视卷，应在写入容器启动之前调用。
+// 每次处置都会打印日志，并记录到 ctx 中报告的时间线（watchdog）
+func StartVolumeWatchdog(ctx context.Context, cli *client.Client, volume string, p VolumePolicy) (*VolumeWatchdog, error) {
+	if p.Interval <= 0 {
+		p.Interval = time.Second
+	}
+	helper, err := StartSession(ctx, cli, &container.Config{Image: ProbeImage}, &container.HostConfig{
+		// remount 需要 CAP_SYS_ADMIN
+		Privileged: true,
+		Mounts:     []mount.Mount{{Type: mount.TypeVolume, Source: volume, Target: watchMount}},
+	}, "volume-watchdog")
+	if err != nil {
+		return nil, fmt.Errorf("启动卷 %s 的看门狗失败: %w", volume, err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w := &VolumeWatchdog{helper: helper, cancel: cancel, done: make(chan struct{})}
+	report := ReportFrom(ctx)
+
+	go func() {
+		defer close(w.done)
+		alerted := false
+		for {
+			used, size, err := w.df(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					w.fail(err)
+				}
+				return
+			}
+			now := time.Now()
+			w.mu.Lock()
+			w.outcome.Samples = append(w.outcome.Samples, Point{At: now, Value: float64(used) / MiB})
+			w.outcome.Size = size
+			w.mu.Unlock()
+
+			action, newSize := p.decide(used, size, alerted)
+			switch {
+			case float64(used) < p.Threshold*float64(size):
+				alerted = false
+			case action == WatchAlert || action == WatchMax:
+				alerted = true
+			}
+			if action != "" {
+				d := WatchDecision{At: now, Action: action, Used: used, Size: size, NewSize: newSize}
+				if action == WatchGrow {
+					if err := w.grow(ctx, newSize); err != nil {
+						w.fail(err)
+						return
+					}
+				}
+				detail := fmt.Sprintf("卷 %s 已用 %dMiB/%dMiB（阈值 %.0f%%），%s", volume, used/MiB, size/MiB, p.Threshold*100, describeDecision(d))
+				log.Printf("[watchdog] %s", detail)
+				if report != nil {
+					report.Mark(now, "watchdog", "%s", detail)
+				}
+				w.mu.Lock()
+				w.outcome.Decisions = append(w.outcome.Decisions, d)
+				w.mu.Unlock()
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.Interval):
+			}
+		}
+	}()
+	return w, nil
+}
+
+func describeDecision(d WatchDecision) string {
+	switch d.Action {
+	case WatchGrow:
+		return fmt.Sprintf("扩容到 %dMiB", d.NewSize/MiB)
+	case WatchMax:
+		return "已达容量上限，不再扩容"
+	default:
+		return "告警"
+	}
+}
+
+// df 读取卷的已用与容量（字节）
+func (w *VolumeWatchdog) df(ctx context.Context) (used, size int64, err error) {
+	result, err := w.helper.Exec(ctx, ShellStep("watchdog-df", "df -k -P "+watchMount+" | tail -1"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("读取卷用量中断: %w", err)
+	}
+	used, size, err = parseDFLine(result.Stdout)
+	if err != nil {
+		return 0, 0, fmt.Errorf("读取卷用量失败: %w", err)
+	}
+	return used, size, nil
+}
+
+// grow 通过 remount 调整 tmpfs 的 size，写入容器挂载的是同一个 tmpfs，立即可见
+func (w *VolumeWatchdog) grow(ctx context.Context, size int64) error {
+	result, err := w.helper.Exec(ctx, ShellStep("watchdog-grow", fmt.Sprintf("mount -o remount,size=%d %s", size, watchMount)))
+	if err != nil {
+		return fmt.Errorf("扩容卷中断: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("扩容卷失败（退出码 %d）: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+// parseDFLine 解析 "df -k -P" 的数据行，返回已用与容量（字节）
+func parseDFLine(line string) (used, size int64, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return 0, 0, fmt.Errorf("无法解析 df 输出 %q", strings.TrimSpace(line))
+	}
+	size, err = strconv.ParseInt(fields[1], 10, 64)
+	if err == nil {
+		used, err = strconv.ParseInt(fields[2], 10, 64)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("无法解析 df 输出 %q: %w", strings.TrimSpace(line), err)
+	}
+	return used * 1024, size * 1024, nil
+}
+
+func (w *VolumeWatchdog) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.outcome.Err = err
+}
+
+// Stop 结束监视、删除辅助容器并返回运行结果
+func (w *VolumeWatchdog) Stop() WatchOutcome {
+	w.cancel()
+	<-w.done
+	w.helper.Close()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.outcome
+}
@@ -0,0 +1,41 @@
+package scenario
+
+import "testing"
+
+func TestVolumePolicyDecide(t *testing.T) {
+	grow := VolumePolicy{Threshold: 0.6, Step: 32 * MiB, Max: 128 * MiB}
+	alert := VolumePolicy{Threshold: 0.6, AlertOnly: true}
+	cases := []struct {
+		name     string
+		policy   VolumePolicy
+		used     int64
+		size     int64
+		alerted  bool
+		want     string
+		wantSize int64
+	}{
+		{"低于阈值", grow, 10 * MiB, 32 * MiB, false, "", 32 * MiB},
+		{"扩容", grow, 20 * MiB, 32 * MiB, false, WatchGrow, 64 * MiB},
+		{"扩容不超过上限", grow, 80 * MiB, 112 * MiB, false, WatchGrow, 128 * MiB},
+		{"达到上限", grow, 100 * MiB, 128 * MiB, false, WatchMax, 128 * MiB},
+		{"上限只记录一次", grow, 120 * MiB, 128 * MiB, true, "", 128 * MiB},
+		{"只告警", alert, 20 * MiB, 32 * MiB, false, WatchAlert, 32 * MiB},
+		{"告警只记录一次", alert, 30 * MiB, 32 * MiB, true, "", 32 * MiB},
+	}
+	for _, c := range cases {
+		got, size := c.policy.decide(c.used, c.size, c.alerted)
+		if got != c.want || size != c.wantSize {
+			t.Errorf("%s: decide = %q, %dMiB, want %q, %dMiB", c.name, got, size/MiB, c.want, c.wantSize/MiB)
+		}
+	}
+}
+
+func TestParseDFLine(t *testing.T) {
+	used, size, err := parseDFLine("tmpfs 32768 20480 12288 63% /watched\n")
+	if err != nil || used != 20*MiB || size != 32*MiB {
+		t.Fatalf("parseDFLine = %d, %d, %v", used, size, err)
+	}
+	if _, _, err := parseDFLine("Filesystem"); err == nil {
+		t.Error("期望解析失败")
+	}
+}
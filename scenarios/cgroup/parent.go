@@ -0,0 +1,311 @@
+// Package cgroup 为多个容器共用父 cgroup 的场景：通过 HostConfig.CgroupParent 把容器放进同一个
+// systemd slice 或 cgroupfs 路径，在父 cgroup 上设置聚合限额，验证聚合限额与容器自身限额同时生效
+package cgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+
+	"test-docker/pkg/scenario"
+)
+
+// ParentName 为父 cgroup 聚合限额场景的注册名
+const ParentName = "cgroup-parent"
+
+const (
+	members         = 3
+	memberMemory    = 96 * scenario.MiB
+	memberNanoCPUs  = 600_000_000
+	parentMemory    = 160 * scenario.MiB
+	parentNanoCPUs  = 1_000_000_000
+	shmSizeBytes    = 128 * scenario.MiB
+	burnFor         = 10 * time.Second
+	burnSettle      = 3 * time.Second
+	cpuTolerance    = 0.15
+	memorySlackMiB  = 4
+	defaultGroupTag = "team-a"
+)
+
+// burnScript 在容器内启动两个忙循环，需求超过容器自身的 CPU 限额
+const burnScript = `for i in 1 2; do timeout %d sh -c 'while :; do :; done' & done; wait`
+
+// hogScript 在子 shell 中以最高的 oom_score_adj 分配 %d MiB 匿名内存（dd 的块缓冲），
+// 触发 OOM 时被杀的是这个进程而不是容器的 1 号进程，其内存随之释放；输出子 shell 的退出码
+const hogScript = `(echo 1000 > /proc/self/oom_score_adj && exec dd if=/dev/zero of=/dev/null bs=%dM count=1 2>/dev/null); echo "hog_exit=$?"`
+
+// memberFill 为一个容器的内存阶段：先向 /dev/shm 写入 ShmMiB（shm 页无法回收，始终低于各级限额），
+// HogMiB 大于 0 时再由单独的进程分配 HogMiB 匿名内存去撞限额
+type memberFill struct {
+	ShmMiB int
+	HogMiB int
+}
+
+// memoryPlan 依次执行：第 1 个容器 32+80MiB 超过自身 96MiB 限额；第 3 个容器 32+48MiB 在自身限额之内，
+// 但父 cgroup 合计 32+64+32+48MiB 超过 160MiB 的聚合限额
+var memoryPlan = [members]memberFill{{ShmMiB: 32, HogMiB: 80}, {ShmMiB: 64}, {ShmMiB: 32, HogMiB: 48}}
+
+// FillResult 为一个容器内存阶段的结果，HogExit 为 -1 表示没有运行分配进程
+type FillResult struct {
+	ShmMiB    int
+	HogExit   int
+	InitAlive bool
+}
+
+func init() {
+	scenario.Register(&Parent{})
+}
+
+// Parent 以同一个 CgroupParent 启动 3 个各自限额 96MiB/0.6 vCPU 的容器，在父 cgroup 上设置
+// 160MiB/1 vCPU 的聚合限额：CPU 阶段三个容器同时满载，单个容器不超过自身配额、合计不超过父配额；
+// 内存阶段按 memoryPlan 依次写入 /dev/shm 并由单独的进程分配内存，第一个容器的分配进程被自身限额杀死，
+// 第三个的分配进程在自身限额之内却被父 cgroup 的聚合限额杀死，各容器的 1 号进程都应存活
+type Parent struct {
+	// Cgroup 为父 cgroup 名称，为空时按 daemon 的 cgroup driver 选择 slice 或 cgroupfs 路径
+	Cgroup string
+
+	parent     scenario.ParentCgroup
+	sessions   []*scenario.Session
+	mismatches []string
+	from, to   time.Time
+	fills      []FillResult
+	peak       int64
+}
+
+func (*Parent) Name() string { return ParentName }
+
+func (p *Parent) Params() map[string]string {
+	return map[string]string{
+		"cgroupParent": p.Cgroup,
+		"members":      strconv.Itoa(members),
+		"memberLimit":  fmt.Sprintf("%dMiB/%.1f", memberMemory/scenario.MiB, float64(memberNanoCPUs)/1e9),
+		"parentLimit":  fmt.Sprintf("%dMiB/%.1f", parentMemory/scenario.MiB, float64(parentNanoCPUs)/1e9),
+	}
+}
+
+func (*Parent) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: members * memberMemory, Tmpfs: members * shmSizeBytes, Containers: members}
+}
+
+func (p *Parent) Prepare(ctx context.Context, env *scenario.Env) error {
+	if err := scenario.PullImage(ctx, env.Client, scenario.ProbeImage); err != nil {
+		return err
+	}
+	info, err := env.Client.Info(ctx, client.InfoOptions{})
+	if err != nil {
+		return fmt.Errorf("查询 daemon Info 失败: %w", err)
+	}
+	driver := info.Info.CgroupDriver
+	name := p.Cgroup
+	if name == "" {
+		name = scenario.DefaultParentName(driver, defaultGroupTag)
+	}
+	p.parent = scenario.ParentCgroup{
+		Name:     name,
+		Memory:   parentMemory,
+		NanoCPUs: parentNanoCPUs,
+		V1:       info.Info.CgroupVersion == "1",
+	}
+	if (driver == "systemd") != p.parent.IsSlice() {
+		return fmt.Errorf("cgroup driver 为 %s，父 cgroup %q 不匹配：systemd 需要 .slice，cgroupfs 需要路径", driver, name)
+	}
+	env.Report.Mark(time.Now(), "phase", "父 cgroup %s（driver %s，宿主机路径 %s）", name, driver, p.parent.RelPath())
+	return nil
+}
+
+func (p *Parent) Run(ctx context.Context, env *scenario.Env) error {
+	p.sessions = nil
+	for i := range members {
+		sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+			Image: scenario.ProbeImage,
+		}, &container.HostConfig{
+			ShmSize: shmSizeBytes,
+			Resources: container.Resources{
+				CgroupParent: p.parent.Name,
+				Memory:       memberMemory,
+				MemorySwap:   memberMemory,
+				NanoCPUs:     memberNanoCPUs,
+			},
+		}, fmt.Sprintf("cgroup-parent-%d", i))
+		if err != nil {
+			return fmt.Errorf("启动第 %d 个容器失败: %w", i+1, err)
+		}
+		p.sessions = append(p.sessions, sess)
+	}
+
+	// systemd 在第一个容器启动后才创建 slice，因此聚合限额在容器启动后写入
+	files, err := scenario.ApplyParentLimits(ctx, env.Client, scenario.ProbeImage, p.parent)
+	if err != nil {
+		return err
+	}
+	p.mismatches = scenario.CheckParentLimits(p.parent, files)
+	env.Report.Mark(time.Now(), "update", "父 cgroup 聚合限额 %dMiB/%.1f vCPU", parentMemory/scenario.MiB, float64(parentNanoCPUs)/1e9)
+
+	if err := p.burn(ctx, env); err != nil {
+		return err
+	}
+	return p.fill(ctx, env)
+}
+
+// burn 让所有容器同时满载，记录稳定后的统计窗口
+func (p *Parent) burn(ctx context.Context, env *scenario.Env) error {
+	start := time.Now()
+	env.Report.Mark(start, "phase", "%d 个容器同时满载 %s", len(p.sessions), burnFor)
+	var wg sync.WaitGroup
+	errs := make([]error, len(p.sessions))
+	for i, sess := range p.sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			step := scenario.ShellStep("burn", fmt.Sprintf(burnScript, int(burnFor/time.Second)))
+			step.Timeout = burnFor + time.Minute
+			_, errs[i] = sess.Exec(ctx, step)
+		}()
+	}
+	wg.Wait()
+	p.from, p.to = start.Add(burnSettle), start.Add(burnFor)
+	return errors.Join(errs...)
+}
+
+// fill 按 memoryPlan 依次向各容器的 /dev/shm 写入并运行分配进程，shm 页计入容器及父 cgroup 的内存
+func (p *Parent) fill(ctx context.Context, env *scenario.Env) error {
+	p.fills = nil
+	for i, sess := range p.sessions {
+		plan := memoryPlan[i]
+		steps := []scenario.ProbeStep{
+			scenario.ShellStep("fill-shm", fmt.Sprintf("dd if=/dev/zero of=/dev/shm/fill bs=1M count=%d", plan.ShmMiB)),
+			scenario.ShellStep("shm-usage", "du -sm /dev/shm | cut -f1"),
+		}
+		if plan.HogMiB > 0 {
+			steps = append(steps, scenario.ShellStep("hog", fmt.Sprintf(hogScript, plan.HogMiB)))
+		}
+		results, err := sess.RunProbes(ctx, steps)
+		for _, r := range results {
+			scenario.LogProbeResult(r)
+		}
+		if err != nil {
+			return fmt.Errorf("容器 %s 的内存阶段中断: %w", sess.Name, err)
+		}
+		fill := FillResult{HogExit: -1}
+		fill.ShmMiB, _ = strconv.Atoi(strings.TrimSpace(results[1].Stdout))
+		if plan.HogMiB > 0 {
+			fill.HogExit = parseHogExit(results[2].Stdout)
+		}
+		inspect, err := env.Client.ContainerInspect(ctx, sess.ID, client.ContainerInspectOptions{})
+		if err != nil {
+			return fmt.Errorf("查询容器 %s 状态失败: %w", sess.Name, err)
+		}
+		fill.InitAlive = inspect.Container.State != nil && inspect.Container.State.Running
+		p.fills = append(p.fills, fill)
+		env.Report.Mark(time.Now(), "phase", "容器 %s：/dev/shm %dMiB，分配 %dMiB 的进程退出码 %d，1 号进程存活 %t",
+			sess.Name, fill.ShmMiB, plan.HogMiB, fill.HogExit, fill.InitAlive)
+	}
+
+	memory, peak, _ := p.parent.UsageFiles()
+	files, err := scenario.ReadParentFiles(ctx, env.Client, scenario.ProbeImage, memory, peak)
+	if err != nil {
+		return err
+	}
+	for _, f := range []string{peak, memory} {
+		if v, err := strconv.ParseInt(files[f], 10, 64); err == nil {
+			p.peak = max(p.peak, v)
+		}
+	}
+	return nil
+}
+
+func (p *Parent) Verify(_ context.Context, env *scenario.Env) (string, error) {
+	problems := append([]string(nil), p.mismatches...)
+
+	var total float64
+	for _, sess := range p.sessions {
+		cpu := env.Report.MeanCPU(sess.ID, p.from, p.to)
+		total += cpu
+		log.Printf("容器 %s 平均 %.2f vCPU（自身限额 %.1f）", sess.Name, cpu, float64(memberNanoCPUs)/1e9)
+		if cpu > float64(memberNanoCPUs)/1e9*(1+cpuTolerance) {
+			problems = append(problems, fmt.Sprintf("容器 %s 平均 %.2f vCPU，超过自身限额", sess.Name, cpu))
+		}
+	}
+	log.Printf("合计 %.2f vCPU（父 cgroup 限额 %.1f）", total, float64(parentNanoCPUs)/1e9)
+	if total == 0 {
+		problems = append(problems, "统计窗口内没有 CPU 采样")
+	} else if total > float64(parentNanoCPUs)/1e9*(1+cpuTolerance) {
+		problems = append(problems, fmt.Sprintf("合计 %.2f vCPU，超过父 cgroup 限额", total))
+	}
+
+	problems = append(problems, checkFills(p.fills)...)
+	shm := make([]int, len(p.fills))
+	for i, f := range p.fills {
+		shm[i] = f.ShmMiB
+	}
+	log.Printf("父 cgroup 内存峰值 %dMiB（限额 %dMiB），各容器 /dev/shm：%v MiB", p.peak/scenario.MiB, parentMemory/scenario.MiB, shm)
+	if p.peak > parentMemory+memorySlackMiB*scenario.MiB {
+		problems = append(problems, fmt.Sprintf("父 cgroup 内存峰值 %dMiB 超过聚合限额", p.peak/scenario.MiB))
+	}
+
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("父 cgroup %s 合计 %.2f vCPU、内存峰值 %dMiB，均未超过聚合限额；各容器 /dev/shm %v MiB，"+
+		"第 1 个容器的分配进程被自身限额杀死，第 %d 个被聚合限额杀死，1 号进程均存活",
+		p.parent.Name, total, p.peak/scenario.MiB, shm, members), nil
+}
+
+// parseHogExit 解析 hogScript 输出的 “hog_exit=<N>”，没有时返回 -1
+func parseHogExit(out string) int {
+	for _, line := range strings.Split(out, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "hog_exit="); ok {
+			if n, err := strconv.Atoi(v); err == nil {
+				return n
+			}
+		}
+	}
+	return -1
+}
+
+// checkFills 核对内存阶段：shm 按计划写入，有分配进程的容器中它被 SIGKILL（137），1 号进程全部存活
+func checkFills(fills []FillResult) []string {
+	if len(fills) != members {
+		return []string{fmt.Sprintf("只完成了 %d 个容器的内存阶段", len(fills))}
+	}
+	var problems []string
+	for i, f := range fills {
+		plan := memoryPlan[i]
+		if f.ShmMiB < plan.ShmMiB {
+			problems = append(problems, fmt.Sprintf("第 %d 个容器 /dev/shm 只有 %dMiB，计划 %dMiB", i+1, f.ShmMiB, plan.ShmMiB))
+		}
+		if !f.InitAlive {
+			problems = append(problems, fmt.Sprintf("第 %d 个容器的 1 号进程没有存活", i+1))
+		}
+		if plan.HogMiB == 0 {
+			continue
+		}
+		if f.HogExit != 137 {
+			limit := "自身"
+			if i > 0 {
+				limit = "父 cgroup 聚合"
+			}
+			problems = append(problems, fmt.Sprintf("第 %d 个容器分配 %dMiB 的进程退出码为 %d，未被%s限额杀死", i+1, plan.HogMiB, f.HogExit, limit))
+		}
+	}
+	return problems
+}
+
+func (p *Parent) Cleanup(ctx context.Context, env *scenario.Env) error {
+	for _, sess := range p.sessions {
+		sess.Close()
+	}
+	p.sessions = nil
+	if p.parent.Name == "" {
+		return nil
+	}
+	return scenario.RemoveParentCgroup(ctx, env.Client, scenario.ProbeImage, p.parent)
+}
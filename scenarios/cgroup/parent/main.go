@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/cgroup"
+)
+
+func main() {
+	scenario.Main(cgroup.ParentName)
+}
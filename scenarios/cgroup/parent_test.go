@@ -0,0 +1,89 @@
+package cgroup
+
+import (
+	"strings"
+	"testing"
+
+	"test-docker/pkg/scenario"
+)
+
+func TestMemoryPlan(t *testing.T) {
+	own := int(memberMemory / scenario.MiB)
+	parent := int(parentMemory / scenario.MiB)
+	var shm int
+	for i, f := range memoryPlan {
+		if f.ShmMiB >= own {
+			t.Errorf("第 %d 个容器的 shm %dMiB 不低于自身限额", i+1, f.ShmMiB)
+		}
+		shm += f.ShmMiB
+		switch i {
+		case 0:
+			if f.ShmMiB+f.HogMiB <= own {
+				t.Errorf("第 1 个容器 %d+%dMiB 没有超过自身限额 %dMiB", f.ShmMiB, f.HogMiB, own)
+			}
+			if own+memorySlackMiB >= parent {
+				t.Errorf("第 1 个容器撞到自身限额时父 cgroup 也会撞限")
+			}
+		case members - 1:
+			if f.ShmMiB+f.HogMiB >= own {
+				t.Errorf("最后一个容器 %d+%dMiB 没有留在自身限额 %dMiB 之内", f.ShmMiB, f.HogMiB, own)
+			}
+			if shm+f.HogMiB <= parent {
+				t.Errorf("合计 %d+%dMiB 没有超过聚合限额 %dMiB", shm, f.HogMiB, parent)
+			}
+		default:
+			if f.HogMiB != 0 {
+				t.Errorf("第 %d 个容器不应运行分配进程", i+1)
+			}
+		}
+	}
+	if shm >= parent {
+		t.Errorf("shm 合计 %dMiB 不低于聚合限额，分配进程被杀后仍无法回收", shm)
+	}
+}
+
+func TestParseHogExit(t *testing.T) {
+	tests := []struct {
+		out  string
+		want int
+	}{
+		{"hog_exit=137\n", 137},
+		{"Killed\nhog_exit=0\n", 0},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		if got := parseHogExit(tt.out); got != tt.want {
+			t.Errorf("parseHogExit(%q) = %d, want %d", tt.out, got, tt.want)
+		}
+	}
+}
+
+func TestCheckFills(t *testing.T) {
+	ok := []FillResult{
+		{ShmMiB: 32, HogExit: 137, InitAlive: true},
+		{ShmMiB: 64, HogExit: -1, InitAlive: true},
+		{ShmMiB: 32, HogExit: 137, InitAlive: true},
+	}
+	tests := []struct {
+		name  string
+		fills func([]FillResult)
+		want  string
+	}{
+		{"通过", func([]FillResult) {}, ""},
+		{"聚合限额未生效", func(f []FillResult) { f[2].HogExit = 0 }, "未被父 cgroup 聚合限额杀死"},
+		{"自身限额未生效", func(f []FillResult) { f[0].HogExit = 0 }, "未被自身限额杀死"},
+		{"1 号进程被杀", func(f []FillResult) { f[1].InitAlive = false }, "1 号进程没有存活"},
+		{"shm 不足", func(f []FillResult) { f[1].ShmMiB = 10 }, "只有 10MiB"},
+	}
+	for _, tt := range tests {
+		fills := append([]FillResult(nil), ok...)
+		tt.fills(fills)
+		got := strings.Join(checkFills(fills), "；")
+		if tt.want == "" && got != "" || tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s: checkFills = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := checkFills(ok[:1]); len(got) != 1 {
+		t.Errorf("checkFills(partial) = %q", got)
+	}
+}
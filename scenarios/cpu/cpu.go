@@ -0,0 +1,116 @@
+// Package cpu 为 CPU 相关场景：在 1 vCPU 配额的常驻容器内用 stress 压测并读取 cpu.stat；2 vCPU 的 CPUQuota 核对与超额负载；同一 CPU 上按权重争用；cpuset/NUMA 绑定核对；CFS 限流对迭代尾延迟的影响
+package cpu
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// Name 为 CPU 探测场景的注册名
+const Name = "cpu-probe"
+
+const (
+	demoImage = "stress"
+	loadSecs  = "6"
+	// probeMaxVCPU 为压测期间平均 vCPU 的上限，在 1 vCPU 配额之上留出采样误差
+	probeMaxVCPU = 1.1
+)
+
+// probeResources 为探测容器的 CPU 限额：CFS 周期默认 100ms，配额 100ms 即 1 vCPU
+var probeResources = container.Resources{
+	CPUPercent: 100000,
+	CPUQuota:   100000,
+}
+
+func init() {
+	scenario.Register(&Probe{})
+}
+
+// Probe 读取 CFS 配额，压测 loadSecs 秒后再次读取 cpu.stat 中的用量与限流计数
+type Probe struct {
+	sess    *scenario.Session
+	results []scenario.ProbeResult
+}
+
+func (*Probe) Name() string { return Name }
+
+func (*Probe) Params() map[string]string {
+	return map[string]string{"cpuQuota": "100000", "load": loadSecs + "s"}
+}
+
+// LocalImages 声明 stress 镜像需在本地构建
+func (*Probe) LocalImages() []string { return []string{demoImage} }
+
+// Prepare 不拉取镜像，stress 为本地构建的镜像
+func (*Probe) Prepare(context.Context, *scenario.Env) error { return nil }
+
+func (p *Probe) Run(ctx context.Context, env *scenario.Env) error {
+	// 容器常驻，之后的读取与压测都通过 exec 完成
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: demoImage,
+		Tty:   false,
+		Cmd:   []string{"sleep", "3600"},
+	}, &container.HostConfig{
+		Resources: probeResources,
+	}, "cpu-test")
+	if err != nil {
+		return fmt.Errorf("执行 CPU 限额探测失败: %w", err)
+	}
+	p.sess = sess
+
+	results, err := sess.RunProbes(ctx, []scenario.ProbeStep{
+		scenario.ReadFilesStep("cgroup-limit",
+			"/sys/fs/cgroup/cpu.max",
+			"/sys/fs/cgroup/cpu/cpu.cfs_quota_us",
+			"/sys/fs/cgroup/cpu/cpu.cfs_period_us"),
+		scenario.ReadFilesStep("cpu-stat-before", "/sys/fs/cgroup/cpu.stat", "/sys/fs/cgroup/cpu/cpu.stat"),
+		{Name: "stress", Cmd: []string{"stress", "--cpu", "2", "--timeout", loadSecs}, Timeout: time.Minute},
+		scenario.ReadFilesStep("cpu-stat-after", "/sys/fs/cgroup/cpu.stat", "/sys/fs/cgroup/cpu/cpu.stat"),
+	})
+	for _, result := range results {
+		scenario.LogProbeResult(result)
+	}
+	p.results = results
+	if err != nil {
+		return fmt.Errorf("探测中断: %w", err)
+	}
+	return nil
+}
+
+// Verify 核对容器内的 CFS 配额与 stress 的退出码；cgroup v2 的 cpu.stat 带有 usage_usec，
+// 据此确认压测期间的平均 vCPU 没有超过配额
+func (p *Probe) Verify(context.Context, *scenario.Env) (string, error) {
+	limit, before, stress, after := p.results[0], p.results[1], p.results[2], p.results[3]
+	if mismatches := scenario.CheckCgroup(probeResources, scenario.ParseReadFiles(limit.Stdout)); len(mismatches) > 0 {
+		return "", fmt.Errorf("CFS 配额未生效: %s", strings.Join(mismatches, "；"))
+	}
+	if stress.ExitCode != 0 {
+		return "", fmt.Errorf("stress 退出码 %d: %s", stress.ExitCode, strings.TrimSpace(stress.Stderr))
+	}
+	statBefore, statAfter := parseCPUStat(before.Stdout), parseCPUStat(after.Stdout)
+	throttle := throttleDelta(statBefore, statAfter)
+	summary := fmt.Sprintf("1 vCPU 配额已生效，压测 %ss 期间 %d/%d 个周期被限流（%s）",
+		loadSecs, throttle.Throttled, throttle.Periods, throttle.Time.Round(time.Millisecond))
+	if usage, ok := statAfter["usage_usec"]; ok {
+		elapsed := after.FinishedAt.Sub(before.StartedAt)
+		vcpu := float64(usage-statBefore["usage_usec"]) / float64(elapsed.Microseconds())
+		if vcpu > probeMaxVCPU {
+			return "", fmt.Errorf("压测期间平均 %.2f vCPU，超过 1 vCPU 配额", vcpu)
+		}
+		summary += fmt.Sprintf("，平均 %.2f vCPU", vcpu)
+	}
+	return summary, nil
+}
+
+func (p *Probe) Cleanup(context.Context, *scenario.Env) error {
+	if p.sess != nil {
+		p.sess.Close()
+	}
+	return nil
+}
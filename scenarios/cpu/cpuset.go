@@ -0,0 +1,278 @@
+package cpu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+const (
+	// CpusetName 为 cpuset/NUMA 绑定场景的注册名
+	CpusetName = "cpuset-pinning"
+	// CpusetRejectName 为不合法 cpuset 被拒绝场景的注册名
+	CpusetRejectName = "cpuset-invalid"
+)
+
+// pinScript 启动 %[1]d 个忙循环线程，在 %[2]d 秒内反复读取每个线程最近运行的 CPU
+// （/proc/<pid>/stat 第 39 列），每次输出一行 "cpu <编号>"
+const pinScript = `pids=""
+for i in $(seq %[1]d); do (while :; do :; done) & pids="$pids $!"; done
+end=$(($(date +%%s)+%[2]d))
+while [ $(date +%%s) -lt $end ]; do
+    for p in $pids; do awk '{print "cpu", $39}' /proc/$p/stat; done
+    sleep 0.2
+done
+kill $pids`
+
+func init() {
+	scenario.Register(&Cpuset{Burn: 6 * time.Second})
+	scenario.Register(&CpusetReject{})
+}
+
+// Cpuset 以 CpusetCpus/CpusetMems 启动容器，核对容器内 /proc/self/status 的
+// Cpus_allowed_list/Mems_allowed_list 与 cgroup 的 effective 集合，再启动多于集合大小的
+// 忙循环线程，确认所有线程只在集合内的 CPU 上运行。集合在创建容器前按宿主机拓扑校验
+type Cpuset struct {
+	// Cpus 为绑定的 CPU 列表，为空时自动选择宿主机编号最大的（至多）两个在线 CPU
+	Cpus string
+	// Mems 为绑定的内存节点列表，为空时选择编号最小的在线节点
+	Mems string
+	// Burn 为忙循环运行并采样的时长
+	Burn time.Duration
+
+	topo     scenario.Topology
+	cpus     []int
+	mems     []int
+	sess     *scenario.Session
+	status   scenario.ProbeResult
+	cgroup   map[string]string
+	observed map[int]int
+}
+
+func (*Cpuset) Name() string { return CpusetName }
+
+func (c *Cpuset) Params() map[string]string {
+	return map[string]string{"cpusetCpus": c.Cpus, "cpusetMems": c.Mems, "burn": c.Burn.String()}
+}
+
+// Prepare 读取宿主机拓扑并校验集合，不合法的集合在此阶段即被拒绝，不会创建受限容器
+func (c *Cpuset) Prepare(ctx context.Context, env *scenario.Env) error {
+	if err := scenario.PullImage(ctx, env.Client, scenario.ProbeImage); err != nil {
+		return err
+	}
+	topo, err := scenario.HostTopology(ctx, env.Client, scenario.ProbeImage)
+	if err != nil {
+		return fmt.Errorf("读取宿主机拓扑失败: %w", err)
+	}
+	c.topo = topo
+	log.Printf("宿主机在线 CPU %s，内存节点 %s", scenario.FormatCPUList(topo.CPUs), scenario.FormatCPUList(topo.Mems))
+
+	cpus, mems := c.Cpus, c.Mems
+	if cpus == "" {
+		cpus = scenario.FormatCPUList(topo.CPUs[max(0, len(topo.CPUs)-2):])
+	}
+	if mems == "" && len(topo.Mems) > 0 {
+		mems = strconv.Itoa(topo.Mems[0])
+	}
+	if err := topo.Validate(cpus, mems); err != nil {
+		return fmt.Errorf("拒绝创建容器: %w", err)
+	}
+	c.cpus, _ = scenario.ParseCPUList(cpus)
+	c.mems, _ = scenario.ParseCPUList(mems)
+	env.Report.Mark(time.Now(), "phase", "绑定 CpusetCpus=%s CpusetMems=%s", cpus, mems)
+	return nil
+}
+
+func (c *Cpuset) Run(ctx context.Context, env *scenario.Env) error {
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: scenario.ProbeImage,
+	}, &container.HostConfig{
+		Resources: container.Resources{
+			CpusetCpus: scenario.FormatCPUList(c.cpus),
+			CpusetMems: scenario.FormatCPUList(c.mems),
+		},
+	}, "cpuset-pinning")
+	if err != nil {
+		return fmt.Errorf("启动绑定容器失败: %w", err)
+	}
+	c.sess = sess
+
+	// 线程数取宿主机 CPU 数，多于集合大小，调度器若允许就会把线程放到集合外
+	threads := max(len(c.topo.CPUs), len(c.cpus)+1)
+	results, err := sess.RunProbes(ctx, []scenario.ProbeStep{
+		scenario.ShellStep("proc-status", "grep -E '^(Cpus|Mems)_allowed_list' /proc/self/status"),
+		scenario.ReadFilesStep("cpuset-effective",
+			"/sys/fs/cgroup/cpuset.cpus.effective",
+			"/sys/fs/cgroup/cpuset.mems.effective",
+			"/sys/fs/cgroup/cpuset/cpuset.effective_cpus",
+			"/sys/fs/cgroup/cpuset/cpuset.effective_mems"),
+		{
+			Name:    "burn",
+			Cmd:     []string{"sh", "-c", fmt.Sprintf(pinScript, threads, int(c.Burn/time.Second))},
+			Timeout: c.Burn + time.Minute,
+		},
+	})
+	for _, result := range results {
+		scenario.LogProbeResult(result)
+	}
+	if err != nil {
+		return fmt.Errorf("探测中断: %w", err)
+	}
+	c.status = results[0]
+	c.cgroup = scenario.ParseReadFiles(results[1].Stdout)
+	c.observed = parseCPUSamples(results[2].Stdout)
+	return nil
+}
+
+func (c *Cpuset) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems []string
+	check := func(what, got string, want []int) {
+		ids, err := scenario.ParseCPUList(got)
+		switch {
+		case got == "":
+			problems = append(problems, what+" 未读取到")
+		case err != nil || !slices.Equal(ids, want):
+			problems = append(problems, fmt.Sprintf("%s 为 %q，期望 %s", what, got, scenario.FormatCPUList(want)))
+		}
+	}
+	status := parseStatus(c.status.Stdout)
+	check("Cpus_allowed_list", status["Cpus_allowed_list"], c.cpus)
+	check("Mems_allowed_list", status["Mems_allowed_list"], c.mems)
+	check("effective cpus", firstNonEmpty(c.cgroup["/sys/fs/cgroup/cpuset.cpus.effective"], c.cgroup["/sys/fs/cgroup/cpuset/cpuset.effective_cpus"]), c.cpus)
+	check("effective mems", firstNonEmpty(c.cgroup["/sys/fs/cgroup/cpuset.mems.effective"], c.cgroup["/sys/fs/cgroup/cpuset/cpuset.effective_mems"]), c.mems)
+
+	var total int
+	var outside []string
+	for cpu, n := range c.observed {
+		total += n
+		if !slices.Contains(c.cpus, cpu) {
+			outside = append(outside, fmt.Sprintf("cpu%d×%d", cpu, n))
+		}
+	}
+	used := slices.Sorted(maps.Keys(c.observed))
+	log.Printf("忙循环线程共采样 %d 次，运行过的 CPU：%s", total, scenario.FormatCPUList(used))
+	if total == 0 {
+		problems = append(problems, "没有采集到忙循环线程所在的 CPU")
+	}
+	if len(outside) > 0 {
+		slices.Sort(outside)
+		problems = append(problems, "线程运行在集合之外："+strings.Join(outside, " "))
+	}
+
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("绑定 CPU %s / 节点 %s 生效，%d 次采样全部落在集合内（实际使用 %s）",
+		scenario.FormatCPUList(c.cpus), scenario.FormatCPUList(c.mems), total, scenario.FormatCPUList(used)), nil
+}
+
+func (c *Cpuset) Cleanup(context.Context, *scenario.Env) error {
+	if c.sess != nil {
+		c.sess.Close()
+		c.sess = nil
+	}
+	return nil
+}
+
+// CpusetReject 以宿主机不存在的 CPU 作为 CpusetCpus，确认按拓扑校验会拒绝该集合，
+// 并且绕过校验直接创建容器时 daemon 同样拒绝启动，校验与 daemon 的判断一致
+type CpusetReject struct {
+	// Cpus 为不合法的 CPU 列表，为空时取宿主机最大在线编号加一
+	Cpus string
+
+	cpus        string
+	validateErr error
+	daemonErr   error
+	result      *scenario.RunResult
+}
+
+func (*CpusetReject) Name() string { return CpusetRejectName }
+
+func (r *CpusetReject) Params() map[string]string {
+	return map[string]string{"cpusetCpus": r.Cpus}
+}
+
+func (r *CpusetReject) Prepare(ctx context.Context, env *scenario.Env) error {
+	return scenario.PullImage(ctx, env.Client, scenario.ProbeImage)
+}
+
+func (r *CpusetReject) Run(ctx context.Context, env *scenario.Env) error {
+	topo, err := scenario.HostTopology(ctx, env.Client, scenario.ProbeImage)
+	if err != nil {
+		return fmt.Errorf("读取宿主机拓扑失败: %w", err)
+	}
+	r.cpus = r.Cpus
+	if r.cpus == "" {
+		r.cpus = strconv.Itoa(slices.Max(topo.CPUs) + 1)
+	}
+	r.validateErr = topo.Validate(r.cpus, "")
+	log.Printf("CpusetCpus=%s 的拓扑校验结果: %v", r.cpus, r.validateErr)
+
+	r.result, r.daemonErr = scenario.RunControlledContainer(ctx, env.Client, &container.Config{
+		Image: scenario.ProbeImage,
+		Cmd:   []string{"true"},
+	}, &container.HostConfig{
+		Resources: container.Resources{CpusetCpus: r.cpus},
+	}, "cpuset-invalid")
+	log.Printf("daemon 对 CpusetCpus=%s 的处理结果: %v", r.cpus, r.daemonErr)
+	return nil
+}
+
+func (r *CpusetReject) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems []string
+	if r.validateErr == nil {
+		problems = append(problems, fmt.Sprintf("拓扑校验接受了 CpusetCpus=%s", r.cpus))
+	}
+	if r.daemonErr == nil {
+		problems = append(problems, fmt.Sprintf("daemon 以 CpusetCpus=%s 运行了容器（退出码 %d）", r.cpus, r.result.StatusCode))
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("CpusetCpus=%s 被拓扑校验与 daemon 同时拒绝", r.cpus), nil
+}
+
+func (*CpusetReject) Cleanup(context.Context, *scenario.Env) error { return nil }
+
+// parseStatus 解析 /proc/self/status 中 "键:\t值" 形式的行
+func parseStatus(out string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			fields[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return fields
+}
+
+// parseCPUSamples 统计 pinScript 输出中每个 CPU 出现的次数
+func parseCPUSamples(out string) map[int]int {
+	counts := map[int]int{}
+	for _, line := range strings.Split(out, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "cpu "); ok {
+			if cpu, err := strconv.Atoi(v); err == nil {
+				counts[cpu]++
+			}
+		}
+	}
+	return counts
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/cpu"
+)
+
+func main() {
+	scenario.Main(cpu.CpusetName, cpu.CpusetRejectName)
+}
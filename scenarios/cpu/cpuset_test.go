@@ -0,0 +1,18 @@
+package cpu
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseCPUSamples(t *testing.T) {
+	out := "cpu 2\ncpu 3\ncpu 2\nawk: /proc/9/stat: No such file\ncpu x\n"
+	want := map[int]int{2: 2, 3: 1}
+	if got := parseCPUSamples(out); !maps.Equal(got, want) {
+		t.Errorf("parseCPUSamples = %v, want %v", got, want)
+	}
+	status := parseStatus("Cpus_allowed_list:\t2-3\nMems_allowed_list:\t0\n")
+	if status["Cpus_allowed_list"] != "2-3" || status["Mems_allowed_list"] != "0" {
+		t.Errorf("parseStatus = %v", status)
+	}
+}
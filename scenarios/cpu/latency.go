@@ -0,0 +1,355 @@
+package cpu
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// LatencyName 为 CFS 限流延迟场景的注册名
+const LatencyName = "cpu-throttle-latency"
+
+const latencyImage = "docker.io/library/python:3.12-alpine"
+
+// latencyScript 模拟延迟敏感的服务：每次迭代执行固定量的计算并记录耗时（微秒），
+// 迭代之间短暂休眠；CPU 需求高于配额，迭代会跨越被限流的周期
+const latencyScript = `import sys, time
+n, loops, pause = int(sys.argv[1]), int(sys.argv[2]), float(sys.argv[3])
+out = []
+for _ in range(n):
+    t = time.perf_counter_ns()
+    x = 0
+    for i in range(loops):
+        x += i
+    out.append((time.perf_counter_ns() - t) // 1000)
+    time.sleep(pause)
+print("latency_us", *out)`
+
+// CFSSetting 为一组 CFS 周期与配额（微秒）
+type CFSSetting struct {
+	Period int64
+	Quota  int64
+}
+
+func (s CFSSetting) String() string {
+	return fmt.Sprintf("%s/%s", time.Duration(s.Quota)*time.Microsecond, time.Duration(s.Period)*time.Microsecond)
+}
+
+// LatencyStats 为迭代延迟的分位数统计
+type LatencyStats struct {
+	Count              int
+	P50, P95, P99, Max time.Duration
+}
+
+// ThrottleStats 为负载期间 cpu.stat 计数的增量
+type ThrottleStats struct {
+	Periods   int64
+	Throttled int64
+	Time      time.Duration
+}
+
+// Stall 返回每次被限流的平均停顿时间，没有限流时为 0
+func (t ThrottleStats) Stall() time.Duration {
+	if t.Throttled == 0 {
+		return 0
+	}
+	return t.Time / time.Duration(t.Throttled)
+}
+
+// LatencyResult 为一组 CFS 设置下的测量结果
+type LatencyResult struct {
+	Setting  CFSSetting
+	Latency  LatencyStats
+	Throttle ThrottleStats
+}
+
+func init() {
+	scenario.Register(&Latency{
+		Settings: []CFSSetting{
+			{Period: 10_000, Quota: 5_000},
+			{Period: 100_000, Quota: 50_000},
+			{Period: 500_000, Quota: 250_000},
+		},
+		Iterations: 1500,
+		Loops:      50_000,
+		Pause:      time.Millisecond,
+	})
+}
+
+// Latency 在 vCPU 相同、CPUPeriod 不同的多组 CFS 设置下运行同一延迟敏感负载，
+// 统计迭代延迟的 p50/p95/p99/max，并与 cpu.stat 中 nr_throttled、throttled_usec 的增量对照：
+// 每组设置都应发生限流，且 p99 随每次限流的平均停顿时间单调相关（秩相关系数为正）
+type Latency struct {
+	// Settings 为待比较的 CFS 设置，Quota/Period 应当相同
+	Settings []CFSSetting
+	// Iterations 为每组设置下的迭代次数
+	Iterations int
+	// Loops 为每次迭代的计算量（Python 循环次数）
+	Loops int
+	// Pause 为迭代之间的休眠
+	Pause time.Duration
+
+	sessions []*scenario.Session
+	results  []LatencyResult
+}
+
+func (*Latency) Name() string { return LatencyName }
+
+func (l *Latency) Params() map[string]string {
+	settings := make([]string, len(l.Settings))
+	for i, s := range l.Settings {
+		settings[i] = s.String()
+	}
+	return map[string]string{
+		"cfs":        strings.Join(settings, ","),
+		"iterations": strconv.Itoa(l.Iterations),
+		"loops":      strconv.Itoa(l.Loops),
+	}
+}
+
+func (l *Latency) Prepare(ctx context.Context, env *scenario.Env) error {
+	if len(l.Settings) < 2 {
+		return fmt.Errorf("至少需要两组 CFS 设置，当前为 %d 组", len(l.Settings))
+	}
+	if err := sameVCPU(l.Settings); err != nil {
+		return err
+	}
+	return scenario.PullImage(ctx, env.Client, latencyImage)
+}
+
+func (l *Latency) Run(ctx context.Context, env *scenario.Env) error {
+	l.results = nil
+	for i, setting := range l.Settings {
+		result, err := l.measure(ctx, env, i, setting)
+		if err != nil {
+			return fmt.Errorf("CFS 设置 %s: %w", setting, err)
+		}
+		env.Report.Mark(time.Now(), "phase", "CFS %s：p50=%s p99=%s max=%s，限流 %d/%d 个周期，共 %s",
+			setting, result.Latency.P50, result.Latency.P99, result.Latency.Max,
+			result.Throttle.Throttled, result.Throttle.Periods, result.Throttle.Time)
+		l.results = append(l.results, result)
+	}
+	return nil
+}
+
+// measure 以一组 CFS 设置启动常驻容器，读取负载前后的 cpu.stat 并收集迭代延迟
+func (l *Latency) measure(ctx context.Context, env *scenario.Env, i int, setting CFSSetting) (LatencyResult, error) {
+	result := LatencyResult{Setting: setting}
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: latencyImage,
+	}, &container.HostConfig{
+		Resources: container.Resources{CPUPeriod: setting.Period, CPUQuota: setting.Quota},
+	}, fmt.Sprintf("cpu-latency-%d", i))
+	if err != nil {
+		return result, fmt.Errorf("启动容器失败: %w", err)
+	}
+	l.sessions = append(l.sessions, sess)
+
+	statFiles := []string{"/sys/fs/cgroup/cpu.stat", "/sys/fs/cgroup/cpu/cpu.stat", "/sys/fs/cgroup/cpu,cpuacct/cpu.stat"}
+	probes, err := sess.RunProbes(ctx, []scenario.ProbeStep{
+		scenario.ReadFilesStep("cpu-stat-before", statFiles...),
+		{
+			Name: "latency",
+			Cmd: []string{"python3", "-c", latencyScript, strconv.Itoa(l.Iterations), strconv.Itoa(l.Loops),
+				strconv.FormatFloat(l.Pause.Seconds(), 'f', -1, 64)},
+			Timeout: 5 * time.Minute,
+		},
+		scenario.ReadFilesStep("cpu-stat-after", statFiles...),
+	})
+	for _, p := range probes {
+		if p.Name != "latency" {
+			scenario.LogProbeResult(p)
+		}
+	}
+	if err != nil {
+		return result, fmt.Errorf("探测中断: %w", err)
+	}
+
+	samples, err := parseLatencies(probes[1].Stdout)
+	if err != nil {
+		return result, err
+	}
+	result.Latency = latencyPercentiles(samples)
+	result.Throttle = throttleDelta(parseCPUStat(probes[0].Stdout), parseCPUStat(probes[2].Stdout))
+	return result, nil
+}
+
+func (l *Latency) Verify(context.Context, *scenario.Env) (string, error) {
+	log.Printf("%-14s %6s %10s %10s %10s %10s %14s %12s %10s", "quota/period", "iters", "p50", "p95", "p99", "max", "nr_throttled", "throttled", "stall")
+	best := l.results[0]
+	var problems []string
+	p99s := make([]float64, len(l.results))
+	stalls := make([]float64, len(l.results))
+	for i, r := range l.results {
+		log.Printf("%-14s %6d %10s %10s %10s %10s %8d/%-5d %12s %10s", r.Setting, r.Latency.Count,
+			r.Latency.P50, r.Latency.P95, r.Latency.P99, r.Latency.Max,
+			r.Throttle.Throttled, r.Throttle.Periods, r.Throttle.Time, r.Throttle.Stall())
+		switch {
+		case r.Latency.Count == 0:
+			problems = append(problems, fmt.Sprintf("CFS %s 没有延迟样本", r.Setting))
+		case r.Throttle.Periods == 0:
+			problems = append(problems, fmt.Sprintf("CFS %s 未读取到 cpu.stat 的周期计数", r.Setting))
+		case r.Throttle.Throttled == 0:
+			problems = append(problems, fmt.Sprintf("CFS %s 没有发生限流，负载未超过配额", r.Setting))
+		}
+		p99s[i], stalls[i] = float64(r.Latency.P99), float64(r.Throttle.Stall())
+		if r.Latency.P99 < best.Latency.P99 {
+			best = r
+		}
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	rho := rankCorrelation(stalls, p99s)
+	log.Printf("p99 与每次限流平均停顿的秩相关系数 %.2f", rho)
+	if rho <= 0 {
+		return "", fmt.Errorf("p99 与每次限流的平均停顿不相关（秩相关系数 %.2f），尾延迟不是由 CFS 限流造成的", rho)
+	}
+	return fmt.Sprintf("%d 组 CFS 设置的 p99 随每次限流的平均停顿增大（秩相关系数 %.2f）；%s 的 p99 最低（%s，max %s，限流 %d 次共 %s，平均停顿 %s）",
+		len(l.results), rho, best.Setting, best.Latency.P99, best.Latency.Max, best.Throttle.Throttled, best.Throttle.Time, best.Throttle.Stall()), nil
+}
+
+// sameVCPU 检查各组设置的 Quota/Period 相同，只有 vCPU 一致时比较 CPUPeriod 的影响才有意义
+func sameVCPU(settings []CFSSetting) error {
+	first := settings[0]
+	for _, s := range settings {
+		if s.Period <= 0 || s.Quota <= 0 {
+			return fmt.Errorf("CFS 设置 %s 的周期与配额必须为正", s)
+		}
+		if s.Quota*first.Period != first.Quota*s.Period {
+			return fmt.Errorf("CFS 设置 %s 与 %s 的 vCPU 不同（%.3f 与 %.3f），无法比较",
+				s, first, float64(s.Quota)/float64(s.Period), float64(first.Quota)/float64(first.Period))
+		}
+	}
+	return nil
+}
+
+// rankCorrelation 计算 Spearman 秩相关系数，并列值取平均秩；任一序列全部相同时返回 0
+func rankCorrelation(x, y []float64) float64 {
+	rx, ry := ranks(x), ranks(y)
+	n := float64(len(rx))
+	var mx, my float64
+	for i := range rx {
+		mx += rx[i] / n
+		my += ry[i] / n
+	}
+	var cov, vx, vy float64
+	for i := range rx {
+		cov += (rx[i] - mx) * (ry[i] - my)
+		vx += (rx[i] - mx) * (rx[i] - mx)
+		vy += (ry[i] - my) * (ry[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
+
+// ranks 返回各值的秩（从 1 开始），并列值取平均秩
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(values[a], values[b]) })
+	r := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		for k := i; k <= j; k++ {
+			r[order[k]] = float64(i+j)/2 + 1
+		}
+		i = j + 1
+	}
+	return r
+}
+
+func (l *Latency) Cleanup(context.Context, *scenario.Env) error {
+	for _, sess := range l.sessions {
+		sess.Close()
+	}
+	l.sessions = nil
+	return nil
+}
+
+// parseLatencies 解析负载输出的 "latency_us <v1> <v2> ..."
+func parseLatencies(out string) ([]time.Duration, error) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "latency_us" {
+			continue
+		}
+		samples := make([]time.Duration, 0, len(fields)-1)
+		for _, f := range fields[1:] {
+			us, err := strconv.ParseInt(f, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("无效的延迟样本 %q", f)
+			}
+			samples = append(samples, time.Duration(us)*time.Microsecond)
+		}
+		return samples, nil
+	}
+	return nil, errors.New("负载输出中没有延迟样本")
+}
+
+// latencyPercentiles 按最近秩法计算分位数
+func latencyPercentiles(samples []time.Duration) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sorted := slices.Sorted(slices.Values(samples))
+	at := func(p float64) time.Duration {
+		rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[min(max(rank, 0), len(sorted)-1)]
+	}
+	return LatencyStats{
+		Count: len(sorted),
+		P50:   at(0.50),
+		P95:   at(0.95),
+		P99:   at(0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// parseCPUStat 解析 ReadFilesStep 读取的 cpu.stat；v1 下多个路径指向同一层级，只取其中一份
+func parseCPUStat(out string) map[string]int64 {
+	stat := map[string]int64{}
+	for _, content := range scenario.ParseReadFiles(out) {
+		for _, line := range strings.Split(content, "\n") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+				if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+					stat[k] = n
+				}
+			}
+		}
+		break
+	}
+	return stat
+}
+
+// throttleDelta 计算前后两次 cpu.stat 的增量，兼容 v2 的 throttled_usec 与 v1 的 throttled_time（纳秒）
+func throttleDelta(before, after map[string]int64) ThrottleStats {
+	d := ThrottleStats{
+		Periods:   after["nr_periods"] - before["nr_periods"],
+		Throttled: after["nr_throttled"] - before["nr_throttled"],
+	}
+	if _, ok := after["throttled_usec"]; ok {
+		d.Time = time.Duration(after["throttled_usec"]-before["throttled_usec"]) * time.Microsecond
+	} else {
+		d.Time = time.Duration(after["throttled_time"] - before["throttled_time"])
+	}
+	return d
+}
@@ -0,0 +1,103 @@
+package cpu
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLatencyPercentiles(t *testing.T) {
+	var samples []time.Duration
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*time.Millisecond)
+	}
+	got := latencyPercentiles(samples)
+	want := LatencyStats{Count: 100, P50: 50 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond, Max: 100 * time.Millisecond}
+	if got != want {
+		t.Errorf("latencyPercentiles = %+v, want %+v", got, want)
+	}
+	if got := latencyPercentiles(nil); got != (LatencyStats{}) {
+		t.Errorf("latencyPercentiles(nil) = %+v", got)
+	}
+}
+
+func TestParseLatencies(t *testing.T) {
+	got, err := parseLatencies("warming up\nlatency_us 1200 80000 950\n")
+	if err != nil || len(got) != 3 || got[1] != 80*time.Millisecond {
+		t.Fatalf("parseLatencies = %v, %v", got, err)
+	}
+	if _, err := parseLatencies("no samples"); err == nil {
+		t.Error("parseLatencies without samples: want error")
+	}
+}
+
+func TestThrottleDelta(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          ThrottleStats
+	}{
+		{
+			name:   "cgroup v2",
+			before: "== /sys/fs/cgroup/cpu.stat\nusage_usec 10\nnr_periods 5\nnr_throttled 1\nthrottled_usec 200\n",
+			after:  "== /sys/fs/cgroup/cpu.stat\nusage_usec 90\nnr_periods 105\nnr_throttled 61\nthrottled_usec 3000200\n",
+			want:   ThrottleStats{Periods: 100, Throttled: 60, Time: 3 * time.Second},
+		},
+		{
+			name:   "cgroup v1",
+			before: "== /sys/fs/cgroup/cpu/cpu.stat\nnr_periods 0\nnr_throttled 0\nthrottled_time 0\n",
+			after:  "== /sys/fs/cgroup/cpu/cpu.stat\nnr_periods 40\nnr_throttled 10\nthrottled_time 500000000\n",
+			want:   ThrottleStats{Periods: 40, Throttled: 10, Time: 500 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		if got := throttleDelta(parseCPUStat(tt.before), parseCPUStat(tt.after)); got != tt.want {
+			t.Errorf("%s: throttleDelta = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSameVCPU(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []CFSSetting
+		wantErr  bool
+	}{
+		{"相同 vCPU", []CFSSetting{{10_000, 5_000}, {100_000, 50_000}, {500_000, 250_000}}, false},
+		{"vCPU 不同", []CFSSetting{{10_000, 5_000}, {100_000, 100_000}}, true},
+		{"配额为 0", []CFSSetting{{10_000, 5_000}, {100_000, 0}}, true},
+	}
+	for _, tt := range tests {
+		if err := sameVCPU(tt.settings); (err != nil) != tt.wantErr {
+			t.Errorf("%s: sameVCPU = %v, wantErr %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRankCorrelation(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		{"同序", []float64{1, 5, 20}, []float64{3, 40, 90}, 1},
+		{"逆序", []float64{1, 5, 20}, []float64{90, 40, 3}, -1},
+		{"部分一致", []float64{1, 5, 20}, []float64{3, 90, 40}, 0.5},
+		{"并列", []float64{1, 1, 20}, []float64{3, 3, 40}, 1},
+		{"全部相同", []float64{2, 2, 2}, []float64{3, 90, 40}, 0},
+	}
+	for _, tt := range tests {
+		if got := rankCorrelation(tt.x, tt.y); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: rankCorrelation = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestThrottleStall(t *testing.T) {
+	if got := (ThrottleStats{Throttled: 60, Time: 3 * time.Second}).Stall(); got != 50*time.Millisecond {
+		t.Errorf("Stall = %s, want 50ms", got)
+	}
+	if got := (ThrottleStats{}).Stall(); got != 0 {
+		t.Errorf("Stall without throttling = %s, want 0", got)
+	}
+}
@@ -0,0 +1,116 @@
+package cpu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// LimitName 为 CPUQuota 限额场景的注册名
+const LimitName = "cpu-limit"
+
+// limitSlack 为平均 vCPU 相对配额允许超出的比例，覆盖采样误差
+const limitSlack = 0.1
+
+func init() {
+	scenario.Register(&Limit{
+		CPUQuota: 200000,
+		Burners:  3,
+		Window:   5 * time.Second,
+	})
+}
+
+// Limit 以 CPUQuota 启动常驻容器（CFS 周期默认 100ms，200000 即 2 vCPU），核对 cgroup 中的配额，
+// 再在容器内启动多于配额的忙循环，检查统计窗口内的平均 vCPU 不超过配额
+type Limit struct {
+	// CPUQuota 为每个 CFS 周期（100ms）内可用的 CPU 时间（微秒）
+	CPUQuota int64
+	// Burners 为容器内并行的忙循环个数，应多于配额对应的 vCPU
+	Burners int
+	// Window 为统计窗口长度
+	Window time.Duration
+
+	sess       *scenario.Session
+	mismatches []string
+	from, to   time.Time
+}
+
+func (*Limit) Name() string { return LimitName }
+
+func (l *Limit) Params() map[string]string {
+	return map[string]string{
+		"cpuQuota": strconv.FormatInt(l.CPUQuota, 10),
+		"burners":  strconv.Itoa(l.Burners),
+		"window":   l.Window.String(),
+	}
+}
+
+func (*Limit) Prepare(ctx context.Context, env *scenario.Env) error {
+	return scenario.PullImage(ctx, env.Client, scenario.ProbeImage)
+}
+
+// vCPU 返回配额对应的 vCPU
+func (l *Limit) vCPU() float64 { return float64(l.CPUQuota) / 100000 }
+
+func (l *Limit) Run(ctx context.Context, env *scenario.Env) error {
+	res := container.Resources{CPUQuota: l.CPUQuota}
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: scenario.ProbeImage,
+		Cmd:   []string{"sh", "-c", strings.Repeat("("+burnScript+") & ", l.Burners) + "wait"},
+	}, &container.HostConfig{Resources: res}, "cpu-limit")
+	if err != nil {
+		return fmt.Errorf("启动负载容器失败: %w", err)
+	}
+	l.sess = sess
+
+	_, mismatches, err := sess.VerifyCgroup(ctx, "verify-quota", res)
+	if err != nil {
+		return fmt.Errorf("读取容器 %s 的 cgroup 失败: %w", sess.Name, err)
+	}
+	l.mismatches = mismatches
+
+	if err := sleep(ctx, time.Second); err != nil {
+		return err
+	}
+	l.from = time.Now()
+	env.Report.Mark(l.from, "phase", "统计窗口开始，%d 个忙循环", l.Burners)
+	if err := sleep(ctx, l.Window); err != nil {
+		return err
+	}
+	l.to = time.Now()
+	env.Report.Mark(l.to, "phase", "统计窗口结束")
+	return nil
+}
+
+func (l *Limit) Verify(_ context.Context, env *scenario.Env) (string, error) {
+	problems := append([]string(nil), l.mismatches...)
+	used := env.Report.MeanCPU(l.sess.ID, l.from, l.to)
+	log.Printf("CPUQuota=%d（%.1f vCPU），%d 个忙循环平均 %.3f vCPU", l.CPUQuota, l.vCPU(), l.Burners, used)
+	switch {
+	case used == 0:
+		problems = append(problems, "统计窗口内没有 CPU 采样")
+	case used > l.vCPU()*(1+limitSlack):
+		problems = append(problems, fmt.Sprintf("平均 %.3f vCPU，超过配额 %.1f vCPU", used, l.vCPU()))
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("cgroup 配额与 CPUQuota=%d 一致，%d 个忙循环平均 %.3f vCPU，未超过 %.1f vCPU",
+		l.CPUQuota, l.Burners, used, l.vCPU()), nil
+}
+
+func (l *Limit) Cleanup(context.Context, *scenario.Env) error {
+	if l.sess != nil {
+		l.sess.Close()
+		l.sess = nil
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package cpu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// SharesName 为 CPU 权重争用场景的注册名
+const SharesName = "cpu-shares"
+
+// burnScript 在容器内启动单个忙循环；所有容器绑定在同一个 CPU 上，争用时按权重分配时间片
+const burnScript = "while :; do :; done"
+
+func init() {
+	scenario.Register(&Shares{
+		Weights:   []int64{2048, 1024, 512},
+		Cpuset:    "0",
+		Warmup:    3 * time.Second,
+		Window:    10 * time.Second,
+		Tolerance: 0.2,
+	})
+}
+
+// Shares 在同一 CpusetCpus 上并行运行多个 CPU 忙循环容器，各自配置不同的 CPUShares
+// （cgroup v2 下由 daemon 换算为 cpu.weight），统计窗口内各容器的 CPU 用量，
+// 检查实际占比与权重占比的偏差不超过 Tolerance
+type Shares struct {
+	// Weights 为各容器的 CPUShares，至少两个
+	Weights []int64
+	// Cpuset 为所有容器共同绑定的 CPU 集合，单个 CPU 时争用最明显
+	Cpuset string
+	// Warmup 为全部容器启动后等待调度稳定的时间，不计入统计窗口
+	Warmup time.Duration
+	// Window 为统计窗口长度
+	Window time.Duration
+	// Tolerance 为实际占比相对期望占比允许的偏差比例
+	Tolerance float64
+
+	sessions []*scenario.Session
+	cgroups  [][]string
+	from, to time.Time
+}
+
+func (*Shares) Name() string { return SharesName }
+
+func (s *Shares) Params() map[string]string {
+	weights := make([]string, len(s.Weights))
+	for i, w := range s.Weights {
+		weights[i] = strconv.FormatInt(w, 10)
+	}
+	return map[string]string{
+		"cpuShares": strings.Join(weights, ":"),
+		"cpuset":    s.Cpuset,
+		"window":    s.Window.String(),
+		"tolerance": strconv.FormatFloat(s.Tolerance, 'f', -1, 64),
+	}
+}
+
+func (s *Shares) Prepare(ctx context.Context, env *scenario.Env) error {
+	if len(s.Weights) < 2 {
+		return fmt.Errorf("至少需要两个权重，当前为 %v", s.Weights)
+	}
+	return scenario.PullImage(ctx, env.Client, scenario.ProbeImage)
+}
+
+func (s *Shares) Run(ctx context.Context, env *scenario.Env) error {
+	s.sessions, s.cgroups = nil, nil
+	for i, w := range s.Weights {
+		res := container.Resources{CPUShares: w, CpusetCpus: s.Cpuset}
+		sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+			Image: scenario.ProbeImage,
+			Cmd:   []string{"sh", "-c", burnScript},
+		}, &container.HostConfig{Resources: res}, fmt.Sprintf("cpu-shares-%d", i))
+		if err != nil {
+			return fmt.Errorf("启动第 %d 个负载容器失败: %w", i+1, err)
+		}
+		s.sessions = append(s.sessions, sess)
+
+		_, mismatches, err := sess.VerifyCgroup(ctx, "verify-shares", res)
+		if err != nil {
+			return fmt.Errorf("读取容器 %s 的 cgroup 失败: %w", sess.Name, err)
+		}
+		s.cgroups = append(s.cgroups, mismatches)
+	}
+
+	env.Report.Mark(time.Now(), "phase", "全部 %d 个容器已启动，预热 %s", len(s.sessions), s.Warmup)
+	if err := sleep(ctx, s.Warmup); err != nil {
+		return err
+	}
+	s.from = time.Now()
+	env.Report.Mark(s.from, "phase", "统计窗口开始")
+	if err := sleep(ctx, s.Window); err != nil {
+		return err
+	}
+	s.to = time.Now()
+	env.Report.Mark(s.to, "phase", "统计窗口结束")
+	return nil
+}
+
+func (s *Shares) Verify(_ context.Context, env *scenario.Env) (string, error) {
+	var problems []string
+	for _, mismatches := range s.cgroups {
+		problems = append(problems, mismatches...)
+	}
+
+	observed := make([]float64, len(s.sessions))
+	for i, sess := range s.sessions {
+		observed[i] = env.Report.MeanCPU(sess.ID, s.from, s.to)
+	}
+	shares, deviations := shareDeviations(s.Weights, observed)
+	var parts []string
+	for i, sess := range s.sessions {
+		log.Printf("容器 %s：CPUShares=%d，平均 %.3f vCPU，占比 %.1f%%，偏差 %+.1f%%",
+			sess.Name, s.Weights[i], observed[i], shares[i]*100, deviations[i]*100)
+		parts = append(parts, fmt.Sprintf("%d=%.1f%%", s.Weights[i], shares[i]*100))
+		if observed[i] == 0 {
+			problems = append(problems, fmt.Sprintf("容器 %s 在统计窗口内没有 CPU 采样", sess.Name))
+		} else if math.Abs(deviations[i]) > s.Tolerance {
+			problems = append(problems, fmt.Sprintf("CPUShares=%d 的容器占比 %.1f%%，偏离期望 %.0f%%",
+				s.Weights[i], shares[i]*100, deviations[i]*100))
+		}
+	}
+
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("%d 个容器的 CPU 占比 %s 与权重一致（容差 %.0f%%）",
+		len(s.sessions), strings.Join(parts, " "), s.Tolerance*100), nil
+}
+
+func (s *Shares) Cleanup(context.Context, *scenario.Env) error {
+	for _, sess := range s.sessions {
+		sess.Close()
+	}
+	s.sessions = nil
+	return nil
+}
+
+// shareDeviations 计算各容器实际的 CPU 占比，以及相对权重期望占比的偏差比例（实际/期望 - 1）
+func shareDeviations(weights []int64, observed []float64) (shares, deviations []float64) {
+	var totalWeight int64
+	var totalCPU float64
+	for i := range weights {
+		totalWeight += weights[i]
+		totalCPU += observed[i]
+	}
+	shares = make([]float64, len(weights))
+	deviations = make([]float64, len(weights))
+	for i := range weights {
+		expected := float64(weights[i]) / float64(totalWeight)
+		if totalCPU > 0 {
+			shares[i] = observed[i] / totalCPU
+		}
+		deviations[i] = shares[i]/expected - 1
+	}
+	return shares, deviations
+}
+
+// sleep 等待 d，ctx 结束时提前返回其错误
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,29 @@
+package cpu
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShareDeviations(t *testing.T) {
+	tests := []struct {
+		name     string
+		weights  []int64
+		observed []float64
+		want     []float64
+	}{
+		{name: "exact", weights: []int64{2048, 1024, 512}, observed: []float64{0.571, 0.286, 0.143}, want: []float64{0, 0, 0}},
+		{name: "equal share", weights: []int64{1024, 1024}, observed: []float64{0.6, 0.4}, want: []float64{0.2, -0.2}},
+		{name: "no usage", weights: []int64{1024, 512}, observed: []float64{0, 0}, want: []float64{-1, -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := shareDeviations(tt.weights, tt.observed)
+			for i := range tt.want {
+				if math.Abs(got[i]-tt.want[i]) > 0.01 {
+					t.Fatalf("deviations = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
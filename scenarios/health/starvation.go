@@ -0,0 +1,404 @@
+// Package health 为资源饥饿下的健康检查场景：为常驻容器配置 Config.Healthcheck，在限额之内逐级
+// 加重 CPU 或内存压力，通过 ContainerInspect 记录健康状态的变化与每次探测的日志，得出健康检查开始失败的饥饿程度
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+
+	"test-docker/pkg/scenario"
+)
+
+// 各饥饿资源对应的注册名
+const (
+	CPUName    = "health-cpu-starvation"
+	MemoryName = "health-memory-starvation"
+)
+
+// Names 为全部健康检查饥饿场景
+var Names = []string{CPUName, MemoryName}
+
+// 施加压力的资源
+const (
+	ResourceCPU    = "cpu"
+	ResourceMemory = "memory"
+)
+
+const (
+	nanoCPUs    = 500_000_000
+	memoryBytes = 64 * scenario.MiB
+	shmBytes    = 128 * scenario.MiB
+	// healthyTimeout 为启动后等待首次 healthy 的上限（不含 StartPeriod）
+	healthyTimeout = 30 * time.Second
+	pollInterval   = 200 * time.Millisecond
+	// releasedLevel 标记撤除压力之后的探测与状态变化
+	releasedLevel = -1
+)
+
+// cpuProbe 为 CPU 饥饿下的健康检查：固定量的 shell 计算，未饥饿时远低于超时
+const cpuProbe = `i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done`
+
+// memoryProbe 为内存饥饿下的健康检查：dd 需要分配 4MiB 缓冲区，余量不足时被 OOM kill
+const memoryProbe = `dd if=/dev/zero of=/dev/null bs=4M count=4 2>/dev/null`
+
+// burnerTag 为忙循环进程的 $0，便于 pkill 精确匹配
+const burnerTag = "starve-burner"
+
+func init() {
+	healthcheck := container.HealthConfig{
+		Interval:    time.Second,
+		Timeout:     500 * time.Millisecond,
+		StartPeriod: 3 * time.Second,
+		Retries:     3,
+	}
+	for _, s := range []*Starvation{
+		{Resource: ResourceCPU, Healthcheck: healthcheck, Levels: []int{0, 1, 2, 4, 8, 16}},
+		{Resource: ResourceMemory, Healthcheck: healthcheck, Levels: []int{0, 50, 75, 85, 92}},
+	} {
+		scenario.Register(s)
+	}
+}
+
+// Transition 为一次健康状态变化
+type Transition struct {
+	At            time.Time
+	From, To      string
+	Level         int
+	FailingStreak int
+}
+
+// ProbeLog 为 State.Health.Log 中的一次探测，Level 为探测开始时所处的饥饿档位
+type ProbeLog struct {
+	Level    int
+	Start    time.Time
+	Duration time.Duration
+	ExitCode int
+	Output   string
+}
+
+// LevelResult 为一个饥饿档位的汇总
+type LevelResult struct {
+	Level    int
+	Status   string
+	Probes   int
+	Failures int
+	Mean     time.Duration
+	Max      time.Duration
+}
+
+// Starvation 启动带健康检查的常驻容器，等待 healthy 后逐级施加压力：CPU 档位为 0.5 vCPU 限额内
+// 并发的忙循环数，内存档位为 64MiB 限额中被 /dev/shm 占用的百分比。每个档位保持足够让 Retries
+// 次失败累积的时间，一旦变为 unhealthy 即停止加压，撤除压力后观察是否恢复
+type Starvation struct {
+	// Resource 为 ResourceCPU 或 ResourceMemory
+	Resource string
+	// Healthcheck 为健康检查的间隔、超时、重试次数与启动宽限期，Test 由 Resource 决定
+	Healthcheck container.HealthConfig
+	// Levels 为递增的饥饿档位，第一个档位应为 0（不加压）
+	Levels []int
+
+	sess        *scenario.Session
+	level       int
+	status      string
+	transitions []Transition
+	probes      []ProbeLog
+	failedAt    int
+	failed      bool
+	recovered   bool
+}
+
+func (s *Starvation) Name() string {
+	if s.Resource == ResourceMemory {
+		return MemoryName
+	}
+	return CPUName
+}
+
+func (s *Starvation) Params() map[string]string {
+	levels := make([]string, len(s.Levels))
+	for i, l := range s.Levels {
+		levels[i] = strconv.Itoa(l)
+	}
+	params := map[string]string{
+		"resource":    s.Resource,
+		"levels":      strings.Join(levels, ","),
+		"interval":    s.Healthcheck.Interval.String(),
+		"timeout":     s.Healthcheck.Timeout.String(),
+		"startPeriod": s.Healthcheck.StartPeriod.String(),
+		"retries":     strconv.Itoa(s.Healthcheck.Retries),
+	}
+	if s.Resource == ResourceMemory {
+		params["memoryLimit"] = fmt.Sprintf("%dMiB", memoryBytes/scenario.MiB)
+	} else {
+		params["nanoCPUs"] = fmt.Sprintf("%.1f", float64(nanoCPUs)/1e9)
+	}
+	return params
+}
+
+func (s *Starvation) Footprint() scenario.Footprint {
+	if s.Resource == ResourceMemory {
+		return scenario.Footprint{Memory: memoryBytes, Tmpfs: shmBytes, Containers: 1}
+	}
+	return scenario.Footprint{Containers: 1}
+}
+
+func (s *Starvation) Prepare(ctx context.Context, env *scenario.Env) error {
+	if s.Resource != ResourceCPU && s.Resource != ResourceMemory {
+		return fmt.Errorf("未知的饥饿资源 %q", s.Resource)
+	}
+	if len(s.Levels) == 0 || s.Levels[0] != 0 {
+		return errors.New("饥饿档位需要以 0（不加压）开始")
+	}
+	if !slices.IsSorted(s.Levels) {
+		return errors.New("饥饿档位需要递增")
+	}
+	return scenario.PullImage(ctx, env.Client, scenario.ProbeImage)
+}
+
+func (s *Starvation) Run(ctx context.Context, env *scenario.Env) error {
+	s.level, s.status, s.failed, s.recovered = 0, "", false, false
+	s.transitions, s.probes = nil, nil
+
+	hc := s.Healthcheck
+	hc.Test = []string{"CMD-SHELL", cpuProbe}
+	resources := container.Resources{NanoCPUs: nanoCPUs}
+	if s.Resource == ResourceMemory {
+		hc.Test = []string{"CMD-SHELL", memoryProbe}
+		resources = container.Resources{Memory: memoryBytes, MemorySwap: memoryBytes}
+	}
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image:       scenario.ProbeImage,
+		Healthcheck: &hc,
+	}, &container.HostConfig{
+		ShmSize:   shmBytes,
+		Resources: resources,
+	}, "health-"+s.Resource)
+	if err != nil {
+		return fmt.Errorf("启动容器失败: %w", err)
+	}
+	s.sess = sess
+
+	if err := s.watch(ctx, env, hc.StartPeriod+healthyTimeout, container.Healthy); err != nil {
+		return err
+	}
+	if s.status != container.Healthy {
+		return fmt.Errorf("%s 内未进入 healthy，最后状态 %q", hc.StartPeriod+healthyTimeout, s.status)
+	}
+
+	hold := s.holdFor()
+	for _, level := range s.Levels {
+		if err := s.apply(ctx, level); err != nil {
+			return err
+		}
+		s.level = level
+		env.Report.Mark(time.Now(), "phase", "饥饿档位 %s", s.describe(level))
+		if err := s.watch(ctx, env, hold, container.Unhealthy); err != nil {
+			return err
+		}
+		if s.status == container.Unhealthy {
+			s.failed, s.failedAt = true, level
+			break
+		}
+	}
+
+	if err := s.apply(ctx, 0); err != nil {
+		return err
+	}
+	s.level = releasedLevel
+	env.Report.Mark(time.Now(), "phase", "撤除压力")
+	if err := s.watch(ctx, env, hold, container.Healthy); err != nil {
+		return err
+	}
+	s.recovered = s.status == container.Healthy
+	return nil
+}
+
+// holdFor 返回每个档位的保持时间：Retries+1 轮探测全部超时所需的时间，再加一个间隔
+func (s *Starvation) holdFor() time.Duration {
+	hc := s.Healthcheck
+	return time.Duration(hc.Retries+1)*(hc.Interval+hc.Timeout) + hc.Interval
+}
+
+// apply 把压力调整到指定档位
+func (s *Starvation) apply(ctx context.Context, level int) error {
+	var step scenario.ProbeStep
+	switch {
+	case s.Resource == ResourceMemory && level == 0:
+		step = scenario.ShellStep("release-shm", "rm -f /dev/shm/starve")
+	case s.Resource == ResourceMemory:
+		mib := memoryBytes / scenario.MiB * int64(level) / 100
+		step = scenario.ShellStep("fill-shm", fmt.Sprintf("dd if=/dev/zero of=/dev/shm/starve bs=1M count=%d; du -sm /dev/shm | cut -f1", mib))
+	case level == 0:
+		// pkill 在没有匹配进程时返回 1，同样视为成功
+		step = scenario.ProbeStep{Name: "release-burners", Cmd: []string{"pkill", "-f", burnerTag}}
+	default:
+		// 档位递增，只补足新增的忙循环；输出重定向后 exec 不必等待后台进程
+		step = scenario.ShellStep("start-burners", fmt.Sprintf(
+			`for i in $(seq %d); do sh -c 'while :; do :; done' %s </dev/null >/dev/null 2>&1 & done`,
+			level-s.level, burnerTag))
+	}
+	step.Timeout = time.Minute
+	result, err := s.sess.Exec(ctx, step)
+	if err != nil {
+		return fmt.Errorf("调整到档位 %s 中断: %w", s.describe(level), err)
+	}
+	scenario.LogProbeResult(result)
+	return nil
+}
+
+// watch 轮询 inspect，记录状态变化与新的探测日志，状态变为 until 或超过 d 时返回
+func (s *Starvation) watch(ctx context.Context, env *scenario.Env, d time.Duration, until string) error {
+	deadline := time.Now().Add(d)
+	for {
+		inspect, err := env.Client.ContainerInspect(ctx, s.sess.ID, client.ContainerInspectOptions{})
+		if err != nil {
+			return fmt.Errorf("查询容器状态失败: %w", err)
+		}
+		health := inspect.Container.State.Health
+		if health == nil {
+			return errors.New("容器状态中没有健康检查信息")
+		}
+		s.probes = mergeProbeLogs(s.probes, health.Log, s.level)
+		if health.Status != s.status {
+			t := Transition{At: time.Now(), From: s.status, To: health.Status, Level: s.level, FailingStreak: health.FailingStreak}
+			s.transitions = append(s.transitions, t)
+			env.Report.Mark(t.At, "health", "%s -> %s（档位 %s，连续失败 %d 次）", displayStatus(t.From), t.To, s.describe(t.Level), t.FailingStreak)
+			s.status = health.Status
+		}
+		if s.status == until || time.Now().After(deadline) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (s *Starvation) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems []string
+	for _, t := range s.transitions {
+		log.Printf("%s %-9s -> %-9s 档位 %-14s 连续失败 %d", t.At.Format("15:04:05.000"), displayStatus(t.From), t.To, s.describe(t.Level), t.FailingStreak)
+	}
+	for _, p := range s.probes {
+		if p.ExitCode != 0 {
+			log.Printf("探测失败：档位 %s，耗时 %s，退出码 %d：%s", s.describe(p.Level), p.Duration.Round(time.Millisecond), p.ExitCode, strings.TrimSpace(p.Output))
+		}
+	}
+	log.Printf("%-16s %-10s %6s %6s %10s %10s", "档位", "状态", "探测", "失败", "平均耗时", "最大耗时")
+	results := summarizeLevels(s.probes, s.transitions)
+	for _, r := range results {
+		log.Printf("%-16s %-10s %6d %6d %10s %10s", s.describe(r.Level), r.Status, r.Probes, r.Failures,
+			r.Mean.Round(time.Millisecond), r.Max.Round(time.Millisecond))
+	}
+
+	if len(results) == 0 || results[0].Level != 0 || results[0].Probes == 0 {
+		problems = append(problems, "未加压时没有健康检查记录")
+	} else if results[0].Failures == results[0].Probes {
+		problems = append(problems, "未加压时健康检查全部失败，探测本身不可用")
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+
+	recovery := "撤除压力后恢复 healthy"
+	if !s.recovered {
+		recovery = fmt.Sprintf("撤除压力后仍为 %s", s.status)
+	}
+	if !s.failed {
+		return fmt.Sprintf("最高档位 %s 下健康检查仍通过，%s", s.describe(s.Levels[len(s.Levels)-1]), recovery), nil
+	}
+	return fmt.Sprintf("健康检查在档位 %s 变为 unhealthy（超时 %s，重试 %d 次），%s",
+		s.describe(s.failedAt), s.Healthcheck.Timeout, s.Healthcheck.Retries, recovery), nil
+}
+
+func (s *Starvation) Cleanup(context.Context, *scenario.Env) error {
+	if s.sess != nil {
+		s.sess.Close()
+		s.sess = nil
+	}
+	return nil
+}
+
+// describe 返回档位的可读描述
+func (s *Starvation) describe(level int) string {
+	if level == releasedLevel {
+		return "撤除压力"
+	}
+	if s.Resource == ResourceMemory {
+		return fmt.Sprintf("shm %d%%", level)
+	}
+	return fmt.Sprintf("%d 个忙循环", level)
+}
+
+func displayStatus(status string) string {
+	if status == "" {
+		return "(none)"
+	}
+	return status
+}
+
+// mergeProbeLogs 把 State.Health.Log 中尚未记录的探测（按开始时间判断）追加到 logs。
+// Log 只保留最近几次探测，需要足够频繁地轮询
+func mergeProbeLogs(logs []ProbeLog, entries []*container.HealthcheckResult, level int) []ProbeLog {
+	var last time.Time
+	if len(logs) > 0 {
+		last = logs[len(logs)-1].Start
+	}
+	for _, e := range entries {
+		if e == nil || !e.Start.After(last) {
+			continue
+		}
+		logs = append(logs, ProbeLog{
+			Level:    level,
+			Start:    e.Start,
+			Duration: e.End.Sub(e.Start),
+			ExitCode: e.ExitCode,
+			Output:   e.Output,
+		})
+	}
+	return logs
+}
+
+// summarizeLevels 按档位汇总探测次数、失败次数与耗时，Status 为该档位最后一次状态变化后的状态
+func summarizeLevels(logs []ProbeLog, transitions []Transition) []LevelResult {
+	var results []LevelResult
+	index := map[int]int{}
+	for _, p := range logs {
+		i, ok := index[p.Level]
+		if !ok {
+			i = len(results)
+			index[p.Level] = i
+			results = append(results, LevelResult{Level: p.Level})
+		}
+		r := &results[i]
+		r.Probes++
+		if p.ExitCode != 0 {
+			r.Failures++
+		}
+		r.Mean += p.Duration
+		r.Max = max(r.Max, p.Duration)
+	}
+	status := ""
+	for i := range results {
+		r := &results[i]
+		r.Mean /= time.Duration(r.Probes)
+		for _, t := range transitions {
+			if t.Level == r.Level {
+				status = t.To
+			}
+		}
+		r.Status = status
+	}
+	return results
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/health"
+)
+
+func main() {
+	scenario.Main(health.Names...)
+}
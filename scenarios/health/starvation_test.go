@@ -0,0 +1,55 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+func TestMergeProbeLogs(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(sec int, exit int) *container.HealthcheckResult {
+		start := base.Add(time.Duration(sec) * time.Second)
+		return &container.HealthcheckResult{Start: start, End: start.Add(100 * time.Millisecond), ExitCode: exit}
+	}
+	logs := mergeProbeLogs(nil, []*container.HealthcheckResult{entry(1, 0), entry(2, 0)}, 0)
+	// 第二次轮询时 Log 中仍包含已记录的探测
+	logs = mergeProbeLogs(logs, []*container.HealthcheckResult{entry(1, 0), entry(2, 0), entry(3, 1), nil}, 4)
+	if len(logs) != 3 {
+		t.Fatalf("len(logs) = %d, want 3", len(logs))
+	}
+	if last := logs[2]; last.Level != 4 || last.ExitCode != 1 || last.Duration != 100*time.Millisecond {
+		t.Errorf("logs[2] = %+v", last)
+	}
+}
+
+func TestSummarizeLevels(t *testing.T) {
+	logs := []ProbeLog{
+		{Level: 0, Duration: 20 * time.Millisecond},
+		{Level: 0, Duration: 40 * time.Millisecond},
+		{Level: 8, Duration: 500 * time.Millisecond, ExitCode: -1},
+		{Level: 8, Duration: 300 * time.Millisecond},
+		{Level: releasedLevel, Duration: 30 * time.Millisecond},
+	}
+	transitions := []Transition{
+		{From: "", To: container.Starting, Level: 0},
+		{From: container.Starting, To: container.Healthy, Level: 0},
+		{From: container.Healthy, To: container.Unhealthy, Level: 8},
+		{From: container.Unhealthy, To: container.Healthy, Level: releasedLevel},
+	}
+	got := summarizeLevels(logs, transitions)
+	want := []LevelResult{
+		{Level: 0, Status: container.Healthy, Probes: 2, Mean: 30 * time.Millisecond, Max: 40 * time.Millisecond},
+		{Level: 8, Status: container.Unhealthy, Probes: 2, Failures: 1, Mean: 400 * time.Millisecond, Max: 500 * time.Millisecond},
+		{Level: releasedLevel, Status: container.Healthy, Probes: 1, Mean: 30 * time.Millisecond, Max: 30 * time.Millisecond},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d levels, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("level %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,439 @@
+// Package logging 为日志驱动的容量限制场景：为容器设置 HostConfig.LogConfig（json-file/local 的
+// max-size、max-file），产生远超上限的日志，核对日志文件是否轮转且总量有界，并统计被丢弃或截断的输出
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/client"
+
+	"test-docker/pkg/scenario"
+)
+
+// 各日志驱动对应的注册名
+const (
+	JSONFileName = "logging-json-file"
+	LocalName    = "logging-local"
+)
+
+// Names 为全部日志轮转场景
+var Names = []string{JSONFileName, LocalName}
+
+const (
+	// logsMount 为辅助容器内该容器日志目录的挂载点
+	logsMount = "/logs"
+	// doneMarker 为负载写完全部日志后输出的最后一行
+	doneMarker = "log-done"
+	// fileSlack 为每个日志文件允许超出 max-size 的余量：驱动在写入后才检查大小，
+	// 单个文件最多多出一条记录及其封装
+	fileSlack = 64 * 1024
+	// logOverhead 为驱动为每条记录附加的封装（时间戳、流名等）的估计
+	logOverhead  = 64
+	pollInterval = time.Second
+	writeTimeout = 5 * time.Minute
+)
+
+// generatorScript 输出 n 行定宽记录 "seq=<8 位序号> <w 个 x>"，最后输出 doneMarker 并保持运行
+const generatorScript = `awk -v n=%d -v w=%d 'BEGIN { pad = sprintf("%%" w "s", ""); gsub(/ /, "x", pad);
+for (i = 1; i <= n; i++) printf "seq=%%08d %%s\n", i, pad; fflush() }'
+echo ` + doneMarker + `
+exec sleep 3600`
+
+func init() {
+	for _, driver := range []string{"json-file", "local"} {
+		scenario.Register(&Rotation{
+			Driver:    driver,
+			MaxSize:   scenario.MiB,
+			MaxFile:   3,
+			Lines:     100_000,
+			LineBytes: 200,
+		})
+	}
+}
+
+// Retained 为从 ContainerLogs 读回的日志统计
+type Retained struct {
+	// Lines 为完整的记录数，Truncated 为序号可识别但长度不符的记录数
+	Lines     int
+	Truncated int
+	// Other 为既不是记录也不是 doneMarker 的行
+	Other int
+	// First、Last 为读回的最小与最大序号，Gaps 为两者之间缺失的记录数
+	First, Last int
+	Gaps        int
+	Done        bool
+}
+
+// Rotation 以指定日志驱动与 max-size/max-file 启动容器，容器输出 Lines 行定宽记录（总量远超上限）。
+// 写入期间通过挂载了该容器日志目录的辅助容器定时统计日志文件数与总大小，写完后通过 ContainerLogs
+// 读回日志，核对保留的是连续的最新记录、没有截断，并计算被轮转丢弃的比例
+type Rotation struct {
+	// Driver 为 json-file 或 local
+	Driver string
+	// MaxSize 为单个日志文件的上限（字节，按 KiB 取整传给驱动），MaxFile 为保留的文件数
+	MaxSize int64
+	MaxFile int
+	// Lines 为输出的记录数，LineBytes 为每条记录的填充长度
+	Lines     int
+	LineBytes int
+
+	rootDir  string
+	sess     *scenario.Session
+	helper   *scenario.Session
+	logPath  string
+	samples  []LogSample
+	retained Retained
+}
+
+// LogSample 为一次日志目录统计
+type LogSample struct {
+	At    time.Time
+	Files map[string]int64
+}
+
+// Total 返回日志文件的总大小
+func (s LogSample) Total() int64 {
+	var total int64
+	for _, size := range s.Files {
+		total += size
+	}
+	return total
+}
+
+func (r *Rotation) Name() string {
+	if r.Driver == "local" {
+		return LocalName
+	}
+	return JSONFileName
+}
+
+func (r *Rotation) Params() map[string]string {
+	return map[string]string{
+		"driver":    r.Driver,
+		"maxSize":   r.maxSizeOpt(),
+		"maxFile":   strconv.Itoa(r.MaxFile),
+		"lines":     strconv.Itoa(r.Lines),
+		"lineBytes": strconv.Itoa(r.LineBytes),
+	}
+}
+
+// Footprint 按轮转失效、全部日志落盘的最坏情况估计磁盘占用
+func (r *Rotation) Footprint() scenario.Footprint {
+	return scenario.Footprint{Disk: int64(r.Lines) * int64(r.recordBytes()+logOverhead), Containers: 2}
+}
+
+// maxSizeOpt 返回传给驱动的 max-size 选项
+func (r *Rotation) maxSizeOpt() string {
+	return fmt.Sprintf("%dk", r.MaxSize/1024)
+}
+
+// bound 返回日志文件总大小的上限
+func (r *Rotation) bound() int64 {
+	return int64(r.MaxFile) * (r.MaxSize + fileSlack)
+}
+
+// recordBytes 返回一条完整记录（不含换行）的长度
+func (r *Rotation) recordBytes() int {
+	return len("seq=00000000 ") + r.LineBytes
+}
+
+func (r *Rotation) Prepare(ctx context.Context, env *scenario.Env) error {
+	if r.Driver != "json-file" && r.Driver != "local" {
+		return fmt.Errorf("不支持的日志驱动 %q", r.Driver)
+	}
+	if int64(r.Lines)*int64(r.recordBytes()) <= r.bound() {
+		return fmt.Errorf("日志总量 %d 字节未超过上限 %d 字节，无法触发丢弃", int64(r.Lines)*int64(r.recordBytes()), r.bound())
+	}
+	if err := scenario.PullImage(ctx, env.Client, scenario.ProbeImage); err != nil {
+		return err
+	}
+	info, err := env.Client.Info(ctx, client.InfoOptions{})
+	if err != nil {
+		return fmt.Errorf("查询 daemon Info 失败: %w", err)
+	}
+	r.rootDir = info.Info.DockerRootDir
+	return nil
+}
+
+func (r *Rotation) Run(ctx context.Context, env *scenario.Env) error {
+	r.samples, r.retained = nil, Retained{}
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: scenario.ProbeImage,
+		Cmd:   []string{"sh", "-c", fmt.Sprintf(generatorScript, r.Lines, r.LineBytes)},
+	}, &container.HostConfig{
+		LogConfig: container.LogConfig{
+			Type: r.Driver,
+			Config: map[string]string{
+				"max-size": r.maxSizeOpt(),
+				"max-file": strconv.Itoa(r.MaxFile),
+			},
+		},
+	}, "logging-"+r.Driver)
+	if err != nil {
+		return fmt.Errorf("启动容器失败: %w", err)
+	}
+	r.sess = sess
+	env.Report.Mark(time.Now(), "phase", "%s 日志负载开始（max-size=%s max-file=%d）", r.Driver, r.maxSizeOpt(), r.MaxFile)
+
+	inspect, err := env.Client.ContainerInspect(ctx, sess.ID, client.ContainerInspectOptions{})
+	if err != nil {
+		return fmt.Errorf("查询容器状态失败: %w", err)
+	}
+	// local 驱动不在 API 中暴露 LogPath，日志位于容器目录的 local-logs 下
+	r.logPath = inspect.Container.LogPath
+	dir := path.Join(r.rootDir, "containers", sess.ID)
+	if r.logPath != "" {
+		dir = path.Dir(r.logPath)
+	}
+	helper, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: scenario.ProbeImage,
+	}, &container.HostConfig{
+		Mounts: []mount.Mount{{Type: mount.TypeBind, Source: dir, Target: logsMount, ReadOnly: true}},
+	}, "logging-inspect")
+	if err != nil {
+		return fmt.Errorf("启动日志目录辅助容器失败: %w", err)
+	}
+	r.helper = helper
+
+	if err := r.watch(ctx, env); err != nil {
+		return err
+	}
+	env.Report.Mark(time.Now(), "phase", "日志负载写完")
+	// 最后一次统计，覆盖写完之后的轮转与压缩
+	if err := sleep(ctx, pollInterval); err != nil {
+		return err
+	}
+	if _, err := r.sample(ctx); err != nil {
+		return err
+	}
+	r.addSeries(env)
+
+	retained, err := r.readBack(ctx, env)
+	if err != nil {
+		return err
+	}
+	r.retained = retained
+	return nil
+}
+
+// watch 定时统计日志文件，直到负载输出 doneMarker
+func (r *Rotation) watch(ctx context.Context, env *scenario.Env) error {
+	deadline := time.Now().Add(writeTimeout)
+	for {
+		sample, err := r.sample(ctx)
+		if err != nil {
+			return err
+		}
+		if len(r.samples) > 1 && len(sample.Files) != len(r.samples[len(r.samples)-2].Files) {
+			env.Report.Mark(sample.At, "rotate", "日志文件数 %d，总计 %dKiB", len(sample.Files), sample.Total()/1024)
+		}
+		done, err := r.finished(ctx, env)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s 内日志负载未写完", writeTimeout)
+		}
+		if err := sleep(ctx, pollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// sample 在辅助容器内统计日志文件大小
+func (r *Rotation) sample(ctx context.Context) (LogSample, error) {
+	pattern := "*-json.log*"
+	if r.Driver == "local" {
+		pattern = "container.log*"
+	}
+	result, err := r.helper.Exec(ctx, scenario.ShellStep("log-files",
+		fmt.Sprintf("cd %s && find . -type f -name %q -exec stat -c '%%s %%n' {} +", logsMount, pattern)))
+	if err != nil {
+		return LogSample{}, fmt.Errorf("统计日志文件中断: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return LogSample{}, fmt.Errorf("统计日志文件失败（退出码 %d）: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	sample := LogSample{At: result.FinishedAt, Files: parseLogFiles(result.Stdout)}
+	r.samples = append(r.samples, sample)
+	return sample, nil
+}
+
+// finished 通过最后一行日志判断负载是否写完
+func (r *Rotation) finished(ctx context.Context, env *scenario.Env) (bool, error) {
+	logs, err := env.Client.ContainerLogs(ctx, r.sess.ID, client.ContainerLogsOptions{ShowStdout: true, Tail: "1"})
+	if err != nil {
+		return false, fmt.Errorf("读取容器日志失败: %w", err)
+	}
+	defer logs.Close()
+	var stdout bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &bytes.Buffer{}, logs); err != nil {
+		return false, fmt.Errorf("解析容器日志失败: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()) == doneMarker, nil
+}
+
+// readBack 通过 ContainerLogs 读回全部保留的日志并统计
+func (r *Rotation) readBack(ctx context.Context, env *scenario.Env) (Retained, error) {
+	logs, err := env.Client.ContainerLogs(ctx, r.sess.ID, client.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return Retained{}, fmt.Errorf("读取容器日志失败: %w", err)
+	}
+	defer logs.Close()
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, logs); err != nil {
+		return Retained{}, fmt.Errorf("解析容器日志失败: %w", err)
+	}
+	if stderr.Len() > 0 {
+		log.Printf("容器 stderr：%s", strings.TrimSpace(stderr.String()))
+	}
+	return countRetained(stdout.String(), r.recordBytes()), nil
+}
+
+func (r *Rotation) addSeries(env *scenario.Env) {
+	series := &scenario.Series{Name: r.Driver + " 日志文件总大小", Unit: "KiB", Limit: float64(r.MaxSize*int64(r.MaxFile)) / 1024}
+	for _, s := range r.samples {
+		series.Points = append(series.Points, scenario.Point{At: s.At, Value: float64(s.Total()) / 1024})
+	}
+	env.Report.AddSeries(series)
+}
+
+func (r *Rotation) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems []string
+	if r.Driver == "json-file" && r.logPath == "" {
+		problems = append(problems, "json-file 驱动的 inspect 结果中没有 LogPath")
+	}
+	if len(r.samples) == 0 {
+		return "", errors.New("没有日志文件统计")
+	}
+
+	var peak int64
+	maxFiles := 0
+	for _, s := range r.samples {
+		peak = max(peak, s.Total())
+		maxFiles = max(maxFiles, len(s.Files))
+	}
+	last := r.samples[len(r.samples)-1]
+	names := make([]string, 0, len(last.Files))
+	for name := range last.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Printf("  %-40s %8dKiB", name, last.Files[name]/1024)
+	}
+	log.Printf("日志文件：最多 %d 个，总大小峰值 %dKiB（上限 %d × %s）", maxFiles, peak/1024, r.MaxFile, r.maxSizeOpt())
+	if maxFiles < 2 {
+		problems = append(problems, "日志文件没有发生轮转")
+	}
+	if maxFiles > r.MaxFile {
+		problems = append(problems, fmt.Sprintf("同时存在 %d 个日志文件，超过 max-file=%d", maxFiles, r.MaxFile))
+	}
+	if peak > r.bound() {
+		problems = append(problems, fmt.Sprintf("日志文件总大小峰值 %dKiB 超过上限 %dKiB", peak/1024, r.bound()/1024))
+	}
+
+	ret := r.retained
+	dropped := r.Lines - ret.Lines - ret.Truncated
+	log.Printf("ContainerLogs 读回 %d 条完整记录（序号 %d-%d，中间缺失 %d 条），截断 %d 条，其他行 %d 行，结束标记 %v",
+		ret.Lines, ret.First, ret.Last, ret.Gaps, ret.Truncated, ret.Other, ret.Done)
+	switch {
+	case !ret.Done || ret.Last != r.Lines:
+		problems = append(problems, fmt.Sprintf("最新的日志没有保留（最大序号 %d，结束标记 %v）", ret.Last, ret.Done))
+	case ret.Gaps > 0:
+		problems = append(problems, fmt.Sprintf("保留的日志中间缺失 %d 条记录", ret.Gaps))
+	}
+	if ret.Truncated > 0 {
+		problems = append(problems, fmt.Sprintf("%d 条记录被截断", ret.Truncated))
+	}
+	if dropped <= 0 {
+		problems = append(problems, "日志总量超过上限却没有记录被丢弃")
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("%s 日志轮转生效：最多 %d 个文件、峰值 %dKiB（上限 %d × %s），保留最新的 %d 条记录，丢弃最早的 %d 条（%.1f%%），无截断",
+		r.Driver, maxFiles, peak/1024, r.MaxFile, r.maxSizeOpt(), ret.Lines, dropped, float64(dropped)*100/float64(r.Lines)), nil
+}
+
+func (r *Rotation) Cleanup(context.Context, *scenario.Env) error {
+	for _, sess := range []*scenario.Session{r.helper, r.sess} {
+		if sess != nil {
+			sess.Close()
+		}
+	}
+	r.helper, r.sess = nil, nil
+	return nil
+}
+
+// parseLogFiles 解析 "stat -c '%s %n'" 的输出，返回文件名到大小的映射
+func parseLogFiles(out string) map[string]int64 {
+	files := map[string]int64{}
+	for _, line := range strings.Split(out, "\n") {
+		size, name, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(size, 10, 64); err == nil {
+			files[strings.TrimPrefix(name, "./")] = n
+		}
+	}
+	return files
+}
+
+// countRetained 统计读回的日志：recordBytes 为一条完整记录的长度
+func countRetained(out string, recordBytes int) Retained {
+	var ret Retained
+	var seqs []int
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		switch {
+		case line == doneMarker:
+			ret.Done = true
+		case strings.HasPrefix(line, "seq=") && len(line) >= len("seq=00000000"):
+			seq, err := strconv.Atoi(line[len("seq="):len("seq=00000000")])
+			if err != nil {
+				ret.Other++
+				continue
+			}
+			if len(line) != recordBytes {
+				ret.Truncated++
+				continue
+			}
+			ret.Lines++
+			seqs = append(seqs, seq)
+		case line != "":
+			ret.Other++
+		}
+	}
+	if len(seqs) == 0 {
+		return ret
+	}
+	sort.Ints(seqs)
+	ret.First, ret.Last = seqs[0], seqs[len(seqs)-1]
+	ret.Gaps = ret.Last - ret.First + 1 - len(seqs)
+	return ret
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
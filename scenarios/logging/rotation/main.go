@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/logging"
+)
+
+func main() {
+	scenario.Main(logging.Names...)
+}
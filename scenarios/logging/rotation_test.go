@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseLogFiles(t *testing.T) {
+	out := "1048600 ./abc-json.log.1\n20480 ./abc-json.log\n\nbroken\n"
+	files := parseLogFiles(out)
+	if len(files) != 2 || files["abc-json.log.1"] != 1048600 || files["abc-json.log"] != 20480 {
+		t.Fatalf("parseLogFiles = %v", files)
+	}
+	if total := (LogSample{Files: files}).Total(); total != 1069080 {
+		t.Errorf("Total = %d", total)
+	}
+}
+
+func TestCountRetained(t *testing.T) {
+	record := func(seq int) string { return fmt.Sprintf("seq=%08d %s", seq, strings.Repeat("x", 4)) }
+	recordBytes := len(record(0))
+	lines := []string{
+		record(5)[:10], // 不足 "seq=" 加 8 位序号，无法识别，计入其他行
+		record(7),
+		record(8),
+		record(10),
+		"unexpected",
+		doneMarker,
+	}
+	got := countRetained(strings.Join(lines, "\n")+"\n", recordBytes)
+	want := Retained{Lines: 3, Other: 2, First: 7, Last: 10, Gaps: 1, Done: true}
+	if got != want {
+		t.Fatalf("countRetained = %+v, want %+v", got, want)
+	}
+
+	got = countRetained(record(3)[:recordBytes-1]+"\n"+record(4)+"\n", recordBytes)
+	if got.Truncated != 1 || got.Lines != 1 || got.Done {
+		t.Errorf("countRetained = %+v", got)
+	}
+}
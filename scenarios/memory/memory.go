@@ -0,0 +1,143 @@
+// Package memory 为内存限额相关场景：在 64 MiB 限额的常驻容器内写满 /dev/shm 观察内存 cgroup 计数，
+// 以及重复 OOM 时不同重启策略的行为
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// Name 为内存探测场景的注册名
+const Name = "memory-probe"
+
+const (
+	demoImage        = "mem-test"
+	memoryLimitBytes = 64 * scenario.MiB
+	// 写入 /dev/shm 的数据会计入容器的内存 cgroup
+	shmFillMiB = 48
+)
+
+// probeResources 为探测容器的内存限额，MemorySwap 与 Memory 相等表示不允许使用 swap
+var probeResources = container.Resources{
+	Memory:     memoryLimitBytes,
+	MemorySwap: memoryLimitBytes,
+}
+
+func init() {
+	scenario.Register(&Probe{})
+}
+
+// Probe 读取内存 cgroup 限额，写入 /dev/shm 后再次读取用量与事件计数
+type Probe struct {
+	sess    *scenario.Session
+	results []scenario.ProbeResult
+}
+
+func (*Probe) Name() string { return Name }
+
+func (*Probe) Params() map[string]string {
+	return map[string]string{
+		"memoryLimit": fmt.Sprintf("%dMiB", memoryLimitBytes/scenario.MiB),
+		"shmFill":     fmt.Sprintf("%dMiB", shmFillMiB),
+	}
+}
+
+func (*Probe) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: memoryLimitBytes, Containers: 1}
+}
+
+// LocalImages 声明 mem-test 镜像需在本地构建
+func (*Probe) LocalImages() []string { return []string{demoImage} }
+
+// Prepare 不拉取镜像，mem-test 为本地构建的镜像
+func (*Probe) Prepare(context.Context, *scenario.Env) error { return nil }
+
+func (p *Probe) Run(ctx context.Context, env *scenario.Env) error {
+	// 创建常驻容器
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: demoImage,
+		Tty:   false,
+		Cmd:   []string{"sleep", "3000"},
+	}, &container.HostConfig{
+		Resources: probeResources,
+	}, "mem-test")
+	if err != nil {
+		return fmt.Errorf("启动内存探测容器失败: %w", err)
+	}
+	p.sess = sess
+
+	usageFiles := []string{
+		"/sys/fs/cgroup/memory.current",
+		"/sys/fs/cgroup/memory.events",
+		"/sys/fs/cgroup/memory/memory.usage_in_bytes",
+		"/sys/fs/cgroup/memory/memory.failcnt",
+	}
+	results, err := sess.RunProbes(ctx, []scenario.ProbeStep{
+		scenario.ReadFilesStep("cgroup-limit",
+			"/sys/fs/cgroup/memory.max",
+			"/sys/fs/cgroup/memory.swap.max",
+			"/sys/fs/cgroup/memory/memory.limit_in_bytes"),
+		scenario.ReadFilesStep("usage-before", usageFiles...),
+		scenario.ShellStep("shm-fill", fmt.Sprintf("dd if=/dev/zero of=/dev/shm/fill bs=1M count=%d", shmFillMiB)),
+		scenario.ReadFilesStep("usage-after", usageFiles...),
+	})
+	for _, result := range results {
+		scenario.LogProbeResult(result)
+	}
+	p.results = results
+	if err != nil {
+		return fmt.Errorf("探测中断: %w", err)
+	}
+	return nil
+}
+
+// Verify 核对容器内的内存限额，并确认写入 /dev/shm 的数据计入了内存 cgroup 且没有超过限额
+func (p *Probe) Verify(context.Context, *scenario.Env) (string, error) {
+	limit, before, fill, after := p.results[0], p.results[1], p.results[2], p.results[3]
+	if mismatches := scenario.CheckCgroup(probeResources, scenario.ParseReadFiles(limit.Stdout)); len(mismatches) > 0 {
+		return "", fmt.Errorf("内存限额未生效: %s", strings.Join(mismatches, "；"))
+	}
+	if fill.ExitCode != 0 {
+		return "", fmt.Errorf("向 /dev/shm 写入 %dMiB 失败（退出码 %d，可能被 OOM 杀死）: %s",
+			shmFillMiB, fill.ExitCode, strings.TrimSpace(fill.Stderr))
+	}
+	usedBefore, okBefore := memoryUsage(before.Stdout)
+	usedAfter, okAfter := memoryUsage(after.Stdout)
+	if !okBefore || !okAfter {
+		return "", errors.New("未读到内存 cgroup 用量")
+	}
+	charged := usedAfter - usedBefore
+	if charged < shmFillMiB*scenario.MiB*9/10 {
+		return "", fmt.Errorf("向 /dev/shm 写入 %dMiB，内存 cgroup 只增加 %dMiB", shmFillMiB, charged/scenario.MiB)
+	}
+	if usedAfter > memoryLimitBytes {
+		return "", fmt.Errorf("内存用量 %dMiB 超过 %dMiB 限额", usedAfter/scenario.MiB, memoryLimitBytes/scenario.MiB)
+	}
+	return fmt.Sprintf("%dMiB 限额已生效，写入 /dev/shm 的 %dMiB 计入内存 cgroup %dMiB，用量 %dMiB",
+		memoryLimitBytes/scenario.MiB, shmFillMiB, charged/scenario.MiB, usedAfter/scenario.MiB), nil
+}
+
+// memoryUsage 从 ReadFilesStep 的输出中取出内存 cgroup 用量，v2 为 memory.current，v1 为 memory.usage_in_bytes
+func memoryUsage(out string) (int64, bool) {
+	files := scenario.ParseReadFiles(out)
+	for _, path := range []string{"/sys/fs/cgroup/memory.current", "/sys/fs/cgroup/memory/memory.usage_in_bytes"} {
+		if v, err := strconv.ParseInt(files[path], 10, 64); err == nil {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func (p *Probe) Cleanup(context.Context, *scenario.Env) error {
+	if p.sess != nil {
+		p.sess.Close()
+	}
+	return nil
+}
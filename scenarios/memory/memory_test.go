@@ -0,0 +1,21 @@
+package memory
+
+import "testing"
+
+func TestMemoryUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want int64
+		ok   bool
+	}{
+		{name: "cgroup v2", out: "== /sys/fs/cgroup/memory.current\n52428800\n== /sys/fs/cgroup/memory.events\nlow 0\noom_kill 0\n", want: 52428800, ok: true},
+		{name: "cgroup v1", out: "== /sys/fs/cgroup/memory/memory.usage_in_bytes\n1048576\n== /sys/fs/cgroup/memory/memory.failcnt\n0\n", want: 1048576, ok: true},
+		{name: "missing", out: "== /sys/fs/cgroup/memory.events\noom_kill 1\n"},
+	}
+	for _, tt := range tests {
+		if got, ok := memoryUsage(tt.out); got != tt.want || ok != tt.ok {
+			t.Errorf("%s: memoryUsage = %d, %t; want %d, %t", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/client"
+
+	"test-docker/pkg/scenario"
+)
+
+// RestartName 为重复 OOM 下重启策略场景的注册名
+const RestartName = "memory-oom-restart"
+
+const (
+	restartMemoryBytes = 32 * scenario.MiB
+	// restartSettle 为重启次数不再变化、容器停在 exited 后的确认时间
+	restartSettle = 2 * time.Second
+	// restartTimeout 为有限重启策略等待最终状态的上限
+	restartTimeout = 90 * time.Second
+	// alwaysObserve 为无上限策略的观察窗口
+	alwaysObserve = 20 * time.Second
+)
+
+// RestartCase 为一种重启策略及其期望的重启次数
+type RestartCase struct {
+	Policy container.RestartPolicy
+	// Want 为期望的重启次数；Unbounded 为 true 时表示观察窗口内至少重启 Want 次
+	Want      int
+	Unbounded bool
+}
+
+func (c RestartCase) String() string {
+	if c.Policy.Name == container.RestartPolicyOnFailure {
+		return fmt.Sprintf("%s:%d", c.Policy.Name, c.Policy.MaximumRetryCount)
+	}
+	return string(c.Policy.Name)
+}
+
+// RestartOutcome 为一种策略的观测结果
+type RestartOutcome struct {
+	Case         RestartCase
+	RestartCount int
+	Status       container.ContainerState
+	OOMKilled    bool
+	ExitCode     int
+	OOMs         int
+	// Backoffs 为每次 die 到下一次 start 的间隔
+	Backoffs []time.Duration
+}
+
+func init() {
+	scenario.Register(&Restart{Cases: []RestartCase{
+		{Policy: container.RestartPolicy{Name: container.RestartPolicyDisabled}, Want: 0},
+		{Policy: container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 1}, Want: 1},
+		{Policy: container.RestartPolicy{Name: container.RestartPolicyOnFailure, MaximumRetryCount: 3}, Want: 3},
+		{Policy: container.RestartPolicy{Name: container.RestartPolicyAlways}, Want: 3, Unbounded: true},
+	}})
+}
+
+// Restart 以不同的 RestartPolicy 运行必然 OOM 的负载，通过 inspect 跟踪 RestartCount 与最终状态，
+// 通过 die/start 事件计算重启退避间隔，核对重启次数是否符合策略
+type Restart struct {
+	Cases []RestartCase
+
+	containers []string
+	outcomes   []RestartOutcome
+}
+
+func (*Restart) Name() string { return RestartName }
+
+func (r *Restart) Params() map[string]string {
+	cases := make([]string, len(r.Cases))
+	for i, c := range r.Cases {
+		cases[i] = c.String()
+	}
+	return map[string]string{
+		"memoryLimit": fmt.Sprintf("%dMiB", restartMemoryBytes/scenario.MiB),
+		"policies":    strings.Join(cases, ","),
+	}
+}
+
+func (*Restart) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: restartMemoryBytes, Containers: 1}
+}
+
+func (*Restart) Prepare(ctx context.Context, env *scenario.Env) error {
+	return scenario.PullImage(ctx, env.Client, scenario.ProbeImage)
+}
+
+func (r *Restart) Run(ctx context.Context, env *scenario.Env) error {
+	r.outcomes = nil
+	for i, c := range r.Cases {
+		env.Report.Mark(time.Now(), "phase", "重启策略 %s", c)
+		outcome, err := r.observe(ctx, env, i, c)
+		if err != nil {
+			return fmt.Errorf("重启策略 %s: %w", c, err)
+		}
+		r.outcomes = append(r.outcomes, outcome)
+	}
+	return nil
+}
+
+// observe 启动一个 OOM 负载容器并轮询 inspect，直到重启次数稳定且容器停止，或无上限策略的观察窗口结束。
+// 容器可能在启动后立即被 OOM 杀死，因此不使用要求启动后处于运行状态的 Session；
+// start 事件不在报告的监听范围内，创建后、启动前单独订阅该容器的 start/die/oom 事件。订阅是异步建立的，
+// 而负载在启动后几毫秒内就会被 OOM 杀死，因此以 daemon 记录的创建时间作为 Since 回放，避免漏掉最初的事件
+func (r *Restart) observe(ctx context.Context, env *scenario.Env, i int, c RestartCase) (RestartOutcome, error) {
+	outcome := RestartOutcome{Case: c}
+	cfg := &container.Config{
+		Image: scenario.ProbeImage,
+		Cmd:   []string{"tail", "/dev/zero"},
+	}
+	scenario.LabelForRun(ctx, cfg)
+	created, err := env.Client.ContainerCreate(ctx, client.ContainerCreateOptions{
+		Config: cfg,
+		HostConfig: &container.HostConfig{
+			RestartPolicy: c.Policy,
+			Resources: container.Resources{
+				Memory:     restartMemoryBytes,
+				MemorySwap: restartMemoryBytes,
+			},
+		},
+		Name: fmt.Sprintf("oom-restart-%d-%s", i, time.Now().Format("150405")),
+	})
+	if err != nil {
+		return outcome, fmt.Errorf("创建容器失败: %w", err)
+	}
+	r.containers = append(r.containers, created.ID)
+
+	inspect, err := env.Client.ContainerInspect(ctx, created.ID, client.ContainerInspectOptions{})
+	if err != nil {
+		return outcome, fmt.Errorf("查询容器状态失败: %w", err)
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, inspect.Container.Created)
+	if err != nil {
+		return outcome, fmt.Errorf("解析容器创建时间 %q 失败: %w", inspect.Container.Created, err)
+	}
+	monitor := scenario.WatchContainerEvents(ctx, env.Client, created.ID, createdAt, events.ActionStart, events.ActionDie, events.ActionOOM)
+	if _, err := env.Client.ContainerStart(ctx, created.ID, client.ContainerStartOptions{}); err != nil {
+		monitor.Stop()
+		return outcome, fmt.Errorf("启动容器失败: %w", err)
+	}
+
+	start := time.Now()
+	deadline := start.Add(restartTimeout)
+	if c.Unbounded {
+		deadline = start.Add(alwaysObserve)
+	}
+	var stableSince time.Time
+	for {
+		inspect, err := env.Client.ContainerInspect(ctx, created.ID, client.ContainerInspectOptions{})
+		if err != nil {
+			monitor.Stop()
+			return outcome, fmt.Errorf("查询容器状态失败: %w", err)
+		}
+		state := inspect.Container.State
+		if inspect.Container.RestartCount != outcome.RestartCount || state.Status != outcome.Status {
+			log.Printf("%s：状态 %s，RestartCount=%d，OOMKilled=%v", c, state.Status, inspect.Container.RestartCount, state.OOMKilled)
+			stableSince = time.Now()
+		}
+		outcome.RestartCount = inspect.Container.RestartCount
+		outcome.Status = state.Status
+		outcome.OOMKilled = state.OOMKilled
+		outcome.ExitCode = state.ExitCode
+
+		now := time.Now()
+		if !c.Unbounded && state.Status == container.StateExited && now.Sub(stableSince) >= restartSettle {
+			break
+		}
+		if now.After(deadline) {
+			if !c.Unbounded {
+				monitor.Stop()
+				return outcome, fmt.Errorf("%s 内容器未停止，最后状态 %s", restartTimeout, state.Status)
+			}
+			break
+		}
+		select {
+		case <-ctx.Done():
+			monitor.Stop()
+			return outcome, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// Stop 会等待稍晚于 inspect 到达的事件
+	evts, err := monitor.Stop()
+	if err != nil {
+		return outcome, fmt.Errorf("监听容器事件失败: %w", err)
+	}
+	slices.SortStableFunc(evts, func(a, b scenario.Event) int { return a.At.Compare(b.At) })
+	outcome.Backoffs = restartBackoffs(evts)
+	for _, e := range evts {
+		if e.Action == "oom" {
+			outcome.OOMs++
+		}
+	}
+	return outcome, nil
+}
+
+func (r *Restart) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems, parts []string
+	for _, o := range r.outcomes {
+		backoffs := make([]string, len(o.Backoffs))
+		for i, b := range o.Backoffs {
+			backoffs[i] = b.Round(time.Millisecond).String()
+		}
+		log.Printf("%-14s RestartCount=%d 最终状态=%s 退出码=%d OOMKilled=%v oom 事件=%d 退避=[%s]",
+			o.Case, o.RestartCount, o.Status, o.ExitCode, o.OOMKilled, o.OOMs, strings.Join(backoffs, " "))
+		parts = append(parts, fmt.Sprintf("%s 重启 %d 次", o.Case, o.RestartCount))
+
+		switch {
+		case o.Case.Unbounded && o.RestartCount < o.Case.Want:
+			problems = append(problems, fmt.Sprintf("%s 在 %s 内只重启 %d 次，期望至少 %d 次", o.Case, alwaysObserve, o.RestartCount, o.Case.Want))
+		case !o.Case.Unbounded && o.RestartCount != o.Case.Want:
+			problems = append(problems, fmt.Sprintf("%s 重启 %d 次，期望 %d 次", o.Case, o.RestartCount, o.Case.Want))
+		}
+		if !o.Case.Unbounded && !o.OOMKilled {
+			problems = append(problems, fmt.Sprintf("%s 最终状态未标记 OOMKilled（退出码 %d）", o.Case, o.ExitCode))
+		}
+		if len(o.Backoffs) < min(o.RestartCount, o.Case.Want) {
+			problems = append(problems, fmt.Sprintf("%s 只从事件中得到 %d 个退避间隔", o.Case, len(o.Backoffs)))
+		}
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return strings.Join(parts, "，") + "，均符合策略", nil
+}
+
+func (r *Restart) Cleanup(ctx context.Context, env *scenario.Env) error {
+	var errs []error
+	for _, id := range r.containers {
+		if _, err := env.Client.ContainerRemove(ctx, id, client.ContainerRemoveOptions{Force: true}); err != nil {
+			errs = append(errs, fmt.Errorf("删除容器 %s 失败: %w", id, err))
+		}
+	}
+	r.containers = nil
+	return errors.Join(errs...)
+}
+
+// restartBackoffs 根据按时间排序的事件计算每次 die 到下一次 start 的间隔
+func restartBackoffs(evts []scenario.Event) []time.Duration {
+	var backoffs []time.Duration
+	var died time.Time
+	for _, e := range evts {
+		switch e.Action {
+		case "die":
+			died = e.At
+		case "start":
+			if !died.IsZero() {
+				backoffs = append(backoffs, e.At.Sub(died))
+				died = time.Time{}
+			}
+		}
+	}
+	return backoffs
+}
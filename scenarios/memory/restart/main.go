@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/memory"
+)
+
+func main() {
+	scenario.Main(memory.RestartName)
+}
@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"test-docker/pkg/scenario"
+)
+
+func TestRestartBackoffs(t *testing.T) {
+	t0 := time.Now()
+	at := func(ms int) time.Time { return t0.Add(time.Duration(ms) * time.Millisecond) }
+	evts := []scenario.Event{
+		{At: at(0), Action: "start"},
+		{At: at(300), Action: "oom"},
+		{At: at(310), Action: "die"},
+		{At: at(420), Action: "start"},
+		{At: at(700), Action: "oom"},
+		{At: at(710), Action: "die"},
+		{At: at(920), Action: "start"},
+		{At: at(1200), Action: "die"},
+	}
+	want := []time.Duration{110 * time.Millisecond, 210 * time.Millisecond}
+	if got := restartBackoffs(evts); !slices.Equal(got, want) {
+		t.Errorf("restartBackoffs = %v, want %v", got, want)
+	}
+}
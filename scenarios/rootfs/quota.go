@@ -0,0 +1,198 @@
+package rootfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+
+	"test-docker/pkg/scenario"
+)
+
+// 软限额场景的注册名，分别以 stop 与 kill 处置
+const (
+	QuotaStopName = "rootfs-quota-stop"
+	QuotaKillName = "rootfs-quota-kill"
+)
+
+// QuotaNames 为全部可写层软限额场景
+var QuotaNames = []string{QuotaStopName, QuotaKillName}
+
+const (
+	quotaWarnBytes  = 192 * scenario.MiB
+	quotaLimitBytes = 256 * scenario.MiB
+	quotaInterval   = 500 * time.Millisecond
+	// quotaFillMiB 为写入目标，远超软限额；软限额未生效时写入会完成并以 0 退出
+	quotaFillMiB = 1024
+	quotaChunk   = 8
+	quotaTimeout = 3 * time.Minute
+)
+
+// quotaFillScript 以 8MiB 为单位分文件写入可写层，每块之间短暂休眠，控制轮询间隔内的写入量；
+// dd 失败（宿主机磁盘写满）时以 55 退出
+const quotaFillScript = `i=0
+while [ $((i * %[2]d)) -lt %[1]d ]; do
+  dd if=/dev/zero of=/root/quota-fill-$i.bin bs=1M count=%[2]d 2>/dev/null || exit 55
+  i=$((i + 1))
+  echo "已写入=$((i * %[2]d))MiB"
+  sleep 0.2
+done
+echo 写入完成`
+
+func init() {
+	for _, action := range []string{scenario.QuotaActionStop, scenario.QuotaActionKill} {
+		scenario.Register(&Quota{Quota: scenario.RootfsQuota{
+			Warn:        quotaWarnBytes,
+			Limit:       quotaLimitBytes,
+			Action:      action,
+			Interval:    quotaInterval,
+			StopTimeout: 2,
+		}})
+	}
+}
+
+// Quota 在不设置 StorageOpt 的容器内持续写入可写层，由 scenario.QuotaEnforcer 轮询 SizeRw
+// 执行软限额：超过告警线时告警，超过限额时停止或杀死容器。适用于 overlay2 未启用 pquota 的宿主机
+type Quota struct {
+	Quota scenario.RootfsQuota
+
+	driver  string
+	sess    *scenario.Session
+	outcome scenario.QuotaOutcome
+	final   container.State
+	sizeRw  int64
+}
+
+func (q *Quota) Name() string {
+	if q.Quota.Action == scenario.QuotaActionKill {
+		return QuotaKillName
+	}
+	return QuotaStopName
+}
+
+func (q *Quota) Params() map[string]string {
+	return map[string]string{
+		"warn":     fmt.Sprintf("%dMiB", q.Quota.Warn/scenario.MiB),
+		"limit":    fmt.Sprintf("%dMiB", q.Quota.Limit/scenario.MiB),
+		"action":   q.Quota.Action,
+		"interval": q.Quota.Interval.String(),
+		"fill":     fmt.Sprintf("%dMiB", quotaFillMiB),
+	}
+}
+
+// Footprint 按软限额失效、写入全部完成的最坏情况估计磁盘占用
+func (*Quota) Footprint() scenario.Footprint {
+	return scenario.Footprint{Disk: quotaFillMiB * scenario.MiB, Containers: 1}
+}
+
+func (q *Quota) Prepare(ctx context.Context, env *scenario.Env) error {
+	if q.Quota.Action != scenario.QuotaActionStop && q.Quota.Action != scenario.QuotaActionKill {
+		return fmt.Errorf("未知的处置方式 %q", q.Quota.Action)
+	}
+	if q.Quota.Warn >= q.Quota.Limit {
+		return errors.New("告警线需要低于软限额")
+	}
+	if err := scenario.PullImage(ctx, env.Client, demoImage); err != nil {
+		return fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	info, err := env.Client.Info(ctx, client.InfoOptions{})
+	if err != nil {
+		return fmt.Errorf("查询 daemon Info 失败: %w", err)
+	}
+	q.driver = info.Info.Driver
+	env.Report.Mark(time.Now(), "phase", "存储驱动 %s，以软限额代替 StorageOpt size", q.driver)
+	return nil
+}
+
+func (q *Quota) Run(ctx context.Context, env *scenario.Env) error {
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: demoImage,
+		Cmd:   []string{"sh", "-c", fmt.Sprintf(quotaFillScript, quotaFillMiB, quotaChunk)},
+	}, &container.HostConfig{}, "rootfs-quota-"+q.Quota.Action)
+	if err != nil {
+		return fmt.Errorf("启动写入容器失败: %w", err)
+	}
+	q.sess = sess
+
+	enforcer := scenario.StartQuotaEnforcer(ctx, env.Client, sess.ID, sess.Name, q.Quota)
+	waitCtx, cancel := context.WithTimeout(ctx, quotaTimeout)
+	waitErr := enforcer.Wait(waitCtx)
+	cancel()
+	q.outcome = enforcer.Stop()
+	env.Report.AddSeries(&scenario.Series{
+		Name:   "可写层 SizeRw",
+		Unit:   "MiB",
+		Limit:  float64(q.Quota.Limit) / scenario.MiB,
+		Points: q.outcome.Samples,
+	})
+	if waitErr != nil {
+		return fmt.Errorf("%s 内容器既未被处置也未退出: %w", quotaTimeout, waitErr)
+	}
+
+	// stop 返回时容器已退出；kill 只发送信号，等待容器真正停止
+	wait := env.Client.ContainerWait(ctx, sess.ID, client.ContainerWaitOptions{Condition: container.WaitConditionNotRunning})
+	select {
+	case err := <-wait.Error:
+		return fmt.Errorf("等待容器退出失败: %w", err)
+	case <-wait.Result:
+	}
+	inspect, err := env.Client.ContainerInspect(ctx, sess.ID, client.ContainerInspectOptions{Size: true})
+	if err != nil {
+		return fmt.Errorf("查询容器状态失败: %w", err)
+	}
+	if inspect.Container.State != nil {
+		q.final = *inspect.Container.State
+	}
+	if inspect.Container.SizeRw != nil {
+		q.sizeRw = *inspect.Container.SizeRw
+	}
+	return nil
+}
+
+func (q *Quota) Verify(context.Context, *scenario.Env) (string, error) {
+	o := q.outcome
+	log.Printf("存储驱动 %s；告警线 %dMiB，软限额 %dMiB，轮询 %d 次，峰值 %dMiB",
+		q.driver, q.Quota.Warn/scenario.MiB, q.Quota.Limit/scenario.MiB, len(o.Samples), o.Peak/scenario.MiB)
+	log.Printf("容器最终状态 %s，退出码 %d，可写层 %dMiB", q.final.Status, q.final.ExitCode, q.sizeRw/scenario.MiB)
+
+	var problems []string
+	if o.Err != nil {
+		problems = append(problems, o.Err.Error())
+	}
+	switch {
+	case o.EnforcedAt.IsZero():
+		problems = append(problems, "可写层未触发软限额处置")
+	case o.WarnedAt.IsZero():
+		problems = append(problems, "处置之前没有告警")
+	}
+	if q.final.Status != container.StateExited {
+		problems = append(problems, fmt.Sprintf("容器最终状态为 %s", q.final.Status))
+	}
+	switch q.final.ExitCode {
+	case 0:
+		problems = append(problems, fmt.Sprintf("写入 %dMiB 全部完成，软限额没有阻止写入", quotaFillMiB))
+	case 55:
+		problems = append(problems, "宿主机磁盘先于软限额写满")
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+
+	over := o.EnforcedSize - q.Quota.Limit
+	return fmt.Sprintf("可写层 %dMiB 时告警，%dMiB 时执行 %s（超出软限额 %dMiB，距告警 %s），容器退出码 %d，最终可写层 %dMiB",
+		o.WarnSize/scenario.MiB, o.EnforcedSize/scenario.MiB, q.Quota.Action, over/scenario.MiB,
+		o.EnforcedAt.Sub(o.WarnedAt).Round(time.Millisecond), q.final.ExitCode, q.sizeRw/scenario.MiB), nil
+}
+
+func (q *Quota) Cleanup(context.Context, *scenario.Env) error {
+	if q.sess != nil {
+		q.sess.Close()
+		q.sess = nil
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/rootfs"
+)
+
+func main() {
+	scenario.Main(rootfs.QuotaNames...)
+}
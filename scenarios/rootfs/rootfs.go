@@ -0,0 +1,129 @@
+// Package rootfs 为系统盘限额探测场景：通过 StorageOpt size 限制可写层后写入数据并观察 df，超出限额的写入应以 ENOSPC 失败
+package rootfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// Name 为系统盘探测场景的注册名
+const Name = "rootfs-probe"
+
+const (
+	demoImage        = "docker.io/library/alpine"
+	rootFsLimitBytes = 128
+	fillMiB          = 64
+)
+
+func init() {
+	scenario.Register(&Probe{})
+}
+
+// Probe 在 128M 可写层限额的常驻容器内写入 64 MiB，再尝试写入与限额等量的数据触发 ENOSPC，前后各读取一次 df
+type Probe struct {
+	sess    *scenario.Session
+	results []scenario.ProbeResult
+}
+
+func (*Probe) Name() string { return Name }
+
+func (*Probe) Params() map[string]string {
+	return map[string]string{
+		"rootfsLimit": fmt.Sprintf("%dM", rootFsLimitBytes),
+		"fill":        fmt.Sprintf("%dMiB", fillMiB),
+	}
+}
+
+func (*Probe) Footprint() scenario.Footprint {
+	return scenario.Footprint{Disk: rootFsLimitBytes * scenario.MiB, Containers: 1}
+}
+
+func (*Probe) Prepare(ctx context.Context, env *scenario.Env) error {
+	if err := scenario.PullImage(ctx, env.Client, demoImage); err != nil {
+		return fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	return nil
+}
+
+func (p *Probe) Run(ctx context.Context, env *scenario.Env) error {
+	// 设置 --storage-opt size=128M 限制可写层的大小
+	hostConfig := &container.HostConfig{}
+	hostConfig.StorageOpt = map[string]string{
+		"size": fmt.Sprintf("%dM", rootFsLimitBytes),
+	}
+	// 创建常驻容器
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: "alpine",
+		Tty:   false,
+		Cmd:   []string{"sleep", "3600"},
+	}, hostConfig, "test-ds")
+	if err != nil {
+		return fmt.Errorf("启动系统盘探测容器失败: %w", err)
+	}
+	p.sess = sess
+
+	results, err := sess.RunProbes(ctx, []scenario.ProbeStep{
+		scenario.ShellStep("df-before", "df -m /"),
+		scenario.ShellStep("write", fmt.Sprintf("dd if=/dev/zero of=/root/system-fill.bin bs=1M count=%d && sync", fillMiB)),
+		scenario.ShellStep("overfill", fmt.Sprintf("dd if=/dev/zero of=/root/overfill.bin bs=1M count=%d && sync", rootFsLimitBytes)),
+		scenario.ShellStep("df-after", "df -m /"),
+	})
+	for _, result := range results {
+		scenario.LogProbeResult(result)
+	}
+	p.results = results
+	if err != nil {
+		return fmt.Errorf("探测中断: %w", err)
+	}
+	return nil
+}
+
+// Verify 核对 df 看到的根文件系统容量不超过限额、限额内的写入成功，而超出限额的写入以 ENOSPC 失败
+func (p *Probe) Verify(context.Context, *scenario.Env) (string, error) {
+	dfBefore, write, overfill := p.results[0], p.results[1], p.results[2]
+	size, ok := dfSizeMiB(dfBefore.Stdout)
+	if !ok {
+		return "", errors.New("无法解析 df 输出")
+	}
+	if size > rootFsLimitBytes {
+		return "", fmt.Errorf("df 显示根文件系统 %dMiB，大于 %dM 限额，StorageOpt size 未生效", size, rootFsLimitBytes)
+	}
+	if write.ExitCode != 0 {
+		return "", fmt.Errorf("限额内写入 %dMiB 失败（退出码 %d）: %s", fillMiB, write.ExitCode, strings.TrimSpace(write.Stderr))
+	}
+	if overfill.ExitCode == 0 {
+		return "", fmt.Errorf("已写入 %dMiB 后再写入 %dMiB 仍然成功，可写层限额未生效", fillMiB, rootFsLimitBytes)
+	}
+	if !strings.Contains(overfill.Stderr, "No space left") {
+		return "", fmt.Errorf("超额写入退出码 %d，但不是 ENOSPC: %s", overfill.ExitCode, strings.TrimSpace(overfill.Stderr))
+	}
+	return fmt.Sprintf("根文件系统 %dMiB，写入 %dMiB 成功，超额写入以 ENOSPC 失败", size, fillMiB), nil
+}
+
+// dfSizeMiB 解析 df -m 输出第二行的容量列
+func dfSizeMiB(out string) (int64, bool) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) < 2 {
+		return 0, false
+	}
+	fields := strings.Fields(lines[1])
+	if len(fields) < 2 {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	return size, err == nil
+}
+
+func (p *Probe) Cleanup(context.Context, *scenario.Env) error {
+	if p.sess != nil {
+		p.sess.Close()
+	}
+	return nil
+}
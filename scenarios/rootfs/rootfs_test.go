@@ -0,0 +1,13 @@
+package rootfs
+
+import "testing"
+
+func TestDfSizeMiB(t *testing.T) {
+	out := "Filesystem           1M-blocks      Used Available Use% Mounted on\noverlay                    128         1       127   1% /\n"
+	if size, ok := dfSizeMiB(out); !ok || size != 128 {
+		t.Errorf("dfSizeMiB = %d, %t; want 128", size, ok)
+	}
+	if _, ok := dfSizeMiB("df: /: No such file or directory\n"); ok {
+		t.Error("dfSizeMiB without a data line: want false")
+	}
+}
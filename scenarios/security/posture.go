@@ -0,0 +1,205 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// 各配置的注册名
+const (
+	BaselineName   = "security-baseline"
+	CapDropName    = "security-capdrop"
+	ReadonlyName   = "security-readonly"
+	NoNewPrivsName = "security-no-new-privileges"
+	SeccompName    = "security-seccomp"
+	UserName       = "security-user"
+)
+
+// Names 为全部安全基线场景，按注册顺序排列
+var Names = []string{BaselineName, CapDropName, ReadonlyName, NoNewPrivsName, SeccompName, UserName}
+
+func init() {
+	// with 在默认配置（root 用户、默认 capability 与 seccomp，只有 mount 被拒绝）的基础上追加被拒绝的操作
+	with := func(denied ...string) map[string]bool {
+		m := map[string]bool{OpMount: true}
+		for _, op := range denied {
+			m[op] = true
+		}
+		return m
+	}
+	for _, p := range []*Posture{
+		{Config: BaselineName, Expect: with()},
+		{
+			Config: CapDropName,
+			Host:   container.HostConfig{CapDrop: []string{"ALL"}, CapAdd: []string{"CHOWN"}},
+			Expect: with(OpRawSock),
+		},
+		{
+			Config: ReadonlyName,
+			// /tmp 单独挂载可写 tmpfs，提权探测仍能准备 setuid 文件
+			Host:   container.HostConfig{ReadonlyRootfs: true, Tmpfs: map[string]string{"/tmp": "rw,exec,suid,mode=1777"}},
+			Expect: with(OpRootfs),
+		},
+		{
+			Config: NoNewPrivsName,
+			Host:   container.HostConfig{SecurityOpt: []string{"no-new-privileges:true"}},
+			Expect: with(OpSetuid),
+		},
+		{
+			Config: SeccompName,
+			Host:   container.HostConfig{SecurityOpt: []string{"seccomp=" + denySeccompChown}},
+			Expect: with(OpChown),
+		},
+		{
+			Config: UserName,
+			User:   unprivilegedUser,
+			Expect: with(OpChown, OpRawSock, OpRootfs),
+		},
+	} {
+		scenario.Register(p)
+	}
+}
+
+// Outcome 为一个敏感操作的探测结果
+type Outcome struct {
+	Op       string
+	Denied   bool
+	Expected bool
+	Detail   string
+}
+
+// Posture 以一种安全配置启动常驻容器，依次探测 Ops 中的敏感操作，
+// 与 Expect（操作 -> 是否应被拒绝）比较
+type Posture struct {
+	// Config 为配置名，同时作为场景的注册名
+	Config string
+	// Host 为被测的安全配置
+	Host container.HostConfig
+	// User 为容器的运行用户，为空时为镜像默认用户（root）
+	User string
+	// Expect 记录应被拒绝的操作，未列出的操作应当成功
+	Expect map[string]bool
+
+	sess     *scenario.Session
+	outcomes []Outcome
+}
+
+func (p *Posture) Name() string { return p.Config }
+
+func (p *Posture) Params() map[string]string {
+	params := map[string]string{"user": p.User, "readonlyRootfs": fmt.Sprint(p.Host.ReadonlyRootfs)}
+	if len(p.Host.CapDrop) > 0 {
+		params["capDrop"] = strings.Join(p.Host.CapDrop, ",")
+	}
+	if len(p.Host.CapAdd) > 0 {
+		params["capAdd"] = strings.Join(p.Host.CapAdd, ",")
+	}
+	var opts []string
+	for _, opt := range p.Host.SecurityOpt {
+		// seccomp 配置内容较长，只记录类型
+		if strings.HasPrefix(opt, "seccomp=") {
+			opt = "seccomp=custom"
+		}
+		opts = append(opts, opt)
+	}
+	if len(opts) > 0 {
+		params["securityOpt"] = strings.Join(opts, ",")
+	}
+	return params
+}
+
+func (*Posture) Prepare(ctx context.Context, env *scenario.Env) error {
+	return scenario.PullImage(ctx, env.Client, securityImage)
+}
+
+func (p *Posture) Run(ctx context.Context, env *scenario.Env) error {
+	host := p.Host
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: securityImage,
+		User:  p.User,
+	}, &host, p.Config)
+	if err != nil {
+		return fmt.Errorf("启动容器失败: %w", err)
+	}
+	p.sess = sess
+
+	p.outcomes = nil
+	for _, op := range Ops {
+		outcome, err := p.probe(ctx, op)
+		if err != nil {
+			return err
+		}
+		env.Report.Mark(time.Now(), "posture", "%s %s", op, verdict(outcome.Denied))
+		p.outcomes = append(p.outcomes, outcome)
+	}
+	return nil
+}
+
+// probe 执行一个操作的准备与探测步骤，准备失败时视为操作被拒绝并记录原因
+func (p *Posture) probe(ctx context.Context, op string) (Outcome, error) {
+	spec := opProbes[op]
+	outcome := Outcome{Op: op, Expected: p.Expect[op]}
+	if spec.Setup != nil {
+		setup, err := p.sess.Exec(ctx, *spec.Setup)
+		if err != nil {
+			return outcome, fmt.Errorf("探测 %s 的准备步骤中断: %w", op, err)
+		}
+		if setup.ExitCode != 0 {
+			outcome.Denied = true
+			outcome.Detail = "准备失败: " + firstLine(setup.Stderr)
+			return outcome, nil
+		}
+	}
+	result, err := p.sess.Exec(ctx, spec.Step)
+	if err != nil {
+		return outcome, fmt.Errorf("探测 %s 中断: %w", op, err)
+	}
+	outcome.Denied = result.ExitCode != 0
+	outcome.Detail = firstLine(result.Stderr)
+	return outcome, nil
+}
+
+func (p *Posture) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems, parts []string
+	log.Printf("安全基线 %s：", p.Config)
+	for _, o := range p.outcomes {
+		got, want := verdict(o.Denied), verdict(o.Expected)
+		log.Printf("  %-18s %s（预期 %s）%s", o.Op, got, want, o.Detail)
+		parts = append(parts, o.Op+"="+got)
+		if o.Denied != o.Expected {
+			problems = append(problems, fmt.Sprintf("%s 实际%s，预期%s", o.Op, got, want))
+		}
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return "安全基线符合预期：" + strings.Join(parts, " "), nil
+}
+
+func (p *Posture) Cleanup(context.Context, *scenario.Env) error {
+	if p.sess != nil {
+		p.sess.Close()
+		p.sess = nil
+	}
+	return nil
+}
+
+func verdict(denied bool) string {
+	if denied {
+		return "拒绝"
+	}
+	return "允许"
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/security"
+)
+
+func main() {
+	scenario.Main(security.Names...)
+}
@@ -0,0 +1,54 @@
+// Package security 为容器安全基线场景：分别应用 CapDrop/CapAdd、ReadonlyRootfs、no-new-privileges、
+// 自定义 seccomp 与非 root User，在容器内探测一组敏感操作，核对哪些被拒绝，每种配置输出一份基线报告
+package security
+
+import "test-docker/pkg/scenario"
+
+// securityImage 需要 Python 创建原始套接字并执行 setuid 调用
+const securityImage = "docker.io/library/python:3.12-alpine"
+
+// 探测的敏感操作
+const (
+	OpMount   = "mount"
+	OpChown   = "chown"
+	OpRawSock = "raw-socket"
+	OpRootfs  = "rootfs-write"
+	OpSetuid  = "setuid-escalation"
+)
+
+// Ops 为探测顺序
+var Ops = []string{OpMount, OpChown, OpRawSock, OpRootfs, OpSetuid}
+
+// suidPython 为探测提权时复制出的 setuid root 解释器
+const suidPython = "/tmp/suid-python"
+
+// unprivilegedUser 为提权探测使用的普通用户（nobody）
+const unprivilegedUser = "65534:65534"
+
+// opProbe 描述一个敏感操作的探测：Setup 以 root 执行准备工作，Step 以退出码 0 表示操作成功
+type opProbe struct {
+	Setup *scenario.ProbeStep
+	Step  scenario.ProbeStep
+}
+
+var opProbes = map[string]opProbe{
+	OpMount: {Step: scenario.ShellStep(OpMount, "mount -t tmpfs posture /mnt")},
+	OpChown: {Step: scenario.ShellStep(OpChown, `f=$(mktemp) && chown 1234:1234 "$f"`)},
+	OpRawSock: {Step: scenario.ProbeStep{Name: OpRawSock, Cmd: []string{"python3", "-c",
+		"import socket; socket.socket(socket.AF_INET, socket.SOCK_RAW, socket.IPPROTO_ICMP)"}}},
+	OpRootfs: {Step: scenario.ShellStep(OpRootfs, "touch /posture-probe")},
+	OpSetuid: {
+		Setup: &scenario.ProbeStep{Name: OpSetuid + "-setup", User: "0", Cmd: []string{"sh", "-c",
+			`cp "$(readlink -f "$(command -v python3)")" ` + suidPython + ` && chmod 4755 ` + suidPython}},
+		Step: scenario.ProbeStep{Name: OpSetuid, User: unprivilegedUser, Cmd: []string{suidPython, "-c",
+			"import os; os.setuid(0); assert os.geteuid() == 0"}},
+	},
+}
+
+// denySeccompChown 为自定义 seccomp 配置：默认放行，只拒绝 chown 一族系统调用
+const denySeccompChown = `{
+  "defaultAction": "SCMP_ACT_ALLOW",
+  "syscalls": [
+    {"names": ["chown", "fchown", "lchown", "fchownat"], "action": "SCMP_ACT_ERRNO"}
+  ]
+}`
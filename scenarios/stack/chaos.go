@@ -0,0 +1,233 @@
+package stack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// NetworkChaosName 为网络混沌场景的注册名
+const NetworkChaosName = "stack-chaos-network"
+
+const (
+	chaosMemory = 32 * scenario.MiB
+	// disconnectFor 与 pauseFor 为断开网络与暂停的持续时间
+	disconnectFor = 3 * time.Second
+	pauseFor      = 4 * time.Second
+	// probeDelay 为动作开始后等待多久再发起请求，确保扰动已经生效
+	probeDelay = time.Second
+	// requestTimeout 为单次 HTTP 请求的超时（秒），probeDelay 加上它要短于 pauseFor
+	requestTimeout = 2
+	// recoverTimeout 为恢复后等待请求重新成功的上限
+	recoverTimeout = 10 * time.Second
+)
+
+// chaosServerScript 写入首页后以 busybox httpd 在前台提供服务
+const chaosServerScript = `mkdir -p /www && echo ok > /www/index.html
+exec httpd -f -p ` + httpPort + ` -h /www`
+
+func init() {
+	scenario.Register(&NetworkChaos{})
+}
+
+// NetworkChaos 启动 server 与 client 两个成员，先把 client 从 Stack 的网络断开并按时重连，再单独暂停 server 后恢复。
+// 每个动作前、动作期间与恢复后都从 client 经网络别名请求 server：期望扰动前请求成功、扰动期间失败、
+// 恢复后在 recoverTimeout 内重新成功，动作本身执行成功且容器仍在运行
+type NetworkChaos struct {
+	stack  *scenario.Stack
+	phases []chaosPhase
+}
+
+// chaosPhase 记录一个动作前后 client 到 server 的连通性
+type chaosPhase struct {
+	Action scenario.ChaosResult
+	// Baseline 与 During 为扰动前、扰动期间的请求是否成功
+	Baseline bool
+	During   bool
+	// Recovered 为动作恢复后请求重新成功所用的时间，未恢复时为负
+	Recovered time.Duration
+}
+
+func (*NetworkChaos) Name() string { return NetworkChaosName }
+
+func (*NetworkChaos) Params() map[string]string {
+	return map[string]string{
+		"members": "server,client",
+		"actions": fmt.Sprintf("disconnect-client@%s,pause-server@%s", disconnectFor, pauseFor),
+	}
+}
+
+func (*NetworkChaos) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: 2 * chaosMemory, Containers: 2}
+}
+
+func (n *NetworkChaos) Prepare(ctx context.Context, env *scenario.Env) error {
+	if err := scenario.PullImage(ctx, env.Client, scenario.ProbeImage); err != nil {
+		return fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	host := *scenario.BuildHostConfig(container.Resources{NanoCPUs: nanoCPUs, Memory: chaosMemory}, 0, nil)
+	n.stack = &scenario.Stack{
+		Name: "stack-chaos",
+		Members: []scenario.StackMember{
+			{
+				Name:   "server",
+				Config: container.Config{Image: scenario.ProbeImage, Cmd: []string{"sh", "-c", chaosServerScript}},
+				Host:   host,
+				Ready:  ptr(scenario.ShellStep("server-ready", "wget -q -O /dev/null http://127.0.0.1:"+httpPort+"/index.html")),
+			},
+			{
+				Name:      "client",
+				Config:    container.Config{Image: scenario.ProbeImage},
+				Host:      host,
+				DependsOn: []string{"server"},
+				Ready:     ptr(requestStep("client-ready")),
+			},
+		},
+	}
+	return nil
+}
+
+// requestStep 从 client 经网络别名请求 server 的首页
+func requestStep(name string) scenario.ProbeStep {
+	return scenario.ShellStep(name, fmt.Sprintf("wget -q -T %d -O /dev/null http://server:%s/index.html", requestTimeout, httpPort))
+}
+
+func (n *NetworkChaos) Run(ctx context.Context, env *scenario.Env) error {
+	n.phases = nil
+	if err := n.stack.Up(ctx, env.Client); err != nil {
+		return err
+	}
+	for _, step := range []struct {
+		target string
+		action scenario.ChaosAction
+	}{
+		{"client", scenario.ChaosAction{Name: "disconnect-client", Kind: scenario.ChaosDisconnect, Network: n.stack.Name, Duration: disconnectFor}},
+		{"server", scenario.ChaosAction{Name: "pause-server", Kind: scenario.ChaosPause, Duration: pauseFor}},
+	} {
+		phase, err := n.disturb(ctx, env, n.stack.Session(step.target), step.action)
+		if err != nil {
+			return fmt.Errorf("%s: %w", step.action.Name, err)
+		}
+		n.phases = append(n.phases, phase)
+	}
+	return nil
+}
+
+// disturb 对 target 执行单个动作，并在动作前、probeDelay 之后与动作恢复后分别从 client 请求 server
+func (n *NetworkChaos) disturb(ctx context.Context, env *scenario.Env, target *scenario.Session, a scenario.ChaosAction) (chaosPhase, error) {
+	client := n.stack.Session("client")
+	var phase chaosPhase
+	var err error
+	if phase.Baseline, err = request(ctx, client, a.Name+"-before"); err != nil {
+		return phase, err
+	}
+	runner, err := scenario.StartChaos(ctx, env.Client, target.ID, scenario.ChaosPlan{Actions: []scenario.ChaosAction{a}})
+	if err != nil {
+		return phase, err
+	}
+	if err = sleep(ctx, probeDelay); err == nil {
+		phase.During, err = request(ctx, client, a.Name+"-during")
+	}
+	if err == nil {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-runner.Done():
+		}
+	}
+	results := runner.Stop()
+	if err != nil {
+		return phase, err
+	}
+	phase.Action = results[0]
+
+	start := time.Now()
+	phase.Recovered = -1
+	for time.Since(start) < recoverTimeout {
+		ok, err := request(ctx, client, a.Name+"-after")
+		if err != nil {
+			return phase, err
+		}
+		if ok {
+			phase.Recovered = time.Since(start)
+			break
+		}
+		if err := sleep(ctx, pollInterval); err != nil {
+			return phase, err
+		}
+	}
+	log.Printf("[%s] 扰动前请求成功=%t，扰动期间请求成功=%t，恢复用时 %s",
+		a.Name, phase.Baseline, phase.During, phase.Recovered.Round(time.Millisecond))
+	return phase, nil
+}
+
+// request 在 client 内请求一次 server，只有 exec 本身失败才返回 error
+func request(ctx context.Context, client *scenario.Session, name string) (bool, error) {
+	result, err := client.Exec(ctx, requestStep(name))
+	if err != nil {
+		return false, fmt.Errorf("在 client 内请求 server 失败: %w", err)
+	}
+	return result.ExitCode == 0, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+func (n *NetworkChaos) Verify(context.Context, *scenario.Env) (string, error) {
+	if len(n.phases) != 2 {
+		return "", fmt.Errorf("只完成了 %d 个动作", len(n.phases))
+	}
+	var problems []string
+	for _, p := range n.phases {
+		problems = append(problems, p.problems()...)
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("断开 client 期间请求失败，重连后 %s 恢复；暂停 server 期间请求超时，恢复后 %s 恢复",
+		n.phases[0].Recovered.Round(time.Millisecond), n.phases[1].Recovered.Round(time.Millisecond)), nil
+}
+
+// problems 返回一个动作不符合期望之处
+func (p chaosPhase) problems() []string {
+	name := p.Action.Name
+	var problems []string
+	if !p.Baseline {
+		problems = append(problems, name+"：扰动前请求已经失败")
+	}
+	switch {
+	case !p.Action.Fired:
+		problems = append(problems, name+"：动作未触发")
+	case p.Action.Error != "":
+		problems = append(problems, fmt.Sprintf("%s：动作执行失败: %s", name, p.Action.Error))
+	case p.Action.State != "running":
+		problems = append(problems, fmt.Sprintf("%s：动作完成后容器状态为 %s", name, p.Action.State))
+	}
+	if p.During {
+		problems = append(problems, name+"：扰动期间请求仍然成功，扰动没有生效")
+	}
+	if p.Recovered < 0 {
+		problems = append(problems, fmt.Sprintf("%s：恢复后 %s 内请求没有重新成功", name, recoverTimeout))
+	}
+	return problems
+}
+
+func (n *NetworkChaos) Cleanup(ctx context.Context, env *scenario.Env) error {
+	if n.stack == nil {
+		return nil
+	}
+	return n.stack.Down(ctx, env.Client)
+}
@@ -0,0 +1,38 @@
+package stack
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"test-docker/pkg/scenario"
+)
+
+func TestChaosPhaseProblems(t *testing.T) {
+	ok := chaosPhase{
+		Action:    scenario.ChaosResult{Name: "disconnect-client", Fired: true, State: "running"},
+		Baseline:  true,
+		Recovered: 300 * time.Millisecond,
+	}
+	cases := []struct {
+		name  string
+		phase func(*chaosPhase)
+		want  string
+	}{
+		{"通过", func(*chaosPhase) {}, ""},
+		{"扰动前失败", func(p *chaosPhase) { p.Baseline = false }, "扰动前请求已经失败"},
+		{"未触发", func(p *chaosPhase) { p.Action.Fired = false }, "动作未触发"},
+		{"执行失败", func(p *chaosPhase) { p.Action.Error = "no such network" }, "no such network"},
+		{"容器已退出", func(p *chaosPhase) { p.Action.State = "exited" }, "容器状态为 exited"},
+		{"扰动未生效", func(p *chaosPhase) { p.During = true }, "扰动没有生效"},
+		{"未恢复", func(p *chaosPhase) { p.Recovered = -1 }, "没有重新成功"},
+	}
+	for _, c := range cases {
+		p := ok
+		c.phase(&p)
+		got := strings.Join(p.problems(), "；")
+		if c.want == "" && got != "" || c.want != "" && !strings.Contains(got, c.want) {
+			t.Errorf("%s: problems = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
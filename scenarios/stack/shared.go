@@ -0,0 +1,247 @@
+// Package stack 为多容器场景：用 scenario.Stack 启动若干各自带资源限额的命名容器，共享 volume 与网络，
+// 按依赖顺序启动并等待就绪，在运行中写满共享卷或对成员施加断网、暂停等混沌动作，最后对每个成员分别断言
+package stack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+
+	"test-docker/pkg/scenario"
+)
+
+// SharedName 为共享卷写满场景的注册名
+const SharedName = "stack-shared-volume"
+
+const (
+	sharedVolume = "stack-shared-data"
+	volumeBytes  = 64 * scenario.MiB
+	dataPath     = "/data"
+	httpPort     = "8000"
+	chunkMiB     = 4
+	// 写入者的 tmpfs 页计入其内存 cgroup，限额需要高于卷的容量
+	writerMemory = 128 * scenario.MiB
+	serverMemory = 32 * scenario.MiB
+	readerMemory = 32 * scenario.MiB
+	nanoCPUs     = 500_000_000
+	// fillTimeout 为等待写入者写满卷的上限
+	fillTimeout  = 2 * time.Minute
+	pollInterval = 500 * time.Millisecond
+	// settle 为写满之后让读取者继续运行的时间
+	settle = 2 * time.Second
+)
+
+// serverScript 写入首页后以 busybox httpd 在前台提供卷内的文件
+const serverScript = `echo ok > ` + dataPath + `/index.html
+exec httpd -f -p ` + httpPort + ` -h ` + dataPath
+
+// readerScript 反复从本地挂载与 server 的 HTTP 两条路径读取全部分块，统计结果写入 /tmp/stats
+const readerScript = `local_ok=0; local_fail=0; net_ok=0; net_fail=0
+while :; do
+    for f in ` + dataPath + `/chunk-*; do
+        [ -e "$f" ] || continue
+        if cat "$f" > /dev/null; then local_ok=$((local_ok+1)); else local_fail=$((local_fail+1)); fi
+        if wget -q -O /dev/null "http://server:` + httpPort + `/${f##*/}"; then net_ok=$((net_ok+1)); else net_fail=$((net_fail+1)); fi
+    done
+    echo "local_ok=$local_ok local_fail=$local_fail net_ok=$net_ok net_fail=$net_fail" > /tmp/stats.tmp
+    mv /tmp/stats.tmp /tmp/stats
+    sleep 0.2
+done`
+
+// writerScript 按 4MiB 分块写入共享卷直到失败，把结论写入 /tmp/status 后保持运行以便断言
+const writerScript = `i=0
+while dd if=/dev/zero of=` + dataPath + `/chunk-$i bs=1M count=%d 2>/dev/null; do
+    i=$((i+1))
+    sleep 0.1
+done
+if [ "$(df -k -P ` + dataPath + ` | awk 'NR == 2 {print $4}')" -lt 1024 ]; then
+    echo "enospc chunks=$i" > /tmp/status
+else
+    echo "error chunks=$i" > /tmp/status
+fi
+exec sleep 3600`
+
+func init() {
+	scenario.Register(&Shared{})
+}
+
+// Shared 启动三个成员：server 以读写方式挂载共享卷，写入 index.html 后通过 HTTP 提供其中的文件，reader 经本地挂载与
+// server 两条路径反复读取，writer 向卷写入直到 ENOSPC。期望写满只影响 writer：reader 的两条读取路径
+// 始终成功，server 在卷写满后仍能响应
+type Shared struct {
+	stack *scenario.Stack
+	// status 为 writer 写满后的结论
+	status string
+}
+
+func (s *Shared) Name() string { return SharedName }
+
+func (s *Shared) Params() map[string]string {
+	return map[string]string{
+		"volume":  fmt.Sprintf("%dMiB", volumeBytes/scenario.MiB),
+		"members": "server,reader,writer",
+		"chunk":   fmt.Sprintf("%dMiB", chunkMiB),
+	}
+}
+
+func (s *Shared) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: serverMemory + readerMemory + writerMemory, Tmpfs: volumeBytes, Containers: 3}
+}
+
+func (s *Shared) Prepare(ctx context.Context, env *scenario.Env) error {
+	if err := scenario.PullImage(ctx, env.Client, scenario.ProbeImage); err != nil {
+		return fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	s.stack = &scenario.Stack{
+		Name:    "stack-shared",
+		Volumes: []scenario.StackVolume{{Name: sharedVolume, TmpfsSize: volumeBytes}},
+		Members: []scenario.StackMember{
+			{
+				Name:   "server",
+				Config: container.Config{Image: scenario.ProbeImage, Cmd: []string{"sh", "-c", serverScript}},
+				Host:   member(serverMemory, scenario.VolumeMount(sharedVolume, dataPath, false)),
+				Ready:  ptr(scenario.ShellStep("server-ready", "wget -q -O /dev/null http://127.0.0.1:"+httpPort+"/index.html")),
+			},
+			{
+				Name:      "reader",
+				Config:    container.Config{Image: scenario.ProbeImage, Cmd: []string{"sh", "-c", readerScript}},
+				Host:      member(readerMemory, scenario.VolumeMount(sharedVolume, dataPath, true)),
+				DependsOn: []string{"server"},
+				Ready:     ptr(scenario.ShellStep("reader-ready", "test -s /tmp/stats")),
+			},
+			{
+				Name:      "writer",
+				Config:    container.Config{Image: scenario.ProbeImage, Cmd: []string{"sh", "-c", fmt.Sprintf(writerScript, chunkMiB)}},
+				Host:      member(writerMemory, scenario.VolumeMount(sharedVolume, dataPath, false)),
+				DependsOn: []string{"reader"},
+			},
+		},
+	}
+	return nil
+}
+
+func member(memory int64, m mount.Mount) container.HostConfig {
+	return *scenario.BuildHostConfig(container.Resources{NanoCPUs: nanoCPUs, Memory: memory}, 0, []mount.Mount{m})
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func (s *Shared) Run(ctx context.Context, env *scenario.Env) error {
+	s.status = ""
+	if err := s.stack.Up(ctx, env.Client); err != nil {
+		return err
+	}
+	writer := s.stack.Session("writer")
+	deadline := time.Now().Add(fillTimeout)
+	for s.status == "" {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("writer 在 %s 内没有写满共享卷", fillTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+		result, err := writer.Exec(ctx, scenario.ShellStep("writer-status", "cat /tmp/status 2>/dev/null"))
+		if err != nil {
+			return fmt.Errorf("读取 writer 状态失败: %w", err)
+		}
+		s.status = strings.TrimSpace(result.Stdout)
+	}
+	env.Report.Mark(time.Now(), "phase", "writer 结束写入：%s", s.status)
+	log.Printf("writer 结束写入：%s，reader 继续运行 %s", s.status, settle)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(settle):
+	}
+	return nil
+}
+
+func (s *Shared) Verify(ctx context.Context, env *scenario.Env) (string, error) {
+	results, err := s.stack.Check(ctx, []scenario.MemberCheck{
+		{
+			Member: "writer",
+			Name:   "写满共享卷后遇到 ENOSPC",
+			Step:   scenario.ShellStep("writer-status", "cat /tmp/status"),
+			Want: func(r scenario.ProbeResult) error {
+				if !strings.HasPrefix(r.Stdout, "enospc") {
+					return fmt.Errorf("写入因其他原因失败: %s", strings.TrimSpace(r.Stdout))
+				}
+				return nil
+			},
+		},
+		{
+			Member: "reader",
+			Name:   "本地与 HTTP 读取全部成功",
+			Step:   scenario.ShellStep("reader-stats", "cat /tmp/stats"),
+			Want:   wantReaderStats,
+		},
+		{
+			Member: "server",
+			Name:   "卷写满后仍能响应",
+			Step:   scenario.ShellStep("server-alive", "wget -q -O - http://127.0.0.1:"+httpPort+"/index.html"),
+			Want: func(r scenario.ProbeResult) error {
+				if r.ExitCode != 0 {
+					return fmt.Errorf("请求失败（退出码 %d）: %s", r.ExitCode, strings.TrimSpace(r.Stderr))
+				}
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	var problems []string
+	for _, r := range results {
+		if r.Err != nil {
+			problems = append(problems, fmt.Sprintf("%s %s：%v", r.Member, r.Name, r.Err))
+		}
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("writer 写满 %dMiB 共享卷（%s），reader 与 server 不受影响（%s）",
+		volumeBytes/scenario.MiB, s.status, results[1].Output), nil
+}
+
+// wantReaderStats 要求 reader 的两条读取路径都至少成功过一次且没有失败
+func wantReaderStats(r scenario.ProbeResult) error {
+	stats := parseStats(r.Stdout)
+	switch {
+	case stats["local_fail"] > 0 || stats["net_fail"] > 0:
+		return fmt.Errorf("读取失败 本地 %d 次、HTTP %d 次", stats["local_fail"], stats["net_fail"])
+	case stats["local_ok"] == 0 || stats["net_ok"] == 0:
+		return errors.New("没有读到任何分块")
+	}
+	return nil
+}
+
+// parseStats 解析 "key=value ..." 形式的计数
+func parseStats(out string) map[string]int {
+	stats := map[string]int{}
+	for _, field := range strings.Fields(out) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			stats[key] = n
+		}
+	}
+	return stats
+}
+
+func (s *Shared) Cleanup(ctx context.Context, env *scenario.Env) error {
+	if s.stack == nil {
+		return nil
+	}
+	return s.stack.Down(ctx, env.Client)
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/stack"
+)
+
+func main() {
+	scenario.Main(stack.SharedName)
+}
@@ -0,0 +1,25 @@
+package stack
+
+import (
+	"testing"
+
+	"test-docker/pkg/scenario"
+)
+
+func TestWantReaderStats(t *testing.T) {
+	cases := []struct {
+		out string
+		ok  bool
+	}{
+		{"local_ok=12 local_fail=0 net_ok=12 net_fail=0\n", true},
+		{"local_ok=12 local_fail=0 net_ok=11 net_fail=1\n", false},
+		{"local_ok=0 local_fail=0 net_ok=0 net_fail=0\n", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		err := wantReaderStats(scenario.ProbeResult{Stdout: c.out})
+		if (err == nil) != c.ok {
+			t.Errorf("wantReaderStats(%q) = %v, want ok=%v", c.out, err, c.ok)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/update"
+)
+
+func main() {
+	scenario.Main(update.LiveName)
+}
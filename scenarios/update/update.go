@@ -0,0 +1,158 @@
+// Package update 为运行中调整限额的场景：负载运行期间按时间线调用 ContainerUpdate，核对 cgroup 并观察行为变化
+package update
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// LiveName 为运行中调整限额场景的注册名
+const LiveName = "live-update"
+
+const (
+	demoImage        = scenario.ProbeImage
+	memoryLimitBytes = 64 * scenario.MiB
+	raisedMemory     = 192 * scenario.MiB
+	cpuLimitNano     = 1_000_000_000
+	loweredCPUNano   = 500_000_000
+	pidsLimit        = 64
+	loweredPids      = 32
+	shmSizeBytes     = 256 * scenario.MiB
+	fillTargetMiB    = 96
+	observeFor       = 15 * time.Second
+)
+
+// workloadScript 启动两个忙循环压满 CPU，同时每秒向 /dev/shm 写入 8 MiB，
+// 写入量最终超过初始内存限额，只有在中途上调内存后才能全部完成
+const workloadScript = `for i in 1 2; do while :; do :; done & done
+used=0
+while [ $used -lt %d ]; do
+    if ! dd if=/dev/zero of=/dev/shm/fill-$used bs=1M count=8 2>/dev/null; then
+        echo "写入失败：已分配=${used}MiB" >&2
+    fi
+    used=$((used+8))
+    echo "已分配=${used}MiB"
+    sleep 1
+done
+while :; do sleep 3600; done`
+
+func init() {
+	scenario.Register(&Live{})
+}
+
+// Live 在负载运行中上调内存、下调 CPU 配额并收紧 pids
+type Live struct {
+	sess    *scenario.Session
+	updates []scenario.UpdateResult
+}
+
+func (*Live) Name() string { return LiveName }
+
+func (*Live) Params() map[string]string {
+	return map[string]string{
+		"memoryLimit": fmt.Sprintf("%dMiB->%dMiB", memoryLimitBytes/scenario.MiB, raisedMemory/scenario.MiB),
+		"cpuLimit":    fmt.Sprintf("%.1f->%.1f", float64(cpuLimitNano)/1e9, float64(loweredCPUNano)/1e9),
+		"pidsLimit":   fmt.Sprintf("%d->%d", pidsLimit, loweredPids),
+	}
+}
+
+func (*Live) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: raisedMemory, Tmpfs: shmSizeBytes, Containers: 1}
+}
+
+func (*Live) Prepare(ctx context.Context, env *scenario.Env) error {
+	return scenario.PullImage(ctx, env.Client, demoImage)
+}
+
+func (l *Live) Run(ctx context.Context, env *scenario.Env) error {
+	pids := int64(pidsLimit)
+	sess, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: demoImage,
+		Cmd:   []string{"sh", "-c", fmt.Sprintf(workloadScript, fillTargetMiB)},
+	}, &container.HostConfig{
+		ShmSize: shmSizeBytes,
+		Resources: container.Resources{
+			Memory:     memoryLimitBytes,
+			MemorySwap: memoryLimitBytes,
+			NanoCPUs:   cpuLimitNano,
+			PidsLimit:  &pids,
+		},
+	}, "live-update")
+	if err != nil {
+		return fmt.Errorf("启动负载容器失败: %w", err)
+	}
+	l.sess = sess
+	start := time.Now()
+
+	// 在内存写满初始限额之前上调内存，随后在 CPU 满载时下调配额，最后收紧 pids
+	lowered := int64(loweredPids)
+	updates, err := sess.ApplyTimeline(ctx, start, []scenario.LimitUpdate{
+		{Name: "raise-memory", After: 3 * time.Second, Resources: container.Resources{Memory: raisedMemory, MemorySwap: raisedMemory}},
+		{Name: "lower-cpu", After: 7 * time.Second, Resources: container.Resources{NanoCPUs: loweredCPUNano}},
+		{Name: "lower-pids", After: 11 * time.Second, Resources: container.Resources{PidsLimit: &lowered}},
+	})
+	for _, u := range updates {
+		scenario.LogUpdateResult(u)
+	}
+	l.updates = updates
+	if err != nil {
+		return fmt.Errorf("调整限额中断: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(start.Add(observeFor))):
+	}
+	return nil
+}
+
+func (l *Live) Verify(ctx context.Context, env *scenario.Env) (string, error) {
+	final, err := l.sess.RunProbes(ctx, []scenario.ProbeStep{
+		scenario.ShellStep("shm-usage", "du -sm /dev/shm | cut -f1"),
+		scenario.ReadFilesStep("memory-events", "/sys/fs/cgroup/memory.events", "/sys/fs/cgroup/memory/memory.oom_control"),
+	})
+	for _, result := range final {
+		scenario.LogProbeResult(result)
+	}
+	if err != nil {
+		return "", fmt.Errorf("探测中断: %w", err)
+	}
+
+	var problems []string
+	for _, u := range l.updates {
+		problems = append(problems, u.Mismatches...)
+	}
+	filled, _ := strconv.Atoi(strings.TrimSpace(final[0].Stdout))
+	if filled < fillTargetMiB {
+		problems = append(problems, fmt.Sprintf("上调内存后 /dev/shm 只写入了 %dMiB，预期 %dMiB", filled, fillTargetMiB))
+	}
+	cpuAt := l.updates[1].At
+	before := env.Report.MeanCPU(l.sess.ID, cpuAt.Add(-3*time.Second), cpuAt)
+	after := env.Report.MeanCPU(l.sess.ID, cpuAt.Add(time.Second), l.updates[2].At)
+	log.Printf("下调 CPU 前平均 %.2f vCPU，下调后平均 %.2f vCPU", before, after)
+	if before == 0 || after > before*0.75 {
+		problems = append(problems, fmt.Sprintf("下调 CPU 后用量未明显下降：%.2f -> %.2f vCPU", before, after))
+	}
+
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("3 次调整均已写入 cgroup，上调内存后写满 %dMiB，CPU 由 %.2f 降至 %.2f vCPU", filled, before, after), nil
+}
+
+func (l *Live) Cleanup(context.Context, *scenario.Env) error {
+	if l.sess != nil {
+		l.sess.Close()
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+
+	"test-docker/pkg/scenario"
+)
+
+// 各混沌计划对应的注册名
+const (
+	ChaosKillName   = "volume-chaos-kill"
+	ChaosStopName   = "volume-chaos-stop"
+	ChaosDetachName = "volume-chaos-detach"
+)
+
+// ChaosNames 为全部卷写入混沌场景
+var ChaosNames = []string{ChaosKillName, ChaosStopName, ChaosDetachName}
+
+const (
+	chaosVolume      = "volume-chaos-demo"
+	chaosVolumeBytes = 64 * scenario.MiB
+	chaosChunkMiB    = 2
+	// pauseGapRatio 为暂停期间输出间隔至少应达到暂停时长的比例
+	pauseGapRatio = 0.8
+)
+
+func init() {
+	scenario.Register(&Chaos{
+		name:     ChaosKillName,
+		WantExit: 137,
+		Actions: []scenario.ChaosAction{
+			{Name: "pause", Kind: scenario.ChaosPause, AtProgress: 0.25, Duration: 2 * time.Second},
+			{Name: "kill", Kind: scenario.ChaosKill, AtProgress: 0.5, Signal: "SIGKILL"},
+		},
+	})
+	// sh 作为 1 号进程不响应 SIGTERM，stop 在超时后由 daemon 发送 SIGKILL
+	scenario.Register(&Chaos{
+		name:     ChaosStopName,
+		WantExit: 137,
+		Actions: []scenario.ChaosAction{
+			{Name: "stop", Kind: scenario.ChaosStop, AtProgress: 0.5, StopTimeout: 2},
+		},
+	})
+	// 根文件系统只读，卷被摘除后写入落到只读的挂载点目录上，写入者以 42 退出
+	scenario.Register(&Chaos{
+		name:       ChaosDetachName,
+		WantExit:   42,
+		WantStderr: "Read-only file system",
+		Actions: []scenario.ChaosAction{
+			{Name: "detach", Kind: scenario.ChaosDetachVolume, AtProgress: 0.5, Target: volumeMountPath},
+		},
+	})
+}
+
+// Chaos 创建 64 MiB 的 tmpfs 卷，以只读根文件系统的容器按固定速率写入，并按卷的使用率触发 Actions。
+// 期望写入者在卷写满之前以 WantExit 结束，所有动作都已触发且执行成功
+type Chaos struct {
+	name    string
+	Actions []scenario.ChaosAction
+	// WantExit 为期望的写入者退出码，WantStderr 不为空时要求 stderr 包含该内容
+	WantExit   int64
+	WantStderr string
+
+	result  *scenario.RunResult
+	outcome []scenario.ChaosResult
+}
+
+func (c *Chaos) Name() string { return c.name }
+
+func (c *Chaos) Params() map[string]string {
+	actions := make([]string, 0, len(c.Actions))
+	for _, a := range c.Actions {
+		actions = append(actions, fmt.Sprintf("%s@%.0f%%", a.Kind, a.AtProgress*100))
+	}
+	return map[string]string{
+		"volume":  fmt.Sprintf("%dMiB", chaosVolumeBytes/scenario.MiB),
+		"chunk":   fmt.Sprintf("%dMiB", chaosChunkMiB),
+		"actions": strings.Join(actions, ","),
+	}
+}
+
+func (*Chaos) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: memoryLimitBytes, Tmpfs: chaosVolumeBytes, Containers: 2}
+}
+
+func (*Chaos) Prepare(ctx context.Context, env *scenario.Env) error {
+	for _, image := range []string{demoImage, scenario.ProbeImage} {
+		if err := scenario.PullImage(ctx, env.Client, image); err != nil {
+			return fmt.Errorf("拉取镜像失败: %w", err)
+		}
+	}
+	return scenario.RecreateTmpfsVolume(ctx, env.Client, chaosVolume, chaosVolumeBytes)
+}
+
+func (c *Chaos) Run(ctx context.Context, env *scenario.Env) error {
+	hostConfig := scenario.BuildHostConfig(container.Resources{
+		NanoCPUs: cpuLimitNano,
+		Memory:   memoryLimitBytes,
+	}, 0, []mount.Mount{{
+		Type:   mount.TypeVolume,
+		Source: chaosVolume,
+		Target: volumeMountPath,
+	}})
+	hostConfig.ReadonlyRootfs = true
+	result, outcome, err := scenario.RunWithChaos(ctx, env.Client, &container.Config{
+		Image: demoImage,
+		Cmd:   []string{"sh", "-c", fmt.Sprintf(steadyWriterScript, volumeMountPath, chaosChunkMiB)},
+	}, hostConfig, c.name, scenario.ChaosPlan{Progress: scenario.FillProgress, Actions: c.Actions})
+	if err != nil {
+		return fmt.Errorf("执行混沌写入失败: %w", err)
+	}
+	c.result, c.outcome = result, outcome
+	scenario.LogRunResult(c.name, result)
+	scenario.LogChaosResults(outcome)
+	env.Report.AddSeries(scenario.FillSeries("volume", result))
+	return nil
+}
+
+func (c *Chaos) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems []string
+	var fired []string
+	for _, r := range c.outcome {
+		switch {
+		case !r.Fired:
+			problems = append(problems, fmt.Sprintf("%s 未触发（%s）", r.Name, r.Trigger))
+		case r.Error != "":
+			problems = append(problems, fmt.Sprintf("%s 执行失败: %s", r.Name, r.Error))
+		default:
+			fired = append(fired, fmt.Sprintf("%s@%.0f%%（%s）", r.Name, r.Progress*100, r.Took.Round(time.Millisecond)))
+		}
+	}
+	if c.result.StatusCode != c.WantExit {
+		problems = append(problems, fmt.Sprintf("写入者退出码为 %d，期望 %d", c.result.StatusCode, c.WantExit))
+	}
+	if c.WantStderr != "" && !strings.Contains(c.result.Stderr, c.WantStderr) {
+		problems = append(problems, fmt.Sprintf("stderr 中没有 %q", c.WantStderr))
+	}
+
+	series := scenario.FillSeries("volume", c.result)
+	var used float64
+	if n := len(series.Points); n > 0 {
+		used = series.Points[n-1].Value
+	}
+	if series.Limit == 0 || used >= series.Limit-chaosChunkMiB {
+		problems = append(problems, fmt.Sprintf("写入者在卷写满（%.0f/%.0fMiB）之后才结束，扰动没有生效", used, series.Limit))
+	}
+	for _, a := range c.Actions {
+		if a.Kind != scenario.ChaosPause {
+			continue
+		}
+		if gap := maxGap(series.Points); gap < time.Duration(pauseGapRatio*float64(a.Duration)) {
+			problems = append(problems, fmt.Sprintf("暂停 %s 期间输出的最大间隔只有 %s", a.Duration, gap.Round(time.Millisecond)))
+		}
+	}
+	log.Printf("写入者退出码 %d，结束时已用 %.0f/%.0fMiB", c.result.StatusCode, used, series.Limit)
+
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("%s 依次生效，写入者在已用 %.0f/%.0fMiB 时以 %d 结束",
+		strings.Join(fired, " "), used, series.Limit, c.result.StatusCode), nil
+}
+
+// maxGap 返回相邻采样点之间的最大间隔
+func maxGap(points []scenario.Point) time.Duration {
+	var gap time.Duration
+	for i := 1; i < len(points); i++ {
+		gap = max(gap, points[i].At.Sub(points[i-1].At))
+	}
+	return gap
+}
+
+func (*Chaos) Cleanup(ctx context.Context, env *scenario.Env) error {
+	return scenario.RemoveVolume(ctx, env.Client, chaosVolume)
+}
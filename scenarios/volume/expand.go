@@ -0,0 +1,76 @@
+package volume
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// ExpandName 为扩容场景的注册名
+const ExpandName = "volume-expand"
+
+const (
+	expandedVolumeBytes = 96 * scenario.MiB
+	writeCountMiB       = 64
+)
+
+const expansionScript = `set -euo pipefail
+TARGET="%s"
+COUNT_MB=%d
+rm -f "$TARGET/expanded.bin"
+dd if=/dev/zero of="$TARGET/expanded.bin" bs=1M count="$COUNT_MB" status=none
+sync
+echo "完成 ${COUNT_MB}MiB 写入，卷可继续使用"`
+
+// Expand 把卷重建为 96 MiB 后写入 64 MiB，确认扩容后写入可以完成
+type Expand struct {
+	result *scenario.RunResult
+}
+
+func (*Expand) Name() string { return ExpandName }
+
+func (*Expand) Params() map[string]string {
+	return map[string]string{
+		"volumeLimit": fmt.Sprintf("%dMiB", expandedVolumeBytes/scenario.MiB),
+		"write":       fmt.Sprintf("%dMiB", writeCountMiB),
+	}
+}
+
+func (*Expand) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: memoryLimitBytes, Tmpfs: expandedVolumeBytes, Containers: 1}
+}
+
+func (*Expand) Prepare(ctx context.Context, env *scenario.Env) error {
+	if err := scenario.PullImage(ctx, env.Client, demoImage); err != nil {
+		return fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	return scenario.RecreateTmpfsVolume(ctx, env.Client, volumeName, expandedVolumeBytes)
+}
+
+func (e *Expand) Run(ctx context.Context, env *scenario.Env) error {
+	script := fmt.Sprintf(expansionScript, volumeMountPath, writeCountMiB)
+	result, err := scenario.RunControlledContainer(ctx, env.Client, &container.Config{
+		Image: demoImage,
+		Cmd:   []string{"sh", "-c", script},
+	}, hostConfig(), "volume-expand")
+	if err != nil {
+		return fmt.Errorf("执行扩容验证失败: %w", err)
+	}
+	e.result = result
+	scenario.LogRunResult("volume expand", result)
+	return nil
+}
+
+func (e *Expand) Verify(context.Context, *scenario.Env) (string, error) {
+	if e.result.StatusCode != 0 {
+		return "", fmt.Errorf("扩容后的写入应成功，但容器退出码为 %d", e.result.StatusCode)
+	}
+	return fmt.Sprintf("扩容后写入 %dMiB，容器退出码为 0", writeCountMiB), nil
+}
+
+func (*Expand) Cleanup(ctx context.Context, env *scenario.Env) error {
+	return scenario.RemoveVolume(ctx, env.Client, volumeName)
+}
@@ -0,0 +1,100 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/moby/moby/api/types/container"
+
+	"test-docker/pkg/scenario"
+)
+
+// FillName 为写满场景的注册名
+const FillName = "volume-fill"
+
+const (
+	volumeLimitBytes = 32 * scenario.MiB
+	chunkMiB         = 4
+)
+
+const volumeFillScript = `set -euo pipefail
+TARGET="%s"
+CHUNK_MB=%d
+TOTAL=0
+rm -f "$TARGET/fillfile"
+touch "$TARGET/fillfile"
+while true; do
+    if dd if=/dev/zero of="$TARGET/fillfile" bs=1M count="$CHUNK_MB" oflag=append conv=notrunc status=none; then
+        TOTAL=$((TOTAL+CHUNK_MB))
+        DF_LINE=$(df -m "$TARGET" | tail -1)
+        USED=$(echo "$DF_LINE" | awk '{print $3}')
+        AVAIL=$(echo "$DF_LINE" | awk '{print $4}')
+        echo "累计写入=${TOTAL}MiB 已用=${USED}MiB 剩余=${AVAIL}MiB"
+        sync
+    else
+        echo "写入失败：卷空间已耗尽" >&2
+        df -m "$TARGET"
+        exit 42
+    fi
+    sleep 0.1
+done`
+
+// Fill 向 32 MiB 的 tmpfs 卷持续写入，直到空间耗尽
+type Fill struct {
+	result *scenario.RunResult
+}
+
+func (*Fill) Name() string { return FillName }
+
+func (*Fill) Params() map[string]string {
+	return map[string]string{
+		"volumeLimit": fmt.Sprintf("%dMiB", volumeLimitBytes/scenario.MiB),
+		"chunk":       fmt.Sprintf("%dMiB", chunkMiB),
+	}
+}
+
+func (*Fill) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: memoryLimitBytes, Tmpfs: volumeLimitBytes, Containers: 1}
+}
+
+func (*Fill) Prepare(ctx context.Context, env *scenario.Env) error {
+	if err := scenario.PullImage(ctx, env.Client, demoImage); err != nil {
+		return fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	return scenario.RecreateTmpfsVolume(ctx, env.Client, volumeName, volumeLimitBytes)
+}
+
+func (f *Fill) Run(ctx context.Context, env *scenario.Env) error {
+	script := fmt.Sprintf(volumeFillScript, volumeMountPath, chunkMiB)
+	result, err := scenario.RunControlledContainer(ctx, env.Client, &container.Config{
+		Image: demoImage,
+		Cmd:   []string{"sh", "-c", script},
+	}, hostConfig(), "volume-fill")
+	if err != nil {
+		return fmt.Errorf("执行写满测试失败: %w", err)
+	}
+	f.result = result
+
+	scenario.LogRunResult("volume fill", result)
+	env.Report.AddSeries(scenario.FillSeries("volume", result))
+	for _, line := range result.Lines {
+		if line.Stream == "stderr" && strings.Contains(line.Text, "写入失败") {
+			env.Report.Mark(line.At, "enospc", "%s", line.Text)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *Fill) Verify(context.Context, *scenario.Env) (string, error) {
+	if f.result.StatusCode == 0 {
+		return "", errors.New("期望写入失败以确认空间上限，但容器以 0 退出")
+	}
+	return fmt.Sprintf("卷写满后容器以 %d 退出", f.result.StatusCode), nil
+}
+
+func (*Fill) Cleanup(ctx context.Context, env *scenario.Env) error {
+	return scenario.RemoveVolume(ctx, env.Client, volumeName)
+}
@@ -0,0 +1,377 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+
+	"test-docker/pkg/scenario"
+)
+
+// IntegrityName 为写满后数据完整性核对场景的注册名
+const IntegrityName = "volume-fill-integrity"
+
+const (
+	integrityVolume = "volume-integrity-demo"
+	// beforeRecords 为写满之前写入并 fsync 的记录数（每条约 4KiB）
+	beforeRecords = 512
+	// recordsPerChunk 为写满期间每写入一块填充数据之前追加的记录数
+	recordsPerChunk = 32
+	beforeJournal   = "before.log"
+	duringJournal   = "during.log"
+	// recordBytes 为一条记录的长度：魔数、seq、len、4000 字节 payload 与 crc32
+	recordBytes = 4 + 4 + 4 + 4000 + 4
+)
+
+// integrityScript 为记录的写入、写满与核对程序，第一个参数为模式：
+//
+//	write <文件> <条数>   写入指定条数的记录后 fsync
+//	fill <目录> <块 MiB> <每块记录数>  交替追加记录与填充数据直到 ENOSPC，之后继续只追加记录直到再次 ENOSPC，以 42 退出
+//	verify <文件>...      逐条读回记录，输出完整、截断与损坏的条数
+//
+// 记录为 "RCD1" + seq(u32) + len(u32) + payload + crc32(seq|len|payload)，均为小端。
+// 写入使用无缓冲的 os.write，写满时的短写会留下截断的记录
+const integrityScript = `import errno, os, struct, sys, time, zlib
+
+MAGIC = b"RCD1"
+PAYLOAD = 4000
+MAX_PAYLOAD = 1 << 20
+
+def record(seq):
+    payload = (b"%08d" % seq) * (PAYLOAD // 8)
+    body = struct.pack("<II", seq, len(payload)) + payload
+    return MAGIC + body + struct.pack("<I", zlib.crc32(body))
+
+def write_all(fd, buf):
+    view = memoryview(buf)
+    while view:
+        view = view[os.write(fd, view):]
+
+def open_append(path):
+    return os.open(path, os.O_WRONLY | os.O_CREAT | os.O_APPEND, 0o644)
+
+def usage(target):
+    st = os.statvfs(target)
+    return (st.f_blocks - st.f_bfree) * st.f_frsize >> 20, st.f_bavail * st.f_frsize >> 20
+
+def write(path, count):
+    fd = open_append(path)
+    for seq in range(count):
+        write_all(fd, record(seq))
+    os.fsync(fd)
+    print("已确认记录=%d" % count, flush=True)
+
+def fill(target, chunk_mb, per_chunk):
+    journal = open_append(os.path.join(target, "` + duringJournal + `"))
+    filler = open_append(os.path.join(target, "fillfile"))
+    chunk = b"\0" * (1 << 20)
+    seq = total = 0
+    filling = True
+    try:
+        while True:
+            for _ in range(per_chunk):
+                write_all(journal, record(seq))
+                seq += 1
+            if filling:
+                try:
+                    for _ in range(chunk_mb):
+                        write_all(filler, chunk)
+                except OSError as e:
+                    if e.errno != errno.ENOSPC:
+                        raise
+                    print("填充数据写满，继续追加记录", flush=True)
+                    filling = False
+                    continue
+                total += chunk_mb
+                used, avail = usage(target)
+                print("累计写入=%dMiB 已用=%dMiB 剩余=%dMiB 已确认记录=%d" % (total, used, avail, seq), flush=True)
+                time.sleep(0.1)
+    except OSError as e:
+        if e.errno != errno.ENOSPC:
+            raise
+        print("写入失败：卷空间已耗尽", file=sys.stderr, flush=True)
+        print("已确认记录=%d" % seq, flush=True)
+        sys.exit(42)
+
+def verify(path):
+    name = os.path.basename(path)
+    try:
+        data = open(path, "rb").read()
+    except FileNotFoundError:
+        print("file=%s missing=1" % name)
+        return
+    pos = complete = truncated = corrupt = 0
+    last = -1
+    while pos < len(data):
+        if data[pos:pos + 4] != MAGIC or len(data) - pos >= 12 and struct.unpack_from("<I", data, pos + 8)[0] > MAX_PAYLOAD:
+            corrupt += 1
+            pos = data.find(MAGIC, pos + 1)
+            if pos < 0:
+                break
+            continue
+        if len(data) - pos < 12:
+            truncated += 1
+            break
+        seq, n = struct.unpack_from("<II", data, pos + 4)
+        end = pos + 12 + n + 4
+        if end > len(data):
+            truncated += 1
+            break
+        if zlib.crc32(data[pos + 4:end - 4]) != struct.unpack_from("<I", data, end - 4)[0]:
+            corrupt += 1
+        else:
+            complete += 1
+            last = seq
+        pos = end
+    print("file=%s bytes=%d complete=%d truncated=%d corrupt=%d last=%d" % (name, len(data), complete, truncated, corrupt, last))
+
+mode, args = sys.argv[1], sys.argv[2:]
+if mode == "write":
+    write(args[0], int(args[1]))
+elif mode == "fill":
+    fill(args[0], int(args[1]), int(args[2]))
+else:
+    for path in args:
+        verify(path)`
+
+// ackedRecords 匹配写入者输出的 “已确认记录=<N>”
+var ackedRecords = regexp.MustCompile(`已确认记录=(\d+)`)
+
+// RecordCounts 为一个记录文件的核对结果
+type RecordCounts struct {
+	Missing   bool
+	Bytes     int64
+	Complete  int
+	Truncated int
+	Corrupt   int
+	// Last 为最后一条完整记录的序号，没有时为 -1
+	Last int
+}
+
+func (c RecordCounts) String() string {
+	if c.Missing {
+		return "文件不存在"
+	}
+	return fmt.Sprintf("完整 %d、截断 %d、损坏 %d", c.Complete, c.Truncated, c.Corrupt)
+}
+
+// Integrity 在 32 MiB 的 tmpfs 卷上先写入 512 条带校验和的记录并 fsync，再由写满容器交替追加记录与填充数据，
+// 填充数据写满后继续只追加记录直到 ENOSPC。写入者以 42 退出后，由一个只读挂载该卷的新容器逐条读回，
+// 统计完整、截断与损坏的记录，核对卷写满时已确认的记录是否都完好。
+// local 驱动在最后一个使用者退出时卸载 tmpfs 卷，其中的数据随之丢失，因此从 Prepare 到 Cleanup
+// 由一个常驻的辅助容器挂载该卷，使三个先后运行的容器看到同一份内容
+type Integrity struct {
+	holder *scenario.Session
+	fill   *scenario.RunResult
+	acked  int
+	// beforeBytes 为写满之后 before.log 的大小，文件不存在时为 -1
+	beforeBytes int64
+	counts      map[string]RecordCounts
+}
+
+func (*Integrity) Name() string { return IntegrityName }
+
+func (*Integrity) Params() map[string]string {
+	return map[string]string{
+		"volumeLimit":     fmt.Sprintf("%dMiB", volumeLimitBytes/scenario.MiB),
+		"chunk":           fmt.Sprintf("%dMiB", chunkMiB),
+		"beforeRecords":   strconv.Itoa(beforeRecords),
+		"recordsPerChunk": strconv.Itoa(recordsPerChunk),
+	}
+}
+
+func (*Integrity) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: memoryLimitBytes, Tmpfs: volumeLimitBytes, Containers: 2}
+}
+
+func (v *Integrity) Prepare(ctx context.Context, env *scenario.Env) error {
+	for _, image := range []string{demoImage, scenario.ProbeImage} {
+		if err := scenario.PullImage(ctx, env.Client, image); err != nil {
+			return fmt.Errorf("拉取镜像失败: %w", err)
+		}
+	}
+	if err := scenario.RecreateTmpfsVolume(ctx, env.Client, integrityVolume, volumeLimitBytes); err != nil {
+		return err
+	}
+	holder, err := scenario.StartSession(ctx, env.Client, &container.Config{
+		Image: scenario.ProbeImage,
+	}, &container.HostConfig{
+		Mounts: []mount.Mount{{Type: mount.TypeVolume, Source: integrityVolume, Target: volumeMountPath, ReadOnly: true}},
+	}, "volume-integrity-holder")
+	if err != nil {
+		return fmt.Errorf("启动保持卷挂载的辅助容器失败: %w", err)
+	}
+	v.holder = holder
+	return nil
+}
+
+// runIntegrity 以 integrityScript 的指定模式运行一个挂载记录卷的容器
+func runIntegrity(ctx context.Context, env *scenario.Env, name string, readOnly bool, args ...string) (*scenario.RunResult, error) {
+	return scenario.RunControlledContainer(ctx, env.Client, &container.Config{
+		Image: demoImage,
+		Cmd:   append([]string{"python3", "-c", integrityScript}, args...),
+	}, scenario.BuildHostConfig(container.Resources{
+		NanoCPUs: cpuLimitNano,
+		Memory:   memoryLimitBytes,
+	}, 0, []mount.Mount{{
+		Type:     mount.TypeVolume,
+		Source:   integrityVolume,
+		Target:   volumeMountPath,
+		ReadOnly: readOnly,
+	}}), name)
+}
+
+func (v *Integrity) Run(ctx context.Context, env *scenario.Env) error {
+	before, err := runIntegrity(ctx, env, "volume-integrity-before", false,
+		"write", volumeMountPath+"/"+beforeJournal, strconv.Itoa(beforeRecords))
+	if err != nil {
+		return fmt.Errorf("写入写满前的记录失败: %w", err)
+	}
+	if before.StatusCode != 0 {
+		return fmt.Errorf("写入写满前的记录失败（退出码 %d）: %s", before.StatusCode, strings.TrimSpace(before.Stderr))
+	}
+	env.Report.Mark(time.Now(), "integrity", "写满前写入并 fsync %d 条记录", beforeRecords)
+
+	fill, err := runIntegrity(ctx, env, "volume-integrity-fill", false,
+		"fill", volumeMountPath, strconv.Itoa(chunkMiB), strconv.Itoa(recordsPerChunk))
+	if err != nil {
+		return fmt.Errorf("执行写满失败: %w", err)
+	}
+	v.fill = fill
+	scenario.LogRunResult("volume fill integrity", fill)
+	env.Report.AddSeries(scenario.FillSeries("volume", fill))
+	v.acked = lastAcked(fill.Stdout)
+	env.Report.Mark(fill.FinishedAt, "integrity", "写满容器退出码 %d，写满期间已确认 %d 条记录", fill.StatusCode, v.acked)
+
+	probe, err := v.holder.Exec(ctx, scenario.ShellStep("integrity-before-journal",
+		"stat -c %s "+volumeMountPath+"/"+beforeJournal))
+	if err != nil {
+		return fmt.Errorf("检查 %s 失败: %w", beforeJournal, err)
+	}
+	v.beforeBytes = journalSize(probe)
+	env.Report.Mark(probe.FinishedAt, "integrity", "写满后 %s 大小 %d 字节（-1 表示不存在）", beforeJournal, v.beforeBytes)
+
+	// 新容器只读挂载，读回的是写入者退出之后卷上留下的内容
+	verified, err := runIntegrity(ctx, env, "volume-integrity-verify", true,
+		"verify", volumeMountPath+"/"+beforeJournal, volumeMountPath+"/"+duringJournal)
+	if err != nil {
+		return fmt.Errorf("读回记录失败: %w", err)
+	}
+	if verified.StatusCode != 0 {
+		return fmt.Errorf("读回记录失败（退出码 %d）: %s", verified.StatusCode, strings.TrimSpace(verified.Stderr))
+	}
+	v.counts = parseRecordCounts(verified.Stdout)
+	for _, file := range []string{beforeJournal, duringJournal} {
+		c := v.counts[file]
+		log.Printf("%s：%s，最后一条完整记录 %d", file, c, c.Last)
+		env.Report.Mark(verified.FinishedAt, "integrity", "%s 读回 %s", file, c)
+	}
+	return nil
+}
+
+// journalSize 解析 stat -c %s 的结果，文件不存在或输出无法解析时返回 -1
+func journalSize(r scenario.ProbeResult) int64 {
+	if r.ExitCode != 0 {
+		return -1
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(r.Stdout), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// lastAcked 返回写入者最后一次输出的已确认记录数
+func lastAcked(out string) int {
+	matches := ackedRecords.FindAllStringSubmatch(out, -1)
+	if len(matches) == 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(matches[len(matches)-1][1])
+	return n
+}
+
+// parseRecordCounts 解析核对程序输出的 “file=<名称> key=value ...” 行
+func parseRecordCounts(out string) map[string]RecordCounts {
+	counts := map[string]RecordCounts{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := map[string]string{}
+		for _, f := range strings.Fields(line) {
+			if key, value, ok := strings.Cut(f, "="); ok {
+				fields[key] = value
+			}
+		}
+		name, ok := fields["file"]
+		if !ok {
+			continue
+		}
+		atoi := func(key string) int {
+			n, _ := strconv.Atoi(fields[key])
+			return n
+		}
+		c := RecordCounts{
+			Missing:   fields["missing"] == "1",
+			Complete:  atoi("complete"),
+			Truncated: atoi("truncated"),
+			Corrupt:   atoi("corrupt"),
+			Last:      -1,
+		}
+		c.Bytes, _ = strconv.ParseInt(fields["bytes"], 10, 64)
+		if last, err := strconv.Atoi(fields["last"]); err == nil {
+			c.Last = last
+		}
+		counts[name] = c
+	}
+	return counts
+}
+
+func (v *Integrity) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems []string
+	if v.fill.StatusCode != 42 {
+		problems = append(problems, fmt.Sprintf("写满容器退出码为 %d，期望 42", v.fill.StatusCode))
+	}
+	switch want := int64(beforeRecords * recordBytes); {
+	case v.beforeBytes < 0:
+		problems = append(problems, fmt.Sprintf("写满后 %s 已不存在", beforeJournal))
+	case v.beforeBytes != want:
+		problems = append(problems, fmt.Sprintf("写满后 %s 为 %d 字节，期望 %d", beforeJournal, v.beforeBytes, want))
+	}
+	before, during := v.counts[beforeJournal], v.counts[duringJournal]
+	switch {
+	case before.Missing:
+		problems = append(problems, "写满前的记录文件不存在")
+	case before.Complete != beforeRecords || before.Truncated > 0 || before.Corrupt > 0:
+		problems = append(problems, fmt.Sprintf("写满前 fsync 的 %d 条记录读回 %s", beforeRecords, before))
+	}
+	switch {
+	case during.Missing:
+		problems = append(problems, "写满期间的记录文件不存在")
+	case during.Corrupt > 0:
+		problems = append(problems, fmt.Sprintf("写满期间的记录有 %d 条损坏", during.Corrupt))
+	case during.Complete < v.acked:
+		problems = append(problems, fmt.Sprintf("写满期间已确认 %d 条记录，只读回 %d 条完整记录", v.acked, during.Complete))
+	case during.Truncated > 1:
+		problems = append(problems, fmt.Sprintf("写满期间的记录有 %d 条截断，期望至多最后一条", during.Truncated))
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("写满前 %d 条记录完好；写满期间已确认 %d 条，读回%s，ENOSPC 只影响未确认的最后一条记录",
+		beforeRecords, v.acked, during), nil
+}
+
+func (v *Integrity) Cleanup(ctx context.Context, env *scenario.Env) error {
+	if v.holder != nil {
+		v.holder.Close()
+		v.holder = nil
+	}
+	return scenario.RemoveVolume(ctx, env.Client, integrityVolume)
+}
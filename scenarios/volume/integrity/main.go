@@ -0,0 +1,10 @@
+package main
+
+import (
+	"test-docker/pkg/scenario"
+	"test-docker/scenarios/volume"
+)
+
+func main() {
+	scenario.Main(volume.IntegrityName)
+}
@@ -0,0 +1,50 @@
+package volume
+
+import (
+	"testing"
+
+	"test-docker/pkg/scenario"
+)
+
+func TestParseRecordCounts(t *testing.T) {
+	out := "file=before.log bytes=2056192 complete=512 truncated=0 corrupt=0 last=511\n" +
+		"file=during.log bytes=1234567 complete=301 truncated=1 corrupt=2 last=300\n" +
+		"file=other.log missing=1\n"
+	counts := parseRecordCounts(out)
+	if c := counts["before.log"]; c.Complete != 512 || c.Truncated != 0 || c.Last != 511 || c.Bytes != 2056192 {
+		t.Errorf("before.log = %+v", c)
+	}
+	if c := counts["during.log"]; c.Complete != 301 || c.Truncated != 1 || c.Corrupt != 2 {
+		t.Errorf("during.log = %+v", c)
+	}
+	if c := counts["other.log"]; !c.Missing || c.Last != -1 {
+		t.Errorf("other.log = %+v", c)
+	}
+}
+
+func TestLastAcked(t *testing.T) {
+	out := "累计写入=4MiB 已用=6MiB 剩余=26MiB 已确认记录=32\n填充数据写满，继续追加记录\n已确认记录=301\n"
+	if got := lastAcked(out); got != 301 {
+		t.Errorf("lastAcked = %d, want 301", got)
+	}
+	if got := lastAcked(""); got != 0 {
+		t.Errorf("lastAcked(\"\") = %d, want 0", got)
+	}
+}
+
+func TestJournalSize(t *testing.T) {
+	cases := []struct {
+		name   string
+		result scenario.ProbeResult
+		want   int64
+	}{
+		{"存在", scenario.ProbeResult{Stdout: "2056192\n"}, 2056192},
+		{"不存在", scenario.ProbeResult{ExitCode: 1, Stderr: "stat: can't stat 'before.log'"}, -1},
+		{"无法解析", scenario.ProbeResult{Stdout: "?\n"}, -1},
+	}
+	for _, c := range cases {
+		if got := journalSize(c.result); got != c.want {
+			t.Errorf("%s: journalSize = %d, want %d", c.name, got, c.want)
+		}
+	}
+}
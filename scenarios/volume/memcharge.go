@@ -0,0 +1,177 @@
+package volume
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+
+	"test-docker/pkg/scenario"
+)
+
+// MemoryChargeName 为 tmpfs 写入计入内存 cgroup 场景的注册名
+const MemoryChargeName = "volume-memory-charge"
+
+const (
+	chargeVolumeName  = "volume-memory-charge"
+	chargeVolumeBytes = 192 * scenario.MiB
+	chargeMemoryBytes = 64 * scenario.MiB
+	chargeChunkMiB    = 8
+)
+
+// memoryChargeScript 按块写入独立文件，每块之后输出卷的已用/剩余与 cgroup 内存用量；
+// dd 因空间耗尽失败时以 42 退出，其他失败（EIO、EROFS，或 dd 被 OOM killer 杀死）以 43 退出，
+// 其中 OOM 只能由容器的 oom 事件识别
+const memoryChargeScript = `TARGET="%s"
+CHUNK_MB=%d
+TOTAL=0
+N=0
+mem() { cat /sys/fs/cgroup/memory.current 2>/dev/null || cat /sys/fs/cgroup/memory/memory.usage_in_bytes; }
+while true; do
+    ERR=$(dd if=/dev/zero of="$TARGET/chunk-$N" bs=1M count="$CHUNK_MB" 2>&1 >/dev/null)
+    RC=$?
+    DF_LINE=$(df -m "$TARGET" | tail -1)
+    USED=$(echo "$DF_LINE" | awk '{print $3}')
+    AVAIL=$(echo "$DF_LINE" | awk '{print $4}')
+    MEM=$(($(mem) / 1048576))
+    if [ $RC -eq 0 ]; then
+        TOTAL=$((TOTAL+CHUNK_MB))
+        echo "累计写入=${TOTAL}MiB 已用=${USED}MiB 剩余=${AVAIL}MiB 内存=${MEM}MiB"
+    elif echo "$ERR" | grep -q "No space left"; then
+        echo "写入失败：卷空间已耗尽 已用=${USED}MiB 剩余=${AVAIL}MiB 内存=${MEM}MiB" >&2
+        exit 42
+    else
+        echo "写入失败：dd 退出码 $RC 已用=${USED}MiB 剩余=${AVAIL}MiB 内存=${MEM}MiB $ERR" >&2
+        exit 43
+    fi
+    N=$((N+1))
+done`
+
+// chargeStep 匹配脚本每步输出中的已用空间与 cgroup 内存
+var chargeStep = regexp.MustCompile(`已用=(\d+)MiB 剩余=(\d+)MiB 内存=(\d+)MiB`)
+
+// MemoryCharge 把容量大于内存限额的 tmpfs 卷挂载到受限容器并写满，
+// 观察 tmpfs 页计入写入者内存 cgroup 时先触发的是 OOM 还是卷的 ENOSPC
+type MemoryCharge struct {
+	result   *scenario.RunResult
+	boundary string
+	at       time.Time
+}
+
+func (*MemoryCharge) Name() string { return MemoryChargeName }
+
+func (*MemoryCharge) Params() map[string]string {
+	return map[string]string{
+		"volumeLimit": fmt.Sprintf("%dMiB", chargeVolumeBytes/scenario.MiB),
+		"memoryLimit": fmt.Sprintf("%dMiB", chargeMemoryBytes/scenario.MiB),
+		"chunk":       fmt.Sprintf("%dMiB", chargeChunkMiB),
+	}
+}
+
+func (*MemoryCharge) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: chargeMemoryBytes, Tmpfs: chargeVolumeBytes, Containers: 1}
+}
+
+func (*MemoryCharge) Prepare(ctx context.Context, env *scenario.Env) error {
+	if err := scenario.PullImage(ctx, env.Client, scenario.ProbeImage); err != nil {
+		return fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	return scenario.RecreateTmpfsVolume(ctx, env.Client, chargeVolumeName, chargeVolumeBytes)
+}
+
+func (m *MemoryCharge) Run(ctx context.Context, env *scenario.Env) error {
+	// 关闭 swap，tmpfs 页无法换出，只能留在内存 cgroup 中
+	host := scenario.BuildHostConfig(container.Resources{
+		Memory:     chargeMemoryBytes,
+		MemorySwap: chargeMemoryBytes,
+	}, 0, []mount.Mount{{Type: mount.TypeVolume, Source: chargeVolumeName, Target: volumeMountPath}})
+	result, err := scenario.RunControlledContainer(ctx, env.Client, &container.Config{
+		Image: scenario.ProbeImage,
+		Cmd:   []string{"sh", "-c", fmt.Sprintf(memoryChargeScript, volumeMountPath, chargeChunkMiB)},
+	}, host, "volume-memory-charge")
+	if err != nil {
+		return fmt.Errorf("执行写入测试失败: %w", err)
+	}
+	m.result = result
+	scenario.LogRunResult("volume memory charge", result)
+
+	env.Report.AddSeries(scenario.FillSeries("volume", result))
+	memory := &scenario.Series{Name: "cgroup memory", Unit: "MiB", Limit: float64(chargeMemoryBytes / scenario.MiB)}
+	for _, line := range result.Lines {
+		if s := chargeStep.FindStringSubmatch(line.Text); s != nil && !line.At.IsZero() {
+			v, _ := strconv.ParseFloat(s[3], 64)
+			memory.Points = append(memory.Points, scenario.Point{At: line.At, Value: v})
+		}
+	}
+	env.Report.AddSeries(memory)
+
+	m.boundary, m.at = firstBoundary(result, env.Report.ContainerEvents(result.ContainerID))
+	if m.boundary == "enospc" {
+		env.Report.Mark(m.at, "enospc", "卷空间先于内存限额耗尽")
+	}
+	return nil
+}
+
+func (m *MemoryCharge) Verify(context.Context, *scenario.Env) (string, error) {
+	var last string
+	step := 0
+	for _, line := range m.result.Lines {
+		if s := chargeStep.FindStringSubmatch(line.Text); s != nil {
+			step++
+			log.Printf("第 %d 步：卷已用 %sMiB，剩余 %sMiB，cgroup 内存 %sMiB", step, s[1], s[2], s[3])
+			last = fmt.Sprintf("卷已用 %sMiB/%dMiB，cgroup 内存 %sMiB/%dMiB",
+				s[1], chargeVolumeBytes/scenario.MiB, s[3], chargeMemoryBytes/scenario.MiB)
+		}
+	}
+	if last == "" {
+		last = "没有完成任何一步写入"
+	}
+	switch m.boundary {
+	case "oom":
+		return fmt.Sprintf("先触发内存 OOM（退出码 %d），tmpfs 写入计入了容器内存 cgroup；最后一步 %s",
+			m.result.StatusCode, last), nil
+	case "enospc":
+		return fmt.Sprintf("先触发卷 ENOSPC，tmpfs 写入未受容器内存限额约束；最后一步 %s", last), nil
+	}
+	return "", fmt.Errorf("无法判断先触发的边界：容器退出码 %d，OOMKilled=%v，没有 oom 事件；最后一步 %s；stderr: %s",
+		m.result.StatusCode, m.result.OOMKilled, last, strings.TrimSpace(m.result.Stderr))
+}
+
+func (*MemoryCharge) Cleanup(ctx context.Context, env *scenario.Env) error {
+	return scenario.RemoveVolume(ctx, env.Client, chargeVolumeName)
+}
+
+// firstBoundary 判断先触发的边界：OOM 以 oom 事件时间为准，没有事件时以 OOMKilled 或 137 推断；
+// ENOSPC 以脚本输出“卷空间已耗尽”的时间为准。43 只表示 dd 因 ENOSPC 以外的原因失败
+// （EIO、EROFS 等），不能据此推断 OOM。events 需要在 Publish 之前通过 Report.ContainerEvents 读取
+func firstBoundary(r *scenario.RunResult, events []scenario.Event) (string, time.Time) {
+	var oomAt, enospcAt time.Time
+	for _, e := range events {
+		if e.Action == "oom" && (oomAt.IsZero() || e.At.Before(oomAt)) {
+			oomAt = e.At
+		}
+	}
+	if oomAt.IsZero() && (r.OOMKilled || r.StatusCode == 137) {
+		oomAt = r.FinishedAt
+	}
+	for _, line := range r.Lines {
+		if line.Stream == "stderr" && strings.Contains(line.Text, "卷空间已耗尽") {
+			enospcAt = line.At
+			break
+		}
+	}
+	switch {
+	case oomAt.IsZero() && enospcAt.IsZero():
+		return "", time.Time{}
+	case enospcAt.IsZero() || (!oomAt.IsZero() && oomAt.Before(enospcAt)):
+		return "oom", oomAt
+	default:
+		return "enospc", enospcAt
+	}
+}
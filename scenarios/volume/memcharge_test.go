@@ -0,0 +1,34 @@
+package volume
+
+import (
+	"testing"
+	"time"
+
+	"test-docker/pkg/scenario"
+)
+
+func TestFirstBoundary(t *testing.T) {
+	t0 := time.Now()
+	enospc := scenario.LogLine{At: t0.Add(2 * time.Second), Stream: "stderr", Text: "写入失败：卷空间已耗尽 已用=192MiB 剩余=0MiB 内存=60MiB"}
+	tests := []struct {
+		name   string
+		r      scenario.RunResult
+		events []scenario.Event
+		want   string
+	}{
+		{name: "oom event", r: scenario.RunResult{StatusCode: 137}, events: []scenario.Event{{At: t0.Add(time.Second), Action: "oom"}}, want: "oom"},
+		{name: "dd killed with oom event", r: scenario.RunResult{StatusCode: 43, FinishedAt: t0},
+			events: []scenario.Event{{At: t0, Action: "oom"}}, want: "oom"},
+		{name: "dd failed without oom", r: scenario.RunResult{StatusCode: 43, FinishedAt: t0}, want: ""},
+		{name: "oom killed", r: scenario.RunResult{StatusCode: 137, OOMKilled: true, FinishedAt: t0}, want: "oom"},
+		{name: "enospc", r: scenario.RunResult{StatusCode: 42, Lines: []scenario.LogLine{enospc}}, want: "enospc"},
+		{name: "oom after enospc", r: scenario.RunResult{StatusCode: 42, Lines: []scenario.LogLine{enospc}},
+			events: []scenario.Event{{At: t0.Add(3 * time.Second), Action: "oom"}}, want: "enospc"},
+		{name: "neither", r: scenario.RunResult{StatusCode: 1}, want: ""},
+	}
+	for _, tt := range tests {
+		if got, _ := firstBoundary(&tt.r, tt.events); got != tt.want {
+			t.Errorf("%s: firstBoundary = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
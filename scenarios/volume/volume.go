@@ -0,0 +1,46 @@
+// Package volume 包含受限数据卷的写满及写满后的数据完整性核对、扩容、按使用率自动扩容、写入期间的混沌扰动以及 tmpfs 写入计入内存 cgroup 的场景，导入即注册到 scenario 注册表
+package volume
+
+import (
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+
+	"test-docker/pkg/scenario"
+)
+
+const (
+	demoImage        = "docker.io/library/python:3.12-alpine"
+	volumeName       = "volume-limit-demo"
+	volumeMountPath  = "/demo-data"
+	cpuLimitNano     = 1_000_000_000
+	memoryLimitBytes = 128 * scenario.MiB
+	rootFsLimitBytes = 512 * scenario.MiB
+)
+
+func init() {
+	scenario.Register(&Fill{})
+	scenario.Register(&Integrity{})
+	scenario.Register(&Expand{})
+	scenario.Register(&MemoryCharge{})
+	scenario.Register(&Watchdog{Policy: scenario.VolumePolicy{
+		Threshold: 0.6,
+		Step:      watchdogStepBytes,
+		Max:       watchdogMaxBytes,
+		Interval:  500 * time.Millisecond,
+	}})
+}
+
+// hostConfig 为写入容器挂载受限卷，并限制 CPU、内存与系统盘
+func hostConfig() *container.HostConfig {
+	mounts := []mount.Mount{{
+		Type:   mount.TypeVolume,
+		Source: volumeName,
+		Target: volumeMountPath,
+	}}
+	return scenario.BuildHostConfig(container.Resources{
+		NanoCPUs: cpuLimitNano,
+		Memory:   memoryLimitBytes,
+	}, rootFsLimitBytes, mounts)
+}
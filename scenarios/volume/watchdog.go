@@ -0,0 +1,155 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+
+	"test-docker/pkg/scenario"
+)
+
+// WatchdogName 为卷自动扩容场景的注册名
+const WatchdogName = "volume-auto-grow"
+
+const (
+	watchdogVolume       = "volume-watchdog-demo"
+	watchdogInitialBytes = 32 * scenario.MiB
+	watchdogStepBytes    = 32 * scenario.MiB
+	watchdogMaxBytes     = 128 * scenario.MiB
+	// tmpfs 页计入写入者的内存 cgroup，内存限额需要高于卷的容量上限
+	watchdogMemoryBytes = 256 * scenario.MiB
+	watchdogChunkMiB    = 2
+	// sizeSlackBytes 为比较容量时允许的误差（df 按块取整）
+	sizeSlackBytes = scenario.MiB
+)
+
+// steadyWriterScript 以固定速率（每 0.25s 写入 2MiB）追加写入，输出格式与 volumeFillScript 相同，
+// 空间耗尽时以 42 退出
+const steadyWriterScript = `set -euo pipefail
+TARGET="%s"
+CHUNK_MB=%d
+TOTAL=0
+rm -f "$TARGET/steady"
+touch "$TARGET/steady"
+while true; do
+    if dd if=/dev/zero of="$TARGET/steady" bs=1M count="$CHUNK_MB" oflag=append conv=notrunc status=none; then
+        TOTAL=$((TOTAL+CHUNK_MB))
+        DF_LINE=$(df -m "$TARGET" | tail -1)
+        USED=$(echo "$DF_LINE" | awk '{print $3}')
+        AVAIL=$(echo "$DF_LINE" | awk '{print $4}')
+        echo "累计写入=${TOTAL}MiB 已用=${USED}MiB 剩余=${AVAIL}MiB"
+    else
+        echo "写入失败：卷空间已耗尽" >&2
+        df -m "$TARGET"
+        exit 42
+    fi
+    sleep 0.25
+done`
+
+// Watchdog 创建 32 MiB 的 tmpfs 卷并启动 scenario.VolumeWatchdog（使用率 60% 时扩容 32 MiB，上限 128 MiB），
+// 再以固定速率持续写入。期望写入者直到容量达到上限后才遇到 ENOSPC
+type Watchdog struct {
+	Policy scenario.VolumePolicy
+
+	result  *scenario.RunResult
+	outcome scenario.WatchOutcome
+}
+
+func (w *Watchdog) Name() string { return WatchdogName }
+
+func (w *Watchdog) Params() map[string]string {
+	return map[string]string{
+		"initial":   fmt.Sprintf("%dMiB", watchdogInitialBytes/scenario.MiB),
+		"threshold": fmt.Sprintf("%.0f%%", w.Policy.Threshold*100),
+		"step":      fmt.Sprintf("%dMiB", w.Policy.Step/scenario.MiB),
+		"max":       fmt.Sprintf("%dMiB", w.Policy.Max/scenario.MiB),
+		"interval":  w.Policy.Interval.String(),
+	}
+}
+
+func (w *Watchdog) Footprint() scenario.Footprint {
+	return scenario.Footprint{Memory: watchdogMemoryBytes, Tmpfs: w.Policy.Max, Containers: 2}
+}
+
+func (w *Watchdog) Prepare(ctx context.Context, env *scenario.Env) error {
+	if w.Policy.Max < watchdogInitialBytes || w.Policy.Step <= 0 {
+		return errors.New("扩容策略需要正的步长，且上限不低于初始容量")
+	}
+	for _, image := range []string{demoImage, scenario.ProbeImage} {
+		if err := scenario.PullImage(ctx, env.Client, image); err != nil {
+			return fmt.Errorf("拉取镜像失败: %w", err)
+		}
+	}
+	return scenario.RecreateTmpfsVolume(ctx, env.Client, watchdogVolume, watchdogInitialBytes)
+}
+
+func (w *Watchdog) Run(ctx context.Context, env *scenario.Env) error {
+	watchdog, err := scenario.StartVolumeWatchdog(ctx, env.Client, watchdogVolume, w.Policy)
+	if err != nil {
+		return err
+	}
+	result, err := scenario.RunControlledContainer(ctx, env.Client, &container.Config{
+		Image: demoImage,
+		Cmd:   []string{"sh", "-c", fmt.Sprintf(steadyWriterScript, volumeMountPath, watchdogChunkMiB)},
+	}, scenario.BuildHostConfig(container.Resources{
+		NanoCPUs: cpuLimitNano,
+		Memory:   watchdogMemoryBytes,
+	}, 0, []mount.Mount{{
+		Type:   mount.TypeVolume,
+		Source: watchdogVolume,
+		Target: volumeMountPath,
+	}}), "volume-auto-grow")
+	w.outcome = watchdog.Stop()
+	if err != nil {
+		return fmt.Errorf("执行持续写入失败: %w", err)
+	}
+	w.result = result
+	scenario.LogRunResult("volume auto-grow", result)
+	env.Report.AddSeries(scenario.FillSeries("volume", result))
+	return nil
+}
+
+func (w *Watchdog) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems []string
+	if w.outcome.Err != nil {
+		problems = append(problems, w.outcome.Err.Error())
+	}
+	var grows []string
+	reachedMax := false
+	for _, d := range w.outcome.Decisions {
+		switch d.Action {
+		case scenario.WatchGrow:
+			grows = append(grows, fmt.Sprintf("%d→%dMiB", d.Size/scenario.MiB, d.NewSize/scenario.MiB))
+			reachedMax = reachedMax || d.NewSize >= w.Policy.Max
+		case scenario.WatchMax:
+			reachedMax = true
+		}
+	}
+	log.Printf("看门狗共 %d 次处置，扩容：%s", len(w.outcome.Decisions), strings.Join(grows, " "))
+
+	series := scenario.FillSeries("volume", w.result)
+	if w.result.StatusCode != 42 {
+		problems = append(problems, fmt.Sprintf("写入者退出码为 %d，期望在达到上限后以 42 结束", w.result.StatusCode))
+	}
+	if !reachedMax {
+		problems = append(problems, "容量没有扩到上限")
+	}
+	// 写入者最后一次输出的已用+剩余即遇到 ENOSPC 时的容量
+	if int64(series.Limit)*scenario.MiB < w.Policy.Max-sizeSlackBytes {
+		problems = append(problems, fmt.Sprintf("写入者在容量 %.0fMiB 时就遇到 ENOSPC，未达到上限 %dMiB", series.Limit, w.Policy.Max/scenario.MiB))
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("看门狗扩容 %d 次（%s），写入者直到容量达到上限 %dMiB 才遇到 ENOSPC",
+		len(grows), strings.Join(grows, " "), w.Policy.Max/scenario.MiB), nil
+}
+
+func (*Watchdog) Cleanup(ctx context.Context, env *scenario.Env) error {
+	return scenario.RemoveVolume(ctx, env.Client, watchdogVolume)
+}
@@ -1,63 +1,91 @@
+//go:build integration
+
 package test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"os"
-	"testing"
+	"strings"
 
-	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/moby/moby/api/pkg/stdcopy"
 	"github.com/moby/moby/api/types/container"
 	"github.com/moby/moby/client"
+
+	"test-docker/pkg/scenario"
 )
 
-func TestCreate(t *testing.T) {
-	cli, err := client.New(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		panic(err)
-	}
+// createSmoke 为最基本的冒烟检查：拉取 alpine，创建、启动并等待容器退出，再读回日志。
+// 只通过 client 的原始调用完成，不依赖场景辅助函数，作为套件的第一项
+type createSmoke struct {
+	id       string
+	exitCode int64
+	stdout   string
+	stderr   string
+}
 
-	defer cli.Close()
+const smokeOutput = "hello world"
 
-	resp, err := cli.ImagePull(context.Background(), "docker.io/library/alpine", client.ImagePullOptions{})
+func (*createSmoke) Name() string { return "create-smoke" }
 
-	if err != nil {
-		panic(err)
-
-	}
-	defer resp.Close()
-	io.Copy(os.Stdout, resp)
+func (*createSmoke) Prepare(ctx context.Context, env *scenario.Env) error {
+	return scenario.PullImage(ctx, env.Client, scenario.ProbeImage)
+}
 
-	fmt.Println("Pulled image successfully")
-	res, err := cli.ContainerCreate(context.Background(), client.ContainerCreateOptions{
-		Image: "alpine",
-		Config: &container.Config{
-			Cmd: []string{"echo", "hello world"},
-			Tty: false,
-		},
-	})
-	fmt.Printf("Created container with ID: %s\n", res.ID)
+func (c *createSmoke) Run(ctx context.Context, env *scenario.Env) error {
+	cfg := &container.Config{Image: scenario.ProbeImage, Cmd: []string{"echo", smokeOutput}}
+	scenario.LabelForRun(ctx, cfg)
+	res, err := env.Client.ContainerCreate(ctx, client.ContainerCreateOptions{Config: cfg})
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("创建容器失败: %w", err)
 	}
-	if _, err := cli.ContainerStart(context.Background(), res.ID, client.ContainerStartOptions{}); err != nil {
-		panic(err)
+	c.id = res.ID
+	if _, err := env.Client.ContainerStart(ctx, c.id, client.ContainerStartOptions{}); err != nil {
+		return fmt.Errorf("启动容器失败: %w", err)
 	}
-	waitResult := cli.ContainerWait(context.Background(), res.ID, client.ContainerWaitOptions{
-		Condition: container.WaitConditionNotRunning,
-	})
+	wait := env.Client.ContainerWait(ctx, c.id, client.ContainerWaitOptions{Condition: container.WaitConditionNotRunning})
 	select {
-	case err := <-waitResult.Error:
-		if err != nil {
-			panic(err)
-		}
-	case status := <-waitResult.Result:
-		fmt.Printf("Container exited with status code: %d\n", status.StatusCode)
-	}
-	logRes, _ := cli.ContainerLogs(context.Background(), res.ID, client.ContainerLogsOptions{
-		ShowStdout: true,
-	})
-	stdcopy.StdCopy(os.Stdout, os.Stderr, logRes)
+	case err := <-wait.Error:
+		return fmt.Errorf("等待容器退出失败: %w", err)
+	case status := <-wait.Result:
+		c.exitCode = status.StatusCode
+	}
+	logs, err := env.Client.ContainerLogs(ctx, c.id, client.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return fmt.Errorf("读取容器日志失败: %w", err)
+	}
+	defer logs.Close()
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, logs); err != nil {
+		return fmt.Errorf("读取容器日志失败: %w", err)
+	}
+	c.stdout, c.stderr = stdout.String(), stderr.String()
+	return nil
+}
+
+func (c *createSmoke) Verify(context.Context, *scenario.Env) (string, error) {
+	var problems []string
+	if c.exitCode != 0 {
+		problems = append(problems, fmt.Sprintf("退出码为 %d，期望 0", c.exitCode))
+	}
+	if got := strings.TrimSpace(c.stdout); got != smokeOutput {
+		problems = append(problems, fmt.Sprintf("stdout 为 %q，期望 %q", got, smokeOutput))
+	}
+	if c.stderr != "" {
+		problems = append(problems, fmt.Sprintf("stderr 不为空: %q", c.stderr))
+	}
+	if len(problems) > 0 {
+		return "", errors.New(strings.Join(problems, "；"))
+	}
+	return fmt.Sprintf("容器 %.12s 输出 %q 后以 0 退出", c.id, smokeOutput), nil
+}
 
+func (c *createSmoke) Cleanup(ctx context.Context, env *scenario.Env) error {
+	if c.id == "" {
+		return nil
+	}
+	_, err := env.Client.ContainerRemove(ctx, c.id, client.ContainerRemoveOptions{Force: true})
+	c.id = ""
+	return err
 }
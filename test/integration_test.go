@@ -0,0 +1,152 @@
+//go:build integration
+
+// Package test 为依赖真实 Docker daemon 的集成测试，需要显式启用：
+//
+//	go test -tags integration -timeout 2h ./test/...
+//
+// 第一项为拉取、创建、启动、等待并读回日志的冒烟检查，之后每个已注册的场景作为一个子测试，
+// 通过 scenario.Execute 完整运行并断言其 Verify 结论。daemon 不可达时整个套件会被跳过；
+// doctor 判定宿主机缺少必需能力的场景分组、依赖的本地镜像不存在以及占用超出宿主机余量的场景会被单独跳过。daemon 地址与场景一致，可用 DOCKER_HOST 或 docker context 指定。
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/client"
+
+	"test-docker/pkg/scenario"
+	_ "test-docker/scenarios/cgroup"
+	_ "test-docker/scenarios/cpu"
+	_ "test-docker/scenarios/health"
+	_ "test-docker/scenarios/logging"
+	_ "test-docker/scenarios/memory"
+	_ "test-docker/scenarios/rootfs"
+	_ "test-docker/scenarios/security"
+	_ "test-docker/scenarios/stack"
+	_ "test-docker/scenarios/update"
+	_ "test-docker/scenarios/volume"
+)
+
+// scenarioTimeout 为单个场景的运行超时，与 cmd/scenario 的默认 -timeout 一致
+const scenarioTimeout = 10 * time.Minute
+
+// newClient 连接 daemon 并拉取探测镜像，daemon 不可达时跳过测试
+func newClient(t *testing.T) *client.Client {
+	t.Helper()
+	cli, err := scenario.NewDockerClient()
+	if err != nil {
+		t.Skipf("无法创建 Docker 客户端，跳过集成测试: %v", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := cli.Ping(ctx, client.PingOptions{NegotiateAPIVersion: true}); err != nil {
+		t.Skipf("Docker daemon %s 不可达，跳过集成测试: %v", scenario.ActiveEndpoint, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := scenario.PullImage(ctx, cli, scenario.ProbeImage); err != nil {
+		t.Fatalf("拉取镜像 %s 失败: %v", scenario.ProbeImage, err)
+	}
+	return cli
+}
+
+// hostChecks 运行与 doctor 相同的检查
+func hostChecks(t *testing.T, cli *client.Client) []scenario.HostCheck {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	facts, err := scenario.CollectHostFacts(ctx, cli, scenario.ProbeImage)
+	if err != nil {
+		t.Fatalf("收集宿主机信息失败: %v", err)
+	}
+	return scenario.EvaluateHost(facts)
+}
+
+// removeLeftovers 按运行 label 删除场景 Cleanup 之后仍残留的容器，以 t.Cleanup 注册，
+// 子测试提前结束（Fatal、panic 或超时）时同样执行
+func removeLeftovers(t *testing.T, cli *client.Client, runID string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	list, err := cli.ContainerList(ctx, client.ContainerListOptions{
+		All:     true,
+		Filters: make(client.Filters).Add("label", scenario.LabelRunID+"="+runID),
+	})
+	if err != nil {
+		t.Errorf("列出残留容器失败: %v", err)
+		return
+	}
+	for _, c := range list.Items {
+		t.Errorf("场景清理后残留容器 %s", c.ID)
+		if _, err := cli.ContainerRemove(ctx, c.ID, client.ContainerRemoveOptions{Force: true}); err != nil {
+			t.Errorf("删除残留容器 %s 失败: %v", c.ID, err)
+		}
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	cli := newClient(t)
+	scenario.ReportDir = t.TempDir()
+	scenario.Opts.HistoryPath = filepath.Join(scenario.ReportDir, "history.jsonl")
+
+	checks := hostChecks(t, cli)
+	plan := []scenario.Scenario{&createSmoke{}}
+	for _, name := range scenario.Names() {
+		s, _ := scenario.Lookup(name)
+		plan = append(plan, s)
+	}
+	guardCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	_, refused := scenario.GuardPlan(guardCtx, cli, plan, false)
+	cancel()
+	tooLarge := map[string]bool{}
+	for _, name := range refused {
+		tooLarge[name] = true
+	}
+
+	for _, s := range plan {
+		t.Run(s.Name(), func(t *testing.T) {
+			if blockers := scenario.Blockers(checks, s); len(blockers) > 0 {
+				var reasons []string
+				for _, c := range blockers {
+					reasons = append(reasons, c.Name+": "+c.Detail)
+				}
+				t.Skipf("宿主机不满足场景的依赖: %v", reasons)
+			}
+			if tooLarge[s.Name()] {
+				t.Skip("场景占用超出宿主机余量")
+			}
+
+			imgCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			missing, err := scenario.MissingImages(imgCtx, cli, s)
+			cancel()
+			if err != nil {
+				t.Fatalf("检查本地镜像失败: %v", err)
+			}
+			if len(missing) > 0 {
+				t.Skipf("缺少本地构建的镜像 %v", missing)
+			}
+
+			runID := scenario.NewRunID(s.Name())
+			t.Cleanup(func() { removeLeftovers(t, cli, runID) })
+			ctx, cancel := context.WithTimeout(scenario.WithRunID(context.Background(), runID), scenarioTimeout)
+			defer cancel()
+			report, err := scenario.Execute(ctx, cli, s)
+			if err != nil {
+				t.Errorf("发布报告失败: %v", err)
+			}
+			if report == nil {
+				t.Fatal("Execute 没有返回报告")
+			}
+			if !report.Passed {
+				t.Fatalf("场景未通过: %s", report.Summary)
+			}
+			t.Logf("通过: %s", report.Summary)
+		})
+	}
+}